@@ -4,9 +4,17 @@ import (
 	"log"
 
 	"github.com/jphacks/os_2522/backend/internal/database"
+	"github.com/jphacks/os_2522/backend/internal/events"
+	"github.com/jphacks/os_2522/backend/internal/extraction"
 	"github.com/jphacks/os_2522/backend/internal/handler"
+	"github.com/jphacks/os_2522/backend/internal/llm"
 	"github.com/jphacks/os_2522/backend/internal/repository"
 	"github.com/jphacks/os_2522/backend/internal/service"
+	"github.com/jphacks/os_2522/backend/internal/storage"
+	"github.com/jphacks/os_2522/backend/internal/transcription"
+	"github.com/jphacks/os_2522/backend/internal/vectorindex"
+	"github.com/jphacks/os_2522/backend/internal/webhook"
+	"github.com/jphacks/os_2522/backend/internal/worker"
 )
 
 // Handlers holds all HTTP handlers
@@ -15,8 +23,18 @@ type Handlers struct {
 	Person      *handler.PersonHandler
 	Face        *handler.FaceHandler
 	Recognition *handler.RecognitionHandler
+	Model       *handler.ModelHandler
 	Encounter   *handler.EncounterHandler
+	Suggestion  *handler.SuggestionHandler
+	Profile     *handler.ProfileHandler
 	Transcribe  *handler.TranscribeHandler
+	Upload      *handler.UploadHandler
+	JobEvents   *handler.JobEventsHandler
+	Summarize   *handler.SummarizeHandler
+	Worker      *worker.Worker
+	Webhooks    *webhook.Dispatcher
+	Profiles    *service.ProfileUpdater
+	Audio       *service.AudioSweeper
 }
 
 func main() {
@@ -46,14 +64,74 @@ func InitializeApp() (*Handlers, error) {
 	faceRepo := repository.NewFaceRepository(db)
 	encounterRepo := repository.NewEncounterRepository(db)
 	jobRepo := repository.NewJobRepository(db)
+	transcriptRepo := repository.NewTranscriptRepository(db)
+	webhookDeliveryRepo := repository.NewWebhookDeliveryRepository(db)
+	uploadRepo := repository.NewUploadRepository(db)
+	modelRepo := repository.NewModelRepository(db)
+	suggestionRepo := repository.NewSuggestionRepository(db)
+	profileRepo := repository.NewProfileRepository(db)
+
+	// Initialize model registry
+	modelService := service.NewModelService(modelRepo)
+
+	// Load face embedding index
+	faceIndex, err := service.LoadFaceIndex(faceRepo, modelService, vectorindex.DefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Face index loaded with %d embeddings", faceIndex.Len())
 
 	// Initialize services
-	personService := service.NewPersonService(personRepo, faceRepo)
-	faceService := service.NewFaceService(faceRepo, personRepo)
-	faceExtractionService := service.NewFaceExtractionService()
-	recognitionService := service.NewRecognitionService(faceRepo, personRepo, encounterRepo)
-	encounterService := service.NewEncounterService(encounterRepo, personRepo)
-	jobService := service.NewJobService(jobRepo)
+	extractor, err := extraction.New(extraction.GetConfigFromEnv())
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Face extraction backend: %s (model %s)", extractor.Name(), extractor.ModelVersion())
+	if err := modelService.CheckExtractionModel(extractor.ModelVersion(), extractor.Dimension()); err != nil {
+		extractor.Close()
+		return nil, err
+	}
+	faceExtractionService := service.NewFaceExtractionService(extractor)
+	audioStore, err := storage.New(storage.GetConfigFromEnv())
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Audio storage backend: %s", audioStore.Name())
+	var transcriber transcription.Transcriber
+	if t, err := transcription.New(transcription.GetConfigFromEnv()); err != nil {
+		log.Printf("Warning: Failed to initialize transcription: %v", err)
+	} else {
+		transcriber = t
+	}
+	var llmProvider llm.Provider
+	if p, err := llm.New(llm.GetConfigFromEnv()); err != nil {
+		log.Printf("Warning: Failed to initialize LLM provider: %v", err)
+	} else {
+		llmProvider = p
+	}
+	summarizeService := service.NewSummarizeService(llmProvider, service.GetSummarizeConfigFromEnv())
+	var summarizer service.Summarizer
+	if llmProvider != nil {
+		summarizer = summarizeService
+	}
+	encounterService := service.NewEncounterService(encounterRepo, personRepo, service.GetEncounterConfigFromEnv())
+	webhookConfig, err := webhook.GetConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	webhookDispatcher := webhook.NewDispatcher(webhookDeliveryRepo, webhookConfig)
+	webhookService := service.NewWebhookService(webhookDeliveryRepo, webhookConfig, webhookDispatcher)
+	eventBroker := events.NewBroker(events.GetConfigFromEnv())
+	jobEventService := service.NewJobEventService(jobRepo, eventBroker)
+	profileUpdater := service.NewProfileUpdater(llmProvider, personRepo, encounterRepo, profileRepo, service.GetProfileConfigFromEnv())
+	profileService := service.NewProfileService(personRepo, profileRepo, profileUpdater)
+	jobWorker := worker.New(jobRepo, service.NewTranscriptionProcessor(audioStore, transcriber, summarizer), worker.GetConfigFromEnv(), encounterService, profileService, webhookService, jobEventService)
+	jobService := service.NewJobService(jobRepo, transcriptRepo, audioStore, jobWorker, webhookService, jobEventService, service.GetAudioConfigFromEnv())
+	uploadService := service.NewUploadService(uploadRepo, personRepo, audioStore, jobService, service.GetUploadConfigFromEnv())
+	personService := service.NewPersonService(personRepo, faceRepo, faceIndex, modelService, faceExtractionService, jobService)
+	faceService := service.NewFaceService(faceRepo, personRepo, faceIndex, modelService)
+	recognitionService := service.NewRecognitionService(faceIndex, modelService, personRepo, encounterRepo, service.GetRecognitionConfigFromEnv())
+	suggestionService := service.NewSuggestionService(llmProvider, personRepo, encounterRepo, suggestionRepo, service.GetSuggestionConfigFromEnv())
 
 	// Initialize handlers
 	handlers := &Handlers{
@@ -61,8 +139,18 @@ func InitializeApp() (*Handlers, error) {
 		Person:      handler.NewPersonHandler(personService),
 		Face:        handler.NewFaceHandler(faceService, faceExtractionService),
 		Recognition: handler.NewRecognitionHandler(recognitionService, faceExtractionService),
+		Model:       handler.NewModelHandler(modelService),
 		Encounter:   handler.NewEncounterHandler(encounterService),
+		Suggestion:  handler.NewSuggestionHandler(suggestionService),
+		Profile:     handler.NewProfileHandler(profileService),
 		Transcribe:  handler.NewTranscribeHandler(jobService),
+		Upload:      handler.NewUploadHandler(uploadService),
+		JobEvents:   handler.NewJobEventsHandler(jobEventService, eventBroker.Config().Heartbeat),
+		Summarize:   handler.NewSummarizeHandler(summarizeService),
+		Worker:      jobWorker,
+		Webhooks:    webhookDispatcher,
+		Profiles:    profileUpdater,
+		Audio:       service.NewAudioSweeper(jobService),
 	}
 
 	return handlers, nil
@@ -0,0 +1,132 @@
+// Package audio identifies audio containers and reads their duration and
+// sample rate without decoding them.
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Container formats recognised by Probe
+const (
+	FormatWAV  = "wav"
+	FormatFLAC = "flac"
+	FormatOgg  = "ogg"
+	FormatMP3  = "mp3"
+)
+
+var (
+	// ErrUnsupported is returned for content that is not in a supported format
+	ErrUnsupported = errors.New("unsupported audio format")
+	// ErrCorrupt is returned for content in a supported format that cannot be read
+	ErrCorrupt = errors.New("corrupt audio")
+)
+
+// Info describes a recording
+type Info struct {
+	// Format is the container, e.g. "ogg"
+	Format string
+	// Codec is the encoding inside the container, e.g. "opus" or "pcm"
+	Codec      string
+	SampleRate int
+	Channels   int
+	Duration   time.Duration
+}
+
+// Probe identifies the recording read from r and reads its properties. It
+// reads sequentially and, for formats that do not declare their length, up to
+// the end of r. Errors wrap ErrUnsupported or ErrCorrupt when the content is at fault.
+func Probe(r io.Reader) (*Info, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	head, err := br.Peek(12)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	var info *Info
+	switch {
+	case len(head) >= 12 && bytes.Equal(head[:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WAVE")):
+		info, err = probeWAV(br)
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		info, err = probeFLAC(br)
+	case bytes.HasPrefix(head, []byte("OggS")):
+		info, err = probeOgg(br)
+	case bytes.HasPrefix(head, []byte("ID3")):
+		// ID3v2 tags precede MP3 frames and, rarely, FLAC streams
+		if err := skipID3(br); err != nil {
+			return nil, err
+		}
+		if next, _ := br.Peek(4); bytes.Equal(next, []byte("fLaC")) {
+			info, err = probeFLAC(br)
+		} else {
+			info, err = probeMP3(br)
+		}
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		info, err = probeMP3(br)
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.Duration <= 0 {
+		return nil, fmt.Errorf("%w: %s contains no audio", ErrCorrupt, info.Format)
+	}
+	return info, nil
+}
+
+// corrupt wraps ErrCorrupt with a description of the problem
+func corrupt(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrCorrupt, fmt.Sprintf(format, args...))
+}
+
+// readFull reads exactly len(buf) bytes, reporting a short read as corruption
+func readFull(r io.Reader, buf []byte, what string) error {
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return corrupt("%s is truncated", what)
+		}
+		return err
+	}
+	return nil
+}
+
+// skip discards n bytes, reporting a short read as corruption
+func skip(r io.Reader, n int64, what string) error {
+	copied, err := io.CopyN(io.Discard, r, n)
+	if copied < n {
+		if err == nil || err == io.EOF {
+			return corrupt("%s is truncated", what)
+		}
+		return err
+	}
+	return nil
+}
+
+// samplesDuration converts a sample count to a duration
+func samplesDuration(samples uint64, sampleRate int) time.Duration {
+	return time.Duration(float64(samples) / float64(sampleRate) * float64(time.Second))
+}
+
+// skipID3 skips an ID3v2 tag
+func skipID3(r io.Reader) error {
+	var header [10]byte
+	if err := readFull(r, header[:], "ID3 tag"); err != nil {
+		return err
+	}
+	size := int64(0)
+	for _, b := range header[6:10] {
+		if b&0x80 != 0 {
+			return corrupt("invalid ID3 tag size")
+		}
+		size = size<<7 | int64(b)
+	}
+	if header[5]&0x10 != 0 {
+		size += 10 // Footer
+	}
+	return skip(r, size, "ID3 tag")
+}
@@ -0,0 +1,208 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func wavFile(sampleRate, channels, bits, frames int) []byte {
+	blockAlign := channels * bits / 8
+	dataSize := frames * blockAlign
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+8+dataSize+8))
+	b.WriteString("WAVE")
+	b.WriteString("LIST")
+	binary.Write(&b, binary.LittleEndian, uint32(3))
+	b.Write([]byte{1, 2, 3, 0}) // Odd-sized chunk with its pad byte
+	b.WriteString("fmt ")
+	binary.Write(&b, binary.LittleEndian, uint32(16))
+	binary.Write(&b, binary.LittleEndian, uint16(1))
+	binary.Write(&b, binary.LittleEndian, uint16(channels))
+	binary.Write(&b, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&b, binary.LittleEndian, uint32(sampleRate*blockAlign))
+	binary.Write(&b, binary.LittleEndian, uint16(blockAlign))
+	binary.Write(&b, binary.LittleEndian, uint16(bits))
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(dataSize))
+	b.Write(make([]byte, dataSize))
+	return b.Bytes()
+}
+
+func flacFile(sampleRate, channels int, totalSamples uint64) []byte {
+	block := make([]byte, 34)
+	block[10] = byte(sampleRate >> 12)
+	block[11] = byte(sampleRate >> 4)
+	block[12] = byte(sampleRate<<4) | byte(channels-1)<<1
+	block[13] = 15<<4 | byte(totalSamples>>32&0x0F) // 16 bits per sample
+	binary.BigEndian.PutUint32(block[14:18], uint32(totalSamples))
+
+	var b bytes.Buffer
+	b.WriteString("fLaC")
+	b.Write([]byte{0x00, 0, 0, 34})
+	b.Write(block)
+	b.Write([]byte{0x84, 0, 0, 4}) // Last block: VORBIS_COMMENT
+	b.Write([]byte{0, 0, 0, 0})
+	b.Write([]byte{0xFF, 0xF8, 0x69, 0x08})
+	return b.Bytes()
+}
+
+func oggPage(headerType byte, granule int64, serial, sequence uint32, body []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("OggS")
+	b.WriteByte(0)
+	b.WriteByte(headerType)
+	binary.Write(&b, binary.LittleEndian, granule)
+	binary.Write(&b, binary.LittleEndian, serial)
+	binary.Write(&b, binary.LittleEndian, sequence)
+	binary.Write(&b, binary.LittleEndian, uint32(0))
+	var table []byte
+	for n := len(body); ; n -= 255 {
+		if n < 255 {
+			table = append(table, byte(n))
+			break
+		}
+		table = append(table, 255)
+	}
+	b.WriteByte(byte(len(table)))
+	b.Write(table)
+	b.Write(body)
+	page := b.Bytes()
+	binary.LittleEndian.PutUint32(page[22:26], oggCRC(page))
+	return page
+}
+
+func opusFile(inputRate uint32, preSkip uint16, granules ...int64) []byte {
+	head := []byte("OpusHead\x01\x02")
+	head = binary.LittleEndian.AppendUint16(head, preSkip)
+	head = binary.LittleEndian.AppendUint32(head, inputRate)
+	head = append(head, 0, 0, 0)
+
+	var b bytes.Buffer
+	b.Write(oggPage(0x02, 0, 7, 0, head))
+	b.Write(oggPage(0, 0, 7, 1, []byte("OpusTags")))
+	for i, granule := range granules {
+		b.Write(oggPage(0, granule, 7, uint32(i+2), make([]byte, 300)))
+	}
+	return b.Bytes()
+}
+
+// mp3Frame128k returns an MPEG-1 Layer III frame at 128 kbit/s and 44.1 kHz
+func mp3Frame128k(payload string) []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x44})
+	copy(frame[36:], payload)
+	return frame
+}
+
+func TestProbe(t *testing.T) {
+	mp3 := bytes.Buffer{}
+	mp3.WriteString("ID3\x03\x00\x00\x00\x00\x00\x05hello")
+	mp3.Write(mp3Frame128k("Info"))
+	for i := 0; i < 100; i++ {
+		mp3.Write(mp3Frame128k(""))
+	}
+	mp3.Write(mp3Frame128k("")[:200]) // Cut short by the end of the recording
+	mp3.WriteString("TAG" + string(make([]byte, 125)))
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected Info
+	}{
+		{
+			name:     "wav",
+			data:     wavFile(16000, 1, 16, 48000),
+			expected: Info{Format: FormatWAV, Codec: "pcm", SampleRate: 16000, Channels: 1, Duration: 3 * time.Second},
+		},
+		{
+			name:     "flac",
+			data:     flacFile(44100, 2, 441000),
+			expected: Info{Format: FormatFLAC, Codec: "flac", SampleRate: 44100, Channels: 2, Duration: 10 * time.Second},
+		},
+		{
+			name:     "ogg opus",
+			data:     opusFile(16000, 312, 48000, 96312),
+			expected: Info{Format: FormatOgg, Codec: "opus", SampleRate: 16000, Channels: 2, Duration: 2 * time.Second},
+		},
+		{
+			name:     "mp3",
+			data:     mp3.Bytes(),
+			expected: Info{Format: FormatMP3, Codec: "mp3", SampleRate: 44100, Channels: 2, Duration: samplesDuration(100*1152, 44100)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Probe(bytes.NewReader(tt.data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, *info)
+		})
+	}
+}
+
+func TestProbe_OggVorbisAfterOtherStream(t *testing.T) {
+	vorbis := []byte("\x01vorbis\x00\x00\x00\x00\x01")
+	vorbis = binary.LittleEndian.AppendUint32(vorbis, 22050)
+	vorbis = append(vorbis, make([]byte, 14)...)
+
+	var b bytes.Buffer
+	b.Write(oggPage(0x02, 0, 1, 0, []byte("fishead\x00"))) // Skeleton stream is skipped
+	b.Write(oggPage(0x02, 0, 2, 0, vorbis))
+	b.Write(oggPage(0, 0, 2, 1, []byte("\x03vorbis")))
+	b.Write(oggPage(0, 0, 1, 1, nil))
+	b.Write(oggPage(0x04, 44100, 2, 2, make([]byte, 10)))
+
+	info, err := Probe(&b)
+	require.NoError(t, err)
+	assert.Equal(t, Info{Format: FormatOgg, Codec: "vorbis", SampleRate: 22050, Channels: 1, Duration: 2 * time.Second}, *info)
+}
+
+func TestProbe_WAVStreamingLength(t *testing.T) {
+	data := wavFile(8000, 1, 8, 4000)
+	binary.LittleEndian.PutUint32(data[len(data)-4000-4:], wavStreaming)
+
+	info, err := Probe(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, info.Duration)
+}
+
+func TestProbe_Rejects(t *testing.T) {
+	truncatedWAV := wavFile(16000, 1, 16, 16000)
+	badCRC := opusFile(48000, 0, 48000)
+	badCRC[len(badCRC)-1] ^= 0xFF
+	missingPage := opusFile(48000, 0, 48000, 96000, 144000)
+	pageSize := (len(missingPage) - len(opusFile(48000, 0))) / 3
+	missingPage = append(missingPage[:len(missingPage)-2*pageSize], missingPage[len(missingPage)-pageSize:]...)
+	mp2 := append([]byte{0xFF, 0xFD, 0x90, 0x44}, make([]byte, 500)...)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected error
+	}{
+		{"empty", nil, ErrUnsupported},
+		{"text", []byte("this is not audio"), ErrUnsupported},
+		{"wav without data", wavFile(16000, 1, 16, 10)[:70], ErrCorrupt},
+		{"truncated wav", truncatedWAV[:len(truncatedWAV)-100], ErrCorrupt},
+		{"silent wav", wavFile(16000, 1, 16, 0), ErrCorrupt},
+		{"flac without length", flacFile(44100, 2, 0), ErrUnsupported},
+		{"flac without frames", flacFile(44100, 2, 100)[:46], ErrCorrupt},
+		{"ogg checksum mismatch", badCRC, ErrCorrupt},
+		{"ogg missing page", missingPage, ErrCorrupt},
+		{"truncated ogg", opusFile(48000, 0, 48000)[:60], ErrCorrupt},
+		{"ogg without audio codec", oggPage(0x02, 0, 1, 0, []byte("fishead\x00")), ErrUnsupported},
+		{"mp2", mp2, ErrUnsupported},
+		{"id3 without frames", []byte("ID3\x03\x00\x00\x00\x00\x00\x00junk"), ErrCorrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Probe(bytes.NewReader(tt.data))
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// probeFLAC reads the STREAMINFO block of a native FLAC stream and checks that
+// audio frames follow the metadata
+func probeFLAC(r io.Reader) (*Info, error) {
+	var marker [4]byte
+	if err := readFull(r, marker[:], "FLAC header"); err != nil {
+		return nil, err
+	}
+
+	var info *Info
+	for first := true; ; first = false {
+		var header [4]byte
+		if err := readFull(r, header[:], "FLAC metadata"); err != nil {
+			return nil, err
+		}
+		last := header[0]&0x80 != 0
+		blockType := header[0] & 0x7F
+		length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+
+		switch {
+		case first && (blockType != 0 || length != 34):
+			return nil, corrupt("FLAC stream does not start with STREAMINFO")
+		case blockType == 127:
+			return nil, corrupt("invalid FLAC metadata block")
+		case first:
+			var block [34]byte
+			if err := readFull(r, block[:], "FLAC STREAMINFO"); err != nil {
+				return nil, err
+			}
+			var err error
+			if info, err = parseStreamInfo(block[:]); err != nil {
+				return nil, err
+			}
+		default:
+			if err := skip(r, int64(length), "FLAC metadata"); err != nil {
+				return nil, err
+			}
+		}
+		if last {
+			break
+		}
+	}
+
+	var sync [2]byte
+	if err := readFull(r, sync[:], "FLAC audio"); err != nil {
+		return nil, err
+	}
+	if sync[0] != 0xFF || sync[1]&0xFE != 0xF8 {
+		return nil, corrupt("FLAC audio frames are missing")
+	}
+	return info, nil
+}
+
+func parseStreamInfo(block []byte) (*Info, error) {
+	sampleRate := int(block[10])<<12 | int(block[11])<<4 | int(block[12])>>4
+	channels := int(block[12]>>1&0x07) + 1
+	totalSamples := uint64(block[13]&0x0F)<<32 | uint64(binary.BigEndian.Uint32(block[14:18]))
+
+	if sampleRate == 0 {
+		return nil, corrupt("FLAC sample rate is zero")
+	}
+	if totalSamples == 0 {
+		// Streamed encoders may leave the length out; it cannot be known without decoding
+		return nil, ErrUnsupported
+	}
+	return &Info{
+		Format:     FormatFLAC,
+		Codec:      "flac",
+		SampleRate: sampleRate,
+		Channels:   channels,
+		Duration:   samplesDuration(totalSamples, sampleRate),
+	}, nil
+}
@@ -0,0 +1,147 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// MPEG audio versions as encoded in the frame header
+const (
+	mpeg25 = 0
+	mpeg2  = 2
+	mpeg1  = 3
+)
+
+var (
+	// mp3Bitrates holds the Layer III bitrates in kbit/s for MPEG-1 and for MPEG-2/2.5
+	mp3Bitrates = [2][16]int{
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	}
+	mp3SampleRates = map[int][3]int{
+		mpeg1:  {44100, 48000, 32000},
+		mpeg2:  {22050, 24000, 16000},
+		mpeg25: {11025, 12000, 8000},
+	}
+)
+
+// mp3Frame is a parsed MPEG audio frame header
+type mp3Frame struct {
+	version    int
+	sampleRate int
+	channels   int
+	length     int
+	samples    int
+}
+
+// parseMP3Frame parses a Layer III frame header. ok is false for anything else,
+// including free-format frames whose length cannot be computed.
+func parseMP3Frame(h []byte) (frame mp3Frame, ok bool) {
+	if h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return frame, false
+	}
+	version := int(h[1] >> 3 & 0x03)
+	layer := h[1] >> 1 & 0x03
+	bitrateIndex := h[2] >> 4
+	rateIndex := h[2] >> 2 & 0x03
+	padding := int(h[2] >> 1 & 0x01)
+	if version == 1 || layer != 1 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return frame, false
+	}
+
+	frame.version = version
+	frame.sampleRate = mp3SampleRates[version][rateIndex]
+	frame.channels = 2
+	if h[3]>>6 == 3 {
+		frame.channels = 1
+	}
+	if version == mpeg1 {
+		frame.samples = 1152
+		frame.length = 144000*mp3Bitrates[0][bitrateIndex]/frame.sampleRate + padding
+	} else {
+		frame.samples = 576
+		frame.length = 72000*mp3Bitrates[1][bitrateIndex]/frame.sampleRate + padding
+	}
+	return frame, true
+}
+
+// isXingFrame reports whether a frame holds a Xing/Info header rather than audio
+func isXingFrame(data []byte, frame mp3Frame) bool {
+	offset := 4 + 32
+	switch {
+	case frame.version == mpeg1 && frame.channels == 1:
+		offset = 4 + 17
+	case frame.version != mpeg1 && frame.channels == 2:
+		offset = 4 + 17
+	case frame.version != mpeg1:
+		offset = 4 + 9
+	}
+	if len(data) < offset+4 {
+		return false
+	}
+	tag := data[offset : offset+4]
+	return bytes.Equal(tag, []byte("Xing")) || bytes.Equal(tag, []byte("Info"))
+}
+
+// probeMP3 walks the frames of an MP3 stream and adds up their samples. It
+// stops at the first thing that is not a matching frame, such as a trailing
+// tag, and ignores a final frame cut short by the end of the recording.
+func probeMP3(r *bufio.Reader) (*Info, error) {
+	var first mp3Frame
+	var samples uint64
+	frames := 0
+
+	for {
+		header, err := r.Peek(4)
+		if len(header) < 4 {
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			break
+		}
+		frame, ok := parseMP3Frame(header)
+		if !ok {
+			if frames == 0 {
+				if header[0] == 0xFF && header[1]&0xE0 == 0xE0 {
+					// MPEG audio, but not Layer III or not readable
+					return nil, ErrUnsupported
+				}
+				return nil, corrupt("MP3 has no audio frames")
+			}
+			break
+		}
+		if frames > 0 && (frame.version != first.version || frame.sampleRate != first.sampleRate) {
+			break
+		}
+
+		data, err := r.Peek(frame.length)
+		if len(data) < frame.length {
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			break
+		}
+		if frames == 0 {
+			first = frame
+		}
+		if frames > 0 || !isXingFrame(data, frame) {
+			samples += uint64(frame.samples)
+		}
+		frames++
+		if _, err := r.Discard(frame.length); err != nil {
+			return nil, err
+		}
+	}
+
+	if frames == 0 {
+		return nil, corrupt("MP3 has no audio frames")
+	}
+	return &Info{
+		Format:     FormatMP3,
+		Codec:      "mp3",
+		SampleRate: first.sampleRate,
+		Channels:   first.channels,
+		Duration:   samplesDuration(samples, first.sampleRate),
+	}, nil
+}
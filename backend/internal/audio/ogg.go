@@ -0,0 +1,149 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// oggCRCTable is the CRC-32 used by Ogg: polynomial 0x04C11DB7, not reflected
+var oggCRCTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+func oggCRC(data []byte) uint32 {
+	var crc uint32
+	for _, b := range data {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// oggStream tracks the logical stream carrying the audio
+type oggStream struct {
+	serial   uint32
+	preSkip  uint64
+	sequence uint32
+	granule  int64
+}
+
+// probeOgg reads every page of an Ogg file, verifying checksums and page
+// order, and takes the duration from the last granule position of the first
+// Opus or Vorbis stream
+func probeOgg(r io.Reader) (*Info, error) {
+	var info *Info
+	var stream *oggStream
+	page := make([]byte, 0, 27+255+255*255)
+
+	for pages := 0; ; pages++ {
+		header := page[:27]
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF && pages > 0 {
+				break
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, corrupt("Ogg page is truncated")
+			}
+			return nil, err
+		}
+		if !bytes.Equal(header[:4], []byte("OggS")) || header[4] != 0 {
+			return nil, corrupt("invalid Ogg page")
+		}
+		segments := int(header[26])
+		table := page[27 : 27+segments]
+		if err := readFull(r, table, "Ogg page"); err != nil {
+			return nil, err
+		}
+		bodySize := 0
+		for _, lacing := range table {
+			bodySize += int(lacing)
+		}
+		body := page[27+segments : 27+segments+bodySize]
+		if err := readFull(r, body, "Ogg page"); err != nil {
+			return nil, err
+		}
+
+		whole := page[:27+segments+bodySize]
+		expected := binary.LittleEndian.Uint32(whole[22:26])
+		binary.LittleEndian.PutUint32(whole[22:26], 0)
+		if oggCRC(whole) != expected {
+			return nil, corrupt("Ogg page checksum mismatch")
+		}
+
+		headerType := header[5]
+		granule := int64(binary.LittleEndian.Uint64(header[6:14]))
+		serial := binary.LittleEndian.Uint32(header[14:18])
+		sequence := binary.LittleEndian.Uint32(header[18:22])
+
+		if stream == nil {
+			if headerType&0x02 == 0 {
+				continue
+			}
+			// Beginning of a logical stream: look for a codec we know
+			if streamInfo, preSkip := parseOggHeader(body); streamInfo != nil {
+				info = streamInfo
+				stream = &oggStream{serial: serial, preSkip: preSkip, sequence: sequence}
+			}
+			continue
+		}
+		if serial != stream.serial {
+			continue
+		}
+		if sequence != stream.sequence+1 {
+			return nil, corrupt("Ogg pages are missing")
+		}
+		stream.sequence = sequence
+		if granule != -1 {
+			stream.granule = granule
+		}
+	}
+
+	if stream == nil {
+		return nil, ErrUnsupported
+	}
+	if uint64(stream.granule) > stream.preSkip {
+		samples := uint64(stream.granule) - stream.preSkip
+		if info.Codec == "opus" {
+			// Opus granule positions always count 48 kHz samples
+			info.Duration = samplesDuration(samples, 48000)
+		} else {
+			info.Duration = samplesDuration(samples, info.SampleRate)
+		}
+	}
+	return info, nil
+}
+
+// parseOggHeader identifies the first packet of a logical stream. It returns
+// nil for codecs other than Opus and Vorbis.
+func parseOggHeader(packet []byte) (*Info, uint64) {
+	switch {
+	case len(packet) >= 19 && bytes.HasPrefix(packet, []byte("OpusHead")):
+		channels := int(packet[9])
+		preSkip := uint64(binary.LittleEndian.Uint16(packet[10:12]))
+		sampleRate := int(binary.LittleEndian.Uint32(packet[12:16]))
+		if sampleRate == 0 {
+			sampleRate = 48000
+		}
+		return &Info{Format: FormatOgg, Codec: "opus", SampleRate: sampleRate, Channels: channels}, preSkip
+	case len(packet) >= 30 && bytes.HasPrefix(packet, []byte("\x01vorbis")):
+		channels := int(packet[11])
+		sampleRate := int(binary.LittleEndian.Uint32(packet[12:16]))
+		if sampleRate == 0 {
+			return nil, 0
+		}
+		return &Info{Format: FormatOgg, Codec: "vorbis", SampleRate: sampleRate, Channels: channels}, 0
+	}
+	return nil, 0
+}
@@ -0,0 +1,115 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// wavCodecs maps WAVE format tags to codec names
+var wavCodecs = map[uint16]string{
+	0x0001: "pcm",
+	0x0003: "float",
+	0x0006: "alaw",
+	0x0007: "mulaw",
+}
+
+// wavStreaming is the data size written by recorders that do not know the length upfront
+const wavStreaming = 0xFFFFFFFF
+
+// probeWAV reads a RIFF/WAVE file. The data chunk is read through to make
+// sure it is as long as declared.
+func probeWAV(r io.Reader) (*Info, error) {
+	var header [12]byte
+	if err := readFull(r, header[:], "WAV header"); err != nil {
+		return nil, err
+	}
+
+	var info *Info
+	var byteRate uint32
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, corrupt("WAV has no data chunk")
+			}
+			return nil, err
+		}
+		id := string(chunk[:4])
+		size := binary.LittleEndian.Uint32(chunk[4:])
+
+		switch id {
+		case "fmt ":
+			if size < 16 || size > 1024 {
+				return nil, corrupt("invalid WAV fmt chunk size %d", size)
+			}
+			buf := make([]byte, size)
+			if err := readFull(r, buf, "WAV fmt chunk"); err != nil {
+				return nil, err
+			}
+			var err error
+			if info, byteRate, err = parseWAVFormat(buf); err != nil {
+				return nil, err
+			}
+			if size%2 == 1 {
+				if err := skip(r, 1, "WAV fmt chunk"); err != nil {
+					return nil, err
+				}
+			}
+
+		case "data":
+			if info == nil {
+				return nil, corrupt("WAV data chunk precedes fmt chunk")
+			}
+			length, err := io.Copy(io.Discard, io.LimitReader(r, int64(size)))
+			if err != nil {
+				return nil, err
+			}
+			if size == wavStreaming {
+				rest, err := io.Copy(io.Discard, r)
+				if err != nil {
+					return nil, err
+				}
+				length += rest
+			} else if length < int64(size) {
+				return nil, corrupt("WAV data chunk is truncated")
+			}
+			info.Duration = samplesDuration(uint64(length), int(byteRate))
+			return info, nil
+
+		default:
+			if err := skip(r, int64(size)+int64(size%2), "WAV "+id+" chunk"); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// parseWAVFormat parses a fmt chunk and returns the stream properties and byte rate
+func parseWAVFormat(buf []byte) (*Info, uint32, error) {
+	tag := binary.LittleEndian.Uint16(buf[0:2])
+	channels := binary.LittleEndian.Uint16(buf[2:4])
+	sampleRate := binary.LittleEndian.Uint32(buf[4:8])
+	byteRate := binary.LittleEndian.Uint32(buf[8:12])
+	blockAlign := binary.LittleEndian.Uint16(buf[12:14])
+
+	if tag == 0xFFFE { // WAVE_FORMAT_EXTENSIBLE: the real tag starts the subformat GUID
+		if len(buf) < 40 {
+			return nil, 0, corrupt("WAV extensible fmt chunk is too short")
+		}
+		tag = binary.LittleEndian.Uint16(buf[24:26])
+	}
+	codec, ok := wavCodecs[tag]
+	if !ok {
+		return nil, 0, ErrUnsupported
+	}
+	if channels == 0 || sampleRate == 0 || blockAlign == 0 || byteRate != sampleRate*uint32(blockAlign) {
+		return nil, 0, corrupt("inconsistent WAV format")
+	}
+
+	return &Info{
+		Format:     FormatWAV,
+		Codec:      codec,
+		SampleRate: int(sampleRate),
+		Channels:   int(channels),
+	}, byteRate, nil
+}
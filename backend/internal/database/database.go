@@ -81,16 +81,50 @@ func AutoMigrate(db *gorm.DB) error {
 		&repository.FaceEntity{},
 		&repository.EncounterEntity{},
 		&repository.JobEntity{},
+		&repository.EmbeddingModelEntity{},
+		&repository.WebhookDeliveryEntity{},
+		&repository.UploadEntity{},
+		&repository.UploadChunkEntity{},
+		&repository.TranscriptSegmentEntity{},
+		&repository.SuggestionCacheEntity{},
+		&repository.ProfileVersionEntity{},
+		&repository.ProfileUpdateEntity{},
 	)
 
 	if err != nil {
 		return fmt.Errorf("migration failed: %w", err)
 	}
 
+	if err := seedEmbeddingModels(db); err != nil {
+		return fmt.Errorf("seeding embedding models failed: %w", err)
+	}
+
 	log.Println("Database migrations completed successfully")
 	return nil
 }
 
+// seedEmbeddingModels registers the models shipped with the app and server if they are missing
+func seedEmbeddingModels(db *gorm.DB) error {
+	description := "FaceNet (TensorFlow Lite) used by the Android app and backend/ml/extract_embedding.py"
+	defaults := []repository.EmbeddingModelEntity{
+		{
+			ModelVersion:     "facenet-tflite-v1",
+			Family:           "facenet-tflite",
+			Dimension:        512,
+			Metric:           "cosine",
+			DefaultThreshold: 0.6,
+			Description:      &description,
+		},
+	}
+
+	for _, model := range defaults {
+		if err := db.Where("model_version = ?", model.ModelVersion).FirstOrCreate(&model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetConfigFromEnv reads database configuration from environment variables
 func GetConfigFromEnv() *Config {
 	driver := os.Getenv("DB_DRIVER")
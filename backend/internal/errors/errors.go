@@ -66,6 +66,14 @@ func InternalServerError(detail string) *AppError {
 	return NewAppError(http.StatusInternalServerError, "Internal Server Error", detail)
 }
 
+func ServiceUnavailable(detail string) *AppError {
+	return NewAppError(http.StatusServiceUnavailable, "Service Unavailable", detail)
+}
+
+func GatewayTimeout(detail string) *AppError {
+	return NewAppError(http.StatusGatewayTimeout, "Gateway Timeout", detail)
+}
+
 // RespondWithError sends a RFC 7807 compliant error response
 func RespondWithError(c *gin.Context, err *AppError) {
 	traceID := c.GetString("trace_id")
@@ -0,0 +1,232 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/utils"
+)
+
+// Config holds the settings of job event streaming
+type Config struct {
+	// Heartbeat is how often idle streams send a comment to keep proxies from closing them
+	Heartbeat time.Duration
+	// BufferSize is how many recent events are kept per job for resuming streams
+	BufferSize int
+	// Retention is how long the events of a job are kept after its last event
+	// once nobody is subscribed
+	Retention time.Duration
+	// SubscriberBuffer is how many events may queue up for a slow subscriber
+	// before it is dropped; it then resumes with Last-Event-ID
+	SubscriberBuffer int
+}
+
+// DefaultConfig returns the default broker settings
+func DefaultConfig() Config {
+	return Config{
+		Heartbeat:        15 * time.Second,
+		BufferSize:       64,
+		Retention:        10 * time.Minute,
+		SubscriberBuffer: 32,
+	}
+}
+
+// GetConfigFromEnv returns the event streaming settings from environment variables
+func GetConfigFromEnv() Config {
+	defaults := DefaultConfig()
+	return Config{
+		Heartbeat:        utils.GetEnvDuration("JOB_EVENTS_HEARTBEAT", defaults.Heartbeat),
+		BufferSize:       utils.GetEnvInt("JOB_EVENTS_BUFFER_SIZE", defaults.BufferSize),
+		Retention:        utils.GetEnvDuration("JOB_EVENTS_RETENTION", defaults.Retention),
+		SubscriberBuffer: defaults.SubscriberBuffer,
+	}
+}
+
+// Subscription receives the events of one job
+type Subscription struct {
+	// Replay holds the buffered events after the requested ID, oldest first
+	Replay []models.JobEvent
+	// Missed is set when the requested ID cannot be resumed from, because
+	// events after it were evicted or it was issued by another process.
+	// The subscriber should start from the job's current state instead.
+	Missed bool
+	// Latest is the job's latest event, or nil when it has none in this process
+	Latest *models.JobEvent
+	// C delivers new events. It is closed when the subscription is closed or
+	// the subscriber fell too far behind.
+	C <-chan models.JobEvent
+
+	broker *Broker
+	jobID  string
+	ch     chan models.JobEvent
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	if s.broker != nil {
+		s.broker.unsubscribe(s.jobID, s.ch)
+	}
+}
+
+type topic struct {
+	events []models.JobEvent
+	// knownAfter is the ID after which events contains the complete history:
+	// the last ID issued before the topic was created, or the last evicted ID
+	knownAfter  int64
+	subscribers map[chan models.JobEvent]struct{}
+	updatedAt   time.Time
+}
+
+// Broker is an in-process publish/subscribe hub for job events. It keeps a
+// short history per job so clients can resume a stream after reconnecting.
+// Event IDs start from the process start time in microseconds, so IDs from
+// an earlier process are recognised as stale rather than replayed.
+type Broker struct {
+	config Config
+
+	mu        sync.Mutex
+	lastID    int64
+	topics    map[string]*topic
+	lastSweep time.Time
+}
+
+// NewBroker creates a Broker
+func NewBroker(config Config) *Broker {
+	defaults := DefaultConfig()
+	if config.Heartbeat <= 0 {
+		config.Heartbeat = defaults.Heartbeat
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaults.BufferSize
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaults.Retention
+	}
+	if config.SubscriberBuffer <= 0 {
+		config.SubscriberBuffer = defaults.SubscriberBuffer
+	}
+
+	return &Broker{
+		config: config,
+		lastID: time.Now().UnixMicro(),
+		topics: make(map[string]*topic),
+	}
+}
+
+// Config returns the effective settings
+func (b *Broker) Config() Config {
+	return b.config
+}
+
+// Publish assigns the event an ID and delivers it to the job's subscribers
+func (b *Broker) Publish(event models.JobEvent) models.JobEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.sweep(now)
+
+	b.lastID++
+	event.ID = b.lastID
+	if event.Time.IsZero() {
+		event.Time = now
+	}
+
+	t := b.topic(event.JobID)
+	t.updatedAt = now
+	t.events = append(t.events, event)
+	if overflow := len(t.events) - b.config.BufferSize; overflow > 0 {
+		t.knownAfter = t.events[overflow-1].ID
+		t.events = append([]models.JobEvent(nil), t.events[overflow:]...)
+	}
+
+	for ch := range t.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Too far behind: drop the subscriber so it reconnects and resumes
+			delete(t.subscribers, ch)
+			close(ch)
+		}
+	}
+	return event
+}
+
+// Subscribe starts receiving the events of a job. lastEventID is the ID of
+// the last event the subscriber has seen, or 0 for a new subscriber.
+func (b *Broker) Subscribe(jobID string, lastEventID int64) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(jobID)
+	ch := make(chan models.JobEvent, b.config.SubscriberBuffer)
+	t.subscribers[ch] = struct{}{}
+
+	sub := &Subscription{
+		C:      ch,
+		broker: b,
+		jobID:  jobID,
+		ch:     ch,
+	}
+	if len(t.events) > 0 {
+		latest := t.events[len(t.events)-1]
+		sub.Latest = &latest
+	}
+
+	if lastEventID > 0 {
+		if lastEventID < t.knownAfter || lastEventID > b.lastID {
+			sub.Missed = true
+			return sub
+		}
+		for _, event := range t.events {
+			if event.ID > lastEventID {
+				sub.Replay = append(sub.Replay, event)
+			}
+		}
+	}
+	return sub
+}
+
+func (b *Broker) unsubscribe(jobID string, ch chan models.JobEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[jobID]
+	if !ok {
+		return
+	}
+	if _, ok := t.subscribers[ch]; ok {
+		delete(t.subscribers, ch)
+		close(ch)
+	}
+	t.updatedAt = time.Now()
+}
+
+// topic returns the topic of a job, creating it if needed. b.mu must be held.
+func (b *Broker) topic(jobID string) *topic {
+	t, ok := b.topics[jobID]
+	if !ok {
+		t = &topic{
+			knownAfter:  b.lastID,
+			subscribers: make(map[chan models.JobEvent]struct{}),
+			updatedAt:   time.Now(),
+		}
+		b.topics[jobID] = t
+	}
+	return t
+}
+
+// sweep forgets jobs without subscribers whose last event is older than the
+// retention. It runs at most once per minute. b.mu must be held.
+func (b *Broker) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < time.Minute {
+		return
+	}
+	b.lastSweep = now
+	for jobID, t := range b.topics {
+		if len(t.subscribers) == 0 && now.Sub(t.updatedAt) > b.config.Retention {
+			delete(b.topics, jobID)
+		}
+	}
+}
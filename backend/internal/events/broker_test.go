@@ -0,0 +1,110 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func publish(b *Broker, jobID string, stage models.JobStage) models.JobEvent {
+	return b.Publish(models.JobEvent{JobID: jobID, Status: models.JobStatusRunning, Stage: stage})
+}
+
+func receive(t *testing.T, sub *Subscription) models.JobEvent {
+	t.Helper()
+	select {
+	case event, ok := <-sub.C:
+		require.True(t, ok, "subscription was closed")
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return models.JobEvent{}
+	}
+}
+
+func TestBroker_DeliversToSubscribersOfTheJob(t *testing.T) {
+	b := NewBroker(DefaultConfig())
+	sub := b.Subscribe("j-1", 0)
+	defer sub.Close()
+	other := b.Subscribe("j-2", 0)
+	defer other.Close()
+	assert.Nil(t, sub.Latest)
+
+	first := publish(b, "j-1", models.JobStageTranscribing)
+	second := publish(b, "j-1", models.JobStageSummarizing)
+	assert.Greater(t, second.ID, first.ID)
+	assert.False(t, first.Time.IsZero())
+
+	assert.Equal(t, first, receive(t, sub))
+	assert.Equal(t, second, receive(t, sub))
+	assert.Empty(t, other.C)
+}
+
+func TestBroker_ResumesAfterLastEventID(t *testing.T) {
+	b := NewBroker(DefaultConfig())
+	first := publish(b, "j-1", models.JobStageUploaded)
+	second := publish(b, "j-1", models.JobStageTranscribing)
+	third := publish(b, "j-1", models.JobStageSummarizing)
+
+	sub := b.Subscribe("j-1", first.ID)
+	defer sub.Close()
+	assert.False(t, sub.Missed)
+	assert.Equal(t, []models.JobEvent{second, third}, sub.Replay)
+	require.NotNil(t, sub.Latest)
+	assert.Equal(t, third.ID, sub.Latest.ID)
+
+	// Fully caught up
+	sub = b.Subscribe("j-1", third.ID)
+	defer sub.Close()
+	assert.False(t, sub.Missed)
+	assert.Empty(t, sub.Replay)
+}
+
+func TestBroker_ReportsMissedEvents(t *testing.T) {
+	b := NewBroker(Config{BufferSize: 2})
+	first := publish(b, "j-1", models.JobStageUploaded)
+	publish(b, "j-1", models.JobStageTranscribing)
+	publish(b, "j-1", models.JobStageSummarizing)
+
+	// The event after first was evicted
+	sub := b.Subscribe("j-1", first.ID-1)
+	defer sub.Close()
+	assert.True(t, sub.Missed)
+	assert.Empty(t, sub.Replay)
+
+	// IDs from a previous process are older than anything this broker issued
+	// for the job, or newer than anything it issued at all
+	sub = b.Subscribe("j-2", 1)
+	defer sub.Close()
+	assert.True(t, sub.Missed)
+	sub = b.Subscribe("j-1", time.Now().Add(time.Hour).UnixMicro())
+	defer sub.Close()
+	assert.True(t, sub.Missed)
+}
+
+func TestBroker_DropsSlowSubscribers(t *testing.T) {
+	b := NewBroker(Config{SubscriberBuffer: 1})
+	sub := b.Subscribe("j-1", 0)
+	defer sub.Close()
+
+	publish(b, "j-1", models.JobStageUploaded)
+	publish(b, "j-1", models.JobStageTranscribing)
+
+	receive(t, sub)
+	_, ok := <-sub.C
+	assert.False(t, ok)
+}
+
+func TestBroker_CloseStopsDelivery(t *testing.T) {
+	b := NewBroker(DefaultConfig())
+	sub := b.Subscribe("j-1", 0)
+	sub.Close()
+	sub.Close()
+
+	publish(b, "j-1", models.JobStageUploaded)
+	_, ok := <-sub.C
+	assert.False(t, ok)
+}
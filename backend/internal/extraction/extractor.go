@@ -0,0 +1,154 @@
+package extraction
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/utils"
+)
+
+// Backend names accepted by EXTRACTION_BACKEND
+const (
+	BackendPython = "python"
+	BackendHTTP   = "http"
+	BackendStub   = "stub"
+)
+
+// DefaultModelVersion is the embedding model computed by the bundled Python script
+const DefaultModelVersion = "facenet-tflite-v1"
+
+// Extractor detects faces in an encoded image and computes their embeddings
+type Extractor interface {
+	backend
+	// ModelVersion returns the registered embedding model the embeddings belong to
+	ModelVersion() string
+	// Dimension returns the length of the embeddings, or 0 if it was not declared
+	Dimension() int
+}
+
+// backend is implemented by every extraction backend
+type backend interface {
+	Extract(ctx context.Context, image []byte) ([]models.DetectedFace, error)
+	// Name returns the backend name for logging
+	Name() string
+	// Close releases workers and connections held by the extractor
+	Close()
+}
+
+// Config selects and configures an extraction backend
+type Config struct {
+	Backend string
+	// ModelVersion is the embedding model the backend serves. It must be
+	// registered, and embeddings are only matched within its family.
+	ModelVersion string
+	// ModelDimension is the embedding length the backend produces, checked
+	// against the registered model at startup. 0 leaves it unchecked; the
+	// stub always declares its own dimension.
+	ModelDimension int
+	Pool           PoolConfig
+	HTTP           HTTPConfig
+	Stub           StubConfig
+}
+
+// GetConfigFromEnv returns the extraction settings from environment variables
+func GetConfigFromEnv() Config {
+	pool := DefaultPoolConfig()
+	httpConfig := DefaultHTTPConfig()
+	stub := DefaultStubConfig()
+	script := absPath(utils.GetEnv("EXTRACTION_SCRIPT_PATH", pool.Args[0]))
+
+	return Config{
+		Backend:        utils.GetEnv("EXTRACTION_BACKEND", BackendPython),
+		ModelVersion:   utils.GetEnv("EXTRACTION_MODEL_VERSION", DefaultModelVersion),
+		ModelDimension: utils.GetEnvInt("EXTRACTION_MODEL_DIMENSION", 0),
+		Pool: PoolConfig{
+			Command:        absPath(utils.GetEnv("EXTRACTION_PYTHON_PATH", pool.Command)),
+			Args:           []string{script, "--serve"},
+			Dir:            filepath.Dir(script),
+			Workers:        utils.GetEnvInt("EXTRACTION_WORKERS", pool.Workers),
+			QueueSize:      utils.GetEnvInt("EXTRACTION_QUEUE_SIZE", pool.QueueSize),
+			RequestTimeout: utils.GetEnvDuration("EXTRACTION_TIMEOUT", pool.RequestTimeout),
+			StartTimeout:   utils.GetEnvDuration("EXTRACTION_START_TIMEOUT", pool.StartTimeout),
+			HealthInterval: utils.GetEnvDuration("EXTRACTION_HEALTH_INTERVAL", pool.HealthInterval),
+			HealthTimeout:  pool.HealthTimeout,
+			RestartBackoff: pool.RestartBackoff,
+		},
+		HTTP: HTTPConfig{
+			URL:     utils.GetEnv("EXTRACTION_HTTP_URL", httpConfig.URL),
+			APIKey:  utils.GetEnv("EXTRACTION_HTTP_API_KEY", httpConfig.APIKey),
+			Timeout: utils.GetEnvDuration("EXTRACTION_TIMEOUT", httpConfig.Timeout),
+		},
+		Stub: StubConfig{
+			Dimension: utils.GetEnvInt("EXTRACTION_STUB_DIMENSION", stub.Dimension),
+		},
+	}
+}
+
+// New creates the extractor selected by config.Backend
+func New(config Config) (Extractor, error) {
+	if config.ModelVersion == "" {
+		config.ModelVersion = DefaultModelVersion
+	}
+	if config.ModelDimension < 0 {
+		return nil, fmt.Errorf("invalid model dimension %d", config.ModelDimension)
+	}
+
+	var b backend
+	switch config.Backend {
+	case BackendPython, "":
+		b = NewPool(config.Pool)
+	case BackendHTTP:
+		httpExtractor, err := NewHTTPExtractor(config.HTTP)
+		if err != nil {
+			return nil, err
+		}
+		b = httpExtractor
+	case BackendStub:
+		stub := NewStubExtractor(config.Stub)
+		if config.ModelDimension != 0 && config.ModelDimension != stub.config.Dimension {
+			return nil, fmt.Errorf("model dimension %d does not match the stub dimension %d", config.ModelDimension, stub.config.Dimension)
+		}
+		config.ModelDimension = stub.config.Dimension
+		b = stub
+	default:
+		return nil, fmt.Errorf("unknown extraction backend %q (expected %s, %s or %s)", config.Backend, BackendPython, BackendHTTP, BackendStub)
+	}
+	return &modelExtractor{backend: b, modelVersion: config.ModelVersion, dimension: config.ModelDimension}, nil
+}
+
+// modelExtractor labels a backend with the model it serves
+type modelExtractor struct {
+	backend
+	modelVersion string
+	dimension    int
+}
+
+func (e *modelExtractor) ModelVersion() string { return e.modelVersion }
+func (e *modelExtractor) Dimension() int       { return e.dimension }
+
+// findFile returns the first candidate that exists, or the last one.
+// This lets the defaults work whether the server is started from the repository root or from backend/.
+func findFile(candidates ...string) string {
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// absPath makes a relative path absolute so it survives the worker's change
+// of directory. Bare command names such as "python3" are left for a PATH lookup.
+func absPath(path string) string {
+	if !strings.ContainsAny(path, "/"+string(filepath.Separator)) {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,159 @@
+package extraction
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func testImage(t *testing.T, seed uint8, flat bool) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 80))
+	for x := 0; x < 100; x++ {
+		for y := 0; y < 80; y++ {
+			if flat {
+				img.Set(x, y, color.RGBA{R: seed, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{R: uint8(x) + seed, G: uint8(y), A: 255})
+			}
+		}
+	}
+	return encodePNG(t, img)
+}
+
+func TestNew_SelectsBackend(t *testing.T) {
+	stub, err := New(Config{Backend: BackendStub})
+	assert.NoError(t, err)
+	assert.Equal(t, BackendStub, stub.Name())
+
+	httpExtractor, err := New(Config{Backend: BackendHTTP, HTTP: HTTPConfig{URL: "http://localhost:9000/extract"}})
+	assert.NoError(t, err)
+	assert.Equal(t, BackendHTTP, httpExtractor.Name())
+
+	_, err = New(Config{Backend: BackendHTTP, HTTP: HTTPConfig{URL: "ftp://example.com"}})
+	assert.Error(t, err)
+
+	_, err = New(Config{Backend: "onnx"})
+	assert.Error(t, err)
+}
+
+func TestNew_LabelsModel(t *testing.T) {
+	stub, err := New(Config{Backend: BackendStub, Stub: StubConfig{Dimension: 128}})
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultModelVersion, stub.ModelVersion())
+	assert.Equal(t, 128, stub.Dimension())
+
+	_, err = New(Config{Backend: BackendStub, ModelDimension: 512, Stub: StubConfig{Dimension: 128}})
+	assert.Error(t, err)
+
+	httpExtractor, err := New(Config{Backend: BackendHTTP, ModelVersion: "arcface-r100-v1", HTTP: HTTPConfig{URL: "http://localhost:9000/extract"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "arcface-r100-v1", httpExtractor.ModelVersion())
+	assert.Equal(t, 0, httpExtractor.Dimension())
+}
+
+func TestGetConfigFromEnv_ResolvesWorkerPaths(t *testing.T) {
+	t.Setenv("EXTRACTION_PYTHON_PATH", "python3")
+	t.Setenv("EXTRACTION_SCRIPT_PATH", "ml/extract_embedding.py")
+
+	config := GetConfigFromEnv()
+	script, err := filepath.Abs("ml/extract_embedding.py")
+	assert.NoError(t, err)
+	assert.Equal(t, "python3", config.Pool.Command)
+	assert.Equal(t, []string{script, "--serve"}, config.Pool.Args)
+	assert.Equal(t, filepath.Dir(script), config.Pool.Dir)
+}
+
+func TestStubExtractor_Deterministic(t *testing.T) {
+	extractor := NewStubExtractor(DefaultStubConfig())
+	imageA := testImage(t, 0, false)
+	imageB := testImage(t, 50, false)
+
+	first, err := extractor.Extract(context.Background(), imageA)
+	assert.NoError(t, err)
+	assert.Len(t, first, 1)
+	assert.Len(t, first[0].Embedding, 512)
+	assert.Equal(t, 48, first[0].BoundingBox.Width) // 3/5 of the shorter side
+
+	second, err := extractor.Extract(context.Background(), imageA)
+	assert.NoError(t, err)
+	assert.Equal(t, first[0].Embedding, second[0].Embedding)
+
+	other, err := extractor.Extract(context.Background(), imageB)
+	assert.NoError(t, err)
+	similarity := utils.DotProduct(first[0].Embedding, other[0].Embedding)
+	assert.Less(t, similarity, 0.5)
+}
+
+func TestStubExtractor_NoFaceAndInvalidImage(t *testing.T) {
+	extractor := NewStubExtractor(StubConfig{Dimension: 128})
+
+	faces, err := extractor.Extract(context.Background(), testImage(t, 10, true))
+	assert.NoError(t, err)
+	assert.NotNil(t, faces)
+	assert.Empty(t, faces)
+
+	_, err = extractor.Extract(context.Background(), []byte("not an image"))
+	assert.Error(t, err)
+}
+
+func TestHTTPExtractor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch string(body) {
+		case "ok":
+			assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+			assert.Equal(t, "application/octet-stream", r.Header.Get("Content-Type"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"faces":[{"bbox":{"x":1,"y":2,"width":30,"height":40},"confidence":0.9,"embedding":[0.6,0.8]}]}`))
+		case "invalid":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"error":"failed to decode image"}`))
+		case "busy":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "slow":
+			time.Sleep(500 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	extractor, err := NewHTTPExtractor(HTTPConfig{URL: server.URL, APIKey: "secret", Timeout: 100 * time.Millisecond})
+	assert.NoError(t, err)
+	defer extractor.Close()
+
+	faces, err := extractor.Extract(context.Background(), []byte("ok"))
+	assert.NoError(t, err)
+	assert.Len(t, faces, 1)
+	assert.Equal(t, 30, faces[0].BoundingBox.Width)
+	assert.Equal(t, []float32{0.6, 0.8}, faces[0].Embedding)
+
+	_, err = extractor.Extract(context.Background(), []byte("invalid"))
+	assert.EqualError(t, err, "failed to decode image")
+
+	_, err = extractor.Extract(context.Background(), []byte("busy"))
+	assert.ErrorIs(t, err, ErrQueueFull)
+
+	_, err = extractor.Extract(context.Background(), []byte("slow"))
+	assert.ErrorIs(t, err, ErrTimeout)
+
+	_, err = extractor.Extract(context.Background(), []byte("boom"))
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
@@ -0,0 +1,141 @@
+package extraction
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+)
+
+// HTTPConfig holds the settings of an external extraction service
+type HTTPConfig struct {
+	// URL receives the raw image as the POST body
+	URL string
+	// APIKey is sent as a bearer token when set
+	APIKey  string
+	Timeout time.Duration
+}
+
+// DefaultHTTPConfig returns the default HTTP extractor settings
+func DefaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		Timeout: 30 * time.Second,
+	}
+}
+
+// HTTPExtractor delegates extraction to an external service.
+//
+// The service receives the encoded image as an application/octet-stream POST body and
+// answers 200 with {"faces": [{"bbox", "confidence", "embedding"}]}, the same shape as the
+// Python worker. 400/415/422 mean the image could not be processed and may carry
+// {"error"} or a Problem {"detail"}; 429 and 503 mean the service is busy.
+type HTTPExtractor struct {
+	config HTTPConfig
+	client *http.Client
+}
+
+// NewHTTPExtractor creates an HTTPExtractor
+func NewHTTPExtractor(config HTTPConfig) (*HTTPExtractor, error) {
+	parsed, err := url.Parse(config.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid extraction URL %q", config.URL)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultHTTPConfig().Timeout
+	}
+
+	return &HTTPExtractor{
+		config: config,
+		client: &http.Client{},
+	}, nil
+}
+
+// Extract posts the image to the extraction service
+func (e *HTTPExtractor) Extract(ctx context.Context, image []byte) ([]models.DetectedFace, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.URL, bytes.NewReader(image))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Accept", "application/json")
+	if e.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.config.APIKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, contextError(ctx)
+		}
+		log.Printf("extraction service request failed: %v", err)
+		return nil, ErrUnavailable
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFrameSize))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, contextError(ctx)
+		}
+		return nil, ErrUnavailable
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		var output struct {
+			Faces []models.DetectedFace `json:"faces"`
+		}
+		if err := json.Unmarshal(body, &output); err != nil {
+			log.Printf("extraction service returned invalid JSON: %v", err)
+			return nil, ErrUnavailable
+		}
+		return output.Faces, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		return nil, ErrQueueFull
+	case resp.StatusCode == http.StatusBadRequest ||
+		resp.StatusCode == http.StatusUnsupportedMediaType ||
+		resp.StatusCode == http.StatusUnprocessableEntity:
+		return nil, errors.New(errorMessage(body, resp.Status))
+	default:
+		log.Printf("extraction service returned %s", resp.Status)
+		return nil, ErrUnavailable
+	}
+}
+
+// Name returns the backend name
+func (e *HTTPExtractor) Name() string {
+	return BackendHTTP
+}
+
+// Close releases idle connections
+func (e *HTTPExtractor) Close() {
+	e.client.CloseIdleConnections()
+}
+
+// errorMessage extracts a human readable message from an error body
+func errorMessage(body []byte, fallback string) string {
+	var payload struct {
+		Error  string `json:"error"`
+		Detail string `json:"detail"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		if payload.Error != "" {
+			return payload.Error
+		}
+		if payload.Detail != "" {
+			return payload.Detail
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,185 @@
+package extraction
+
+import (
+	"context"
+	"errors"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+)
+
+// Errors returned by extractors. Handlers match on their messages.
+var (
+	ErrQueueFull   = errors.New("extraction queue is full")
+	ErrTimeout     = errors.New("extraction timed out")
+	ErrUnavailable = errors.New("extraction worker unavailable")
+	ErrClosed      = errors.New("extraction pool is closed")
+)
+
+// PoolConfig holds the settings of the Python worker pool
+type PoolConfig struct {
+	// Command and Args start one worker process speaking the framed protocol on stdin/stdout
+	Command string
+	Args    []string
+	// Env is appended to the server's environment for worker processes
+	Env []string
+	// Dir is the working directory of worker processes; the server's when empty.
+	// A relative Command or script path is resolved against it.
+	Dir string
+
+	Workers   int
+	QueueSize int
+
+	// RequestTimeout bounds queueing plus extraction of one image
+	RequestTimeout time.Duration
+	// StartTimeout bounds how long a new worker may take to load its models
+	StartTimeout time.Duration
+	// HealthInterval is how often idle workers are pinged
+	HealthInterval time.Duration
+	HealthTimeout  time.Duration
+	// RestartBackoff is the minimum delay between failed start attempts of a worker
+	RestartBackoff time.Duration
+}
+
+// DefaultPoolConfig returns the default pool settings for the bundled Python script
+func DefaultPoolConfig() PoolConfig {
+	script := absPath(findFile("backend/ml/extract_embedding.py", "ml/extract_embedding.py"))
+	return PoolConfig{
+		Command:        absPath(findFile("backend/ml/.venv/bin/python", "ml/.venv/bin/python", "python3")),
+		Args:           []string{script, "--serve"},
+		Dir:            filepath.Dir(script),
+		Workers:        2,
+		QueueSize:      16,
+		RequestTimeout: 30 * time.Second,
+		StartTimeout:   60 * time.Second,
+		HealthInterval: 30 * time.Second,
+		HealthTimeout:  5 * time.Second,
+		RestartBackoff: 5 * time.Second,
+	}
+}
+
+// job is one queued extraction
+type job struct {
+	ctx    context.Context
+	image  []byte
+	result chan jobResult
+}
+
+type jobResult struct {
+	faces []models.DetectedFace
+	err   error
+}
+
+// Pool runs a fixed number of long-lived extraction workers fed from a bounded queue.
+// Each worker keeps its models loaded between requests, is restarted when it crashes,
+// hangs past a deadline or fails a health check.
+type Pool struct {
+	config PoolConfig
+	queue  chan *job
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewPool creates a Pool and starts its workers in the background
+func NewPool(config PoolConfig) *Pool {
+	defaults := DefaultPoolConfig()
+	if config.Workers <= 0 {
+		config.Workers = defaults.Workers
+	}
+	if config.QueueSize < 0 {
+		config.QueueSize = 0
+	}
+	if config.RequestTimeout <= 0 {
+		config.RequestTimeout = defaults.RequestTimeout
+	}
+	if config.StartTimeout <= 0 {
+		config.StartTimeout = defaults.StartTimeout
+	}
+	if config.HealthInterval <= 0 {
+		config.HealthInterval = defaults.HealthInterval
+	}
+	if config.HealthTimeout <= 0 {
+		config.HealthTimeout = defaults.HealthTimeout
+	}
+	if config.RestartBackoff <= 0 {
+		config.RestartBackoff = defaults.RestartBackoff
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		config: config,
+		queue:  make(chan *job, config.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		w := newWorker(i, config)
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			w.run(p.ctx, p.queue)
+		}()
+	}
+	return p
+}
+
+// Extract detects every face in an encoded image.
+// It fails fast with ErrQueueFull when all workers are busy and the queue is full.
+func (p *Pool) Extract(ctx context.Context, image []byte) ([]models.DetectedFace, error) {
+	if p.ctx.Err() != nil {
+		return nil, ErrClosed
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.config.RequestTimeout)
+	defer cancel()
+
+	j := &job{
+		ctx:    ctx,
+		image:  image,
+		result: make(chan jobResult, 1),
+	}
+
+	select {
+	case p.queue <- j:
+	default:
+		return nil, ErrQueueFull
+	}
+
+	select {
+	case r := <-j.result:
+		return r.faces, r.err
+	case <-ctx.Done():
+		return nil, contextError(ctx)
+	case <-p.ctx.Done():
+		return nil, ErrClosed
+	}
+}
+
+// Name returns the backend name
+func (p *Pool) Name() string {
+	return BackendPython
+}
+
+// Close stops every worker and waits for their processes to exit
+func (p *Pool) Close() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+// contextError converts our own deadline into ErrTimeout and passes cancellation through
+func contextError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+	return ctx.Err()
+}
+
+func logf(workerID int, format string, args ...any) {
+	log.Printf("extraction worker %d: "+format, append([]any{workerID}, args...)...)
+}
@@ -0,0 +1,167 @@
+package extraction
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHelperWorker is not a real test. It is started as a worker process by the
+// tests below and answers according to the image contents.
+func TestHelperWorker(t *testing.T) {
+	if os.Getenv("EXTRACTION_HELPER_WORKER") != "1" {
+		return
+	}
+
+	for {
+		var req request
+		if err := readFrame(os.Stdin, &req); err != nil {
+			os.Exit(0)
+		}
+
+		resp := response{ID: req.ID}
+		if req.Op == opExtract {
+			switch string(req.Image) {
+			case "crash":
+				os.Exit(1)
+			case "slow":
+				time.Sleep(2 * time.Second)
+			case "invalid":
+				resp.Error = "failed to decode image"
+			}
+			if resp.Error == "" {
+				resp.Faces = []models.DetectedFace{{
+					BoundingBox: models.BoundingBox{Width: len(req.Image), Height: len(req.Image)},
+					Confidence:  0.9,
+					Embedding:   []float32{1, 0},
+				}}
+			}
+		}
+		_ = writeFrame(os.Stdout, &resp)
+	}
+}
+
+func helperConfig() PoolConfig {
+	config := DefaultPoolConfig()
+	config.Command = os.Args[0]
+	config.Args = []string{"-test.run=^TestHelperWorker$"}
+	config.Env = []string{"EXTRACTION_HELPER_WORKER=1"}
+	config.Dir = os.TempDir()
+	config.Workers = 1
+	config.RequestTimeout = 500 * time.Millisecond
+	config.StartTimeout = 5 * time.Second
+	config.RestartBackoff = time.Millisecond
+	return config
+}
+
+func TestPool_Extract(t *testing.T) {
+	pool := NewPool(helperConfig())
+	defer pool.Close()
+
+	for i := 0; i < 3; i++ {
+		faces, err := pool.Extract(context.Background(), []byte("image"))
+		assert.NoError(t, err)
+		assert.Len(t, faces, 1)
+		assert.Equal(t, 5, faces[0].BoundingBox.Width)
+	}
+}
+
+func TestPool_WorkerErrorKeepsWorker(t *testing.T) {
+	pool := NewPool(helperConfig())
+	defer pool.Close()
+
+	_, err := pool.Extract(context.Background(), []byte("invalid"))
+	assert.EqualError(t, err, "failed to decode image")
+
+	faces, err := pool.Extract(context.Background(), []byte("ok"))
+	assert.NoError(t, err)
+	assert.Len(t, faces, 1)
+}
+
+func TestPool_RestartsAfterCrash(t *testing.T) {
+	pool := NewPool(helperConfig())
+	defer pool.Close()
+
+	_, err := pool.Extract(context.Background(), []byte("crash"))
+	assert.ErrorIs(t, err, ErrUnavailable)
+
+	faces, err := pool.Extract(context.Background(), []byte("ok"))
+	assert.NoError(t, err)
+	assert.Len(t, faces, 1)
+}
+
+func TestPool_TimeoutRestartsWorker(t *testing.T) {
+	pool := NewPool(helperConfig())
+	defer pool.Close()
+
+	start := time.Now()
+	_, err := pool.Extract(context.Background(), []byte("slow"))
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	faces, err := pool.Extract(context.Background(), []byte("ok"))
+	assert.NoError(t, err)
+	assert.Len(t, faces, 1)
+}
+
+func TestPool_CallerCancellation(t *testing.T) {
+	pool := NewPool(helperConfig())
+	defer pool.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := pool.Extract(ctx, []byte("ok"))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPool_QueueFull(t *testing.T) {
+	config := helperConfig()
+	config.QueueSize = 1
+	pool := NewPool(config)
+	defer pool.Close()
+
+	var (
+		mu   sync.Mutex
+		full int
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := pool.Extract(context.Background(), []byte("slow"))
+			if err == ErrQueueFull {
+				mu.Lock()
+				full++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// One job runs and one waits; the rest are rejected immediately
+	assert.GreaterOrEqual(t, full, 3)
+}
+
+func TestPool_Closed(t *testing.T) {
+	pool := NewPool(helperConfig())
+	pool.Close()
+
+	_, err := pool.Extract(context.Background(), []byte("ok"))
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestPool_UnavailableWhenCommandMissing(t *testing.T) {
+	config := helperConfig()
+	config.Command = "/nonexistent/python"
+	pool := NewPool(config)
+	defer pool.Close()
+
+	_, err := pool.Extract(context.Background(), []byte("ok"))
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
@@ -0,0 +1,71 @@
+package extraction
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+)
+
+// maxFrameSize bounds a single frame so a misbehaving worker cannot make us allocate unbounded memory
+const maxFrameSize = 64 << 20
+
+// Operations understood by the worker
+const (
+	opExtract = "extract"
+	opPing    = "ping"
+)
+
+// request is sent to a worker. Image is base64-encoded by encoding/json.
+type request struct {
+	ID    uint64 `json:"id"`
+	Op    string `json:"op"`
+	Image []byte `json:"image,omitempty"`
+}
+
+// response is returned by a worker for the request with the same ID
+type response struct {
+	ID    uint64                `json:"id"`
+	Faces []models.DetectedFace `json:"faces,omitempty"`
+	Error string                `json:"error,omitempty"`
+}
+
+// writeFrame writes v as a JSON payload prefixed with its length as a big-endian uint32
+func writeFrame(w io.Writer, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxFrameSize {
+		return fmt.Errorf("frame too large: %d bytes", len(payload))
+	}
+
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(payload)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(payload)
+	return err
+}
+
+// readFrame reads one length-prefixed JSON payload into v
+func readFrame(r io.Reader, v any) error {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return fmt.Errorf("frame too large: %d bytes", size)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return err
+	}
+	return json.Unmarshal(payload, v)
+}
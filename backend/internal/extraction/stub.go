@@ -0,0 +1,126 @@
+package extraction
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"image"
+
+	// Register decoders so the stub accepts the same uploads as the real backends
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/utils"
+)
+
+// StubConfig holds the settings of the deterministic stub extractor
+type StubConfig struct {
+	// Dimension must match the registered model set by EXTRACTION_MODEL_VERSION
+	// (facenet-tflite-v1 is 512)
+	Dimension int
+}
+
+// DefaultStubConfig returns the default stub settings
+func DefaultStubConfig() StubConfig {
+	return StubConfig{
+		Dimension: 512,
+	}
+}
+
+// StubExtractor is a pure-Go extractor for tests and demos that need no TensorFlow.
+// Every decodable image contains exactly one centred face whose embedding is derived
+// from a hash of the image bytes, so the same file always yields the same embedding.
+// Images of a single flat colour contain no face.
+type StubExtractor struct {
+	config StubConfig
+}
+
+// NewStubExtractor creates a StubExtractor
+func NewStubExtractor(config StubConfig) *StubExtractor {
+	if config.Dimension <= 0 {
+		config.Dimension = DefaultStubConfig().Dimension
+	}
+	return &StubExtractor{
+		config: config,
+	}
+}
+
+// Extract returns the deterministic face of an image
+func (e *StubExtractor) Extract(ctx context.Context, data []byte) ([]models.DetectedFace, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+	if isFlat(img) {
+		return []models.DetectedFace{}, nil
+	}
+
+	bounds := img.Bounds()
+	size := min(bounds.Dx(), bounds.Dy()) * 3 / 5
+	return []models.DetectedFace{{
+		BoundingBox: models.BoundingBox{
+			X:      bounds.Min.X + (bounds.Dx()-size)/2,
+			Y:      bounds.Min.Y + (bounds.Dy()-size)/2,
+			Width:  size,
+			Height: size,
+		},
+		Confidence: 0.99,
+		Embedding:  stubEmbedding(data, e.config.Dimension),
+	}}, nil
+}
+
+// Name returns the backend name
+func (e *StubExtractor) Name() string {
+	return BackendStub
+}
+
+// Close is a no-op
+func (e *StubExtractor) Close() {}
+
+// stubEmbedding expands a SHA-256 of data into a normalized vector of the given dimension
+func stubEmbedding(data []byte, dim int) []float32 {
+	seed := sha256.Sum256(data)
+	embedding := make([]float32, 0, dim)
+
+	var counter [8]byte
+	for block := uint64(0); len(embedding) < dim; block++ {
+		binary.BigEndian.PutUint64(counter[:], block)
+		digest := sha256.Sum256(append(seed[:], counter[:]...))
+		for i := 0; i+4 <= len(digest) && len(embedding) < dim; i += 4 {
+			v := binary.BigEndian.Uint32(digest[i : i+4])
+			embedding = append(embedding, float32(v)/float32(1<<31)-1) // [-1, 1)
+		}
+	}
+	return utils.NormalizeL2(embedding)
+}
+
+// isFlat reports whether a grid of samples across the image all have the same colour
+func isFlat(img image.Image) bool {
+	const samples = 32
+
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return true
+	}
+
+	r0, g0, b0, a0 := img.At(bounds.Min.X, bounds.Min.Y).RGBA()
+	for i := 0; i < samples; i++ {
+		for j := 0; j < samples; j++ {
+			x := bounds.Min.X + i*bounds.Dx()/samples
+			y := bounds.Min.Y + j*bounds.Dy()/samples
+			r, g, b, a := img.At(x, y).RGBA()
+			if r != r0 || g != g0 || b != b0 || a != a0 {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,231 @@
+package extraction
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// process is one running worker process
+type process struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	exited chan struct{}
+}
+
+func startProcess(config PoolConfig) (*process, error) {
+	cmd := exec.Command(config.Command, config.Args...)
+	cmd.Env = append(os.Environ(), config.Env...)
+	cmd.Dir = config.Dir
+	cmd.Stderr = os.Stderr // Model loading logs end up in the server log
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	proc := &process{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReader(stdout),
+		exited: make(chan struct{}),
+	}
+	go func() {
+		_ = cmd.Wait()
+		close(proc.exited)
+	}()
+	return proc, nil
+}
+
+func (p *process) alive() bool {
+	select {
+	case <-p.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// kill terminates the process and waits until it has exited
+func (p *process) kill() {
+	_ = p.cmd.Process.Kill()
+	<-p.exited
+}
+
+// worker owns at most one process at a time and serves queued jobs sequentially
+type worker struct {
+	id     int
+	config PoolConfig
+
+	proc        *process
+	nextID      uint64
+	lastFailure time.Time
+}
+
+func newWorker(id int, config PoolConfig) *worker {
+	return &worker{
+		id:     id,
+		config: config,
+	}
+}
+
+func (w *worker) run(ctx context.Context, queue <-chan *job) {
+	defer w.discard()
+
+	// Load models up front so the first request does not pay for it
+	if err := w.ensureStarted(ctx); err != nil {
+		logf(w.id, "failed to start: %v", err)
+	}
+
+	ticker := time.NewTicker(w.config.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-queue:
+			j.result <- w.handle(ctx, j)
+		case <-ticker.C:
+			w.checkHealth(ctx)
+		}
+	}
+}
+
+func (w *worker) handle(poolCtx context.Context, j *job) jobResult {
+	// The caller may have given up while the job was queued
+	if j.ctx.Err() != nil {
+		return jobResult{err: contextError(j.ctx)}
+	}
+	if err := w.ensureStarted(poolCtx); err != nil {
+		logf(w.id, "unavailable: %v", err)
+		return jobResult{err: ErrUnavailable}
+	}
+
+	resp, err := w.roundTrip(j.ctx, &request{Op: opExtract, Image: j.image})
+	if err != nil {
+		if j.ctx.Err() != nil {
+			return jobResult{err: contextError(j.ctx)}
+		}
+		logf(w.id, "request failed: %v", err)
+		return jobResult{err: ErrUnavailable}
+	}
+	if resp.Error != "" {
+		// The worker is fine; the image could not be processed
+		return jobResult{err: errors.New(resp.Error)}
+	}
+	return jobResult{faces: resp.Faces}
+}
+
+// checkHealth pings an idle worker and replaces it if it does not answer
+func (w *worker) checkHealth(ctx context.Context) {
+	if w.proc == nil || !w.proc.alive() {
+		if err := w.ensureStarted(ctx); err != nil {
+			logf(w.id, "restart failed: %v", err)
+		}
+		return
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, w.config.HealthTimeout)
+	defer cancel()
+	if _, err := w.roundTrip(pingCtx, &request{Op: opPing}); err != nil {
+		logf(w.id, "health check failed, restarting: %v", err)
+		if err := w.ensureStarted(ctx); err != nil {
+			logf(w.id, "restart failed: %v", err)
+		}
+	}
+}
+
+// ensureStarted makes sure a live process is available, starting one if needed.
+// Start attempts are throttled by RestartBackoff so a broken setup does not spin.
+func (w *worker) ensureStarted(ctx context.Context) error {
+	if w.proc != nil && w.proc.alive() {
+		return nil
+	}
+	w.discard()
+
+	if wait := w.config.RestartBackoff - time.Since(w.lastFailure); wait > 0 {
+		return fmt.Errorf("waiting %v before restarting after a failure", wait.Round(time.Millisecond))
+	}
+
+	proc, err := startProcess(w.config)
+	if err != nil {
+		w.lastFailure = time.Now()
+		return err
+	}
+	w.proc = proc
+
+	startCtx, cancel := context.WithTimeout(ctx, w.config.StartTimeout)
+	defer cancel()
+	if _, err := w.roundTrip(startCtx, &request{Op: opPing}); err != nil {
+		w.lastFailure = time.Now()
+		return fmt.Errorf("worker did not become ready: %w", err)
+	}
+	return nil
+}
+
+// roundTrip sends one request and waits for its response. Any failure leaves the
+// stream in an unknown state, so the process is killed and restarted on next use.
+func (w *worker) roundTrip(ctx context.Context, req *request) (*response, error) {
+	proc := w.proc
+	w.nextID++
+	req.ID = w.nextID
+
+	type result struct {
+		resp *response
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		if err := writeFrame(proc.stdin, req); err != nil {
+			done <- result{err: err}
+			return
+		}
+		var resp response
+		if err := readFrame(proc.stdout, &resp); err != nil {
+			done <- result{err: err}
+			return
+		}
+		done <- result{resp: &resp}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			w.discard()
+			return nil, r.err
+		}
+		if r.resp.ID != req.ID {
+			w.discard()
+			return nil, fmt.Errorf("response id %d does not match request id %d", r.resp.ID, req.ID)
+		}
+		return r.resp, nil
+	case <-ctx.Done():
+		// A late answer would be read as the response to the next request
+		w.discard()
+		<-done
+		return nil, ctx.Err()
+	}
+}
+
+// discard kills the current process, if any
+func (w *worker) discard() {
+	if w.proc == nil {
+		return
+	}
+	w.proc.kill()
+	w.proc = nil
+}
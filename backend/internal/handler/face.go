@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -41,11 +40,7 @@ func (h *FaceHandler) AddFace(c *gin.Context) {
 
 	face, err := h.faceService.AddFace(personID, &req)
 	if err != nil {
-		if err.Error() == "person not found" {
-			errors.RespondWithError(c, errors.NotFound("Person not found"))
-			return
-		}
-		errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		respondWithAddFaceError(c, err)
 		return
 	}
 
@@ -71,34 +66,44 @@ func (h *FaceHandler) AddFaceImage(c *gin.Context) {
 	}
 
 	// Extract embedding from image
-	embedding, err := h.faceExtractionService.ExtractEmbedding(imageFile)
+	embedding, err := h.faceExtractionService.ExtractEmbedding(c.Request.Context(), imageFile)
 	if err != nil {
-		errors.RespondWithError(c, errors.BadRequest(fmt.Sprintf("Failed to process image: %v", err)))
+		respondWithExtractionError(c, err)
 		return
 	}
 
 	// Create a request for the existing AddFace service method
 	req := models.FaceEmbeddingRequest{
 		Embedding:    embedding,
-		EmbeddingDim: 512,                 // Assuming 512, should be a constant
-		ModelVersion: "facenet-tflite-v1", // This should probably come from the extraction service
+		EmbeddingDim: len(embedding),
+		ModelVersion: h.faceExtractionService.ModelVersion(),
 		Note:         notePtr,
 	}
 
 	// Call the existing service method to add the face
 	face, err := h.faceService.AddFace(personID, &req)
 	if err != nil {
-		if err.Error() == "person not found" {
-			errors.RespondWithError(c, errors.NotFound("Person not found"))
-			return
-		}
-		errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		respondWithAddFaceError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, face)
 }
 
+// respondWithAddFaceError maps FaceService.AddFace errors to Problem responses
+func respondWithAddFaceError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "person not found":
+		errors.RespondWithError(c, errors.NotFound("Person not found"))
+	case "unknown model version":
+		errors.RespondWithError(c, errors.UnprocessableEntity("Unknown model_version; register it via /v1/models"))
+	case "embedding dimension does not match model":
+		errors.RespondWithError(c, errors.UnprocessableEntity("Embedding length does not match the model dimension"))
+	default:
+		errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+	}
+}
+
 // ListFaces handles GET /persons/{person_id}/faces with optional embedding inclusion
 func (h *FaceHandler) ListFaces(c *gin.Context) {
 	personID := c.Param("person_id")
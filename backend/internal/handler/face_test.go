@@ -149,7 +149,7 @@ func TestFaceHandler_AddFace(t *testing.T) {
 				ModelVersion: "facenet-tflite-v1",
 			},
 			mockSetup:      func(m *MockFaceService) {},
-			expectedStatus: http.StatusBadRequest, // Gin validation returns 400
+			expectedStatus: http.StatusUnprocessableEntity, // Length checked against embedding_dim
 		},
 		{
 			name:     "invalid embedding dimension - mismatch",
@@ -160,7 +160,7 @@ func TestFaceHandler_AddFace(t *testing.T) {
 				ModelVersion: "facenet-tflite-v1",
 			},
 			mockSetup:      func(m *MockFaceService) {},
-			expectedStatus: http.StatusBadRequest, // Gin validation returns 400
+			expectedStatus: http.StatusUnprocessableEntity, // Length checked against embedding_dim
 		},
 		{
 			name:     "missing model version",
@@ -185,6 +185,32 @@ func TestFaceHandler_AddFace(t *testing.T) {
 			},
 			expectedStatus: http.StatusNotFound,
 		},
+		{
+			name:     "unknown model version",
+			personID: "p-123",
+			requestBody: models.FaceEmbeddingRequest{
+				Embedding:    createTestEmbedding(),
+				EmbeddingDim: 512,
+				ModelVersion: "arcface-v9",
+			},
+			mockSetup: func(m *MockFaceService) {
+				m.On("AddFace", "p-123", mock.AnythingOfType("*models.FaceEmbeddingRequest")).Return(nil, errors.New("unknown model version"))
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "dimension does not match registered model",
+			personID: "p-123",
+			requestBody: models.FaceEmbeddingRequest{
+				Embedding:    make([]float32, 128),
+				EmbeddingDim: 128,
+				ModelVersion: "facenet-tflite-v1",
+			},
+			mockSetup: func(m *MockFaceService) {
+				m.On("AddFace", "p-123", mock.AnythingOfType("*models.FaceEmbeddingRequest")).Return(nil, errors.New("embedding dimension does not match model"))
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
 		{
 			name:     "service error",
 			personID: "p-123",
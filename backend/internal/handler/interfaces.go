@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"context"
+	"io"
 	"mime/multipart"
 
+	"github.com/jphacks/os_2522/backend/internal/events"
 	"github.com/jphacks/os_2522/backend/internal/models"
 )
 
@@ -10,9 +13,9 @@ import (
 type PersonServiceInterface interface {
 	ListPersons(limit int, cursor *string, q *string) (*models.PersonList, error)
 	GetPerson(personID string) (*models.Person, error)
-	CreatePerson(req *models.PersonCreate) (*models.Person, error)
+	CreatePerson(ctx context.Context, req *models.PersonCreate) (*models.Person, error)
 	UpdatePerson(personID string, req *models.PersonUpdate) (*models.Person, error)
-	DeletePerson(personID string) error
+	DeletePerson(ctx context.Context, personID string) error
 }
 
 // FaceServiceInterface defines the interface for FaceService
@@ -24,13 +27,34 @@ type FaceServiceInterface interface {
 
 // JobServiceInterface defines the interface for JobService
 type JobServiceInterface interface {
-	CreateTranscriptionJob(personID *string, file *multipart.FileHeader, webhookURL *string) (*models.Job, error)
+	CreateTranscriptionJob(ctx context.Context, personID *string, file *multipart.FileHeader, webhookURL *string) (*models.Job, error)
 	GetJob(jobID string) (*models.Job, error)
+	DeleteJob(ctx context.Context, jobID string) error
+	ListDeliveries(jobID string) (*models.WebhookDeliveryList, error)
+	ListJobs(filter *models.JobFilter, limit int, cursor *string) (*models.JobList, error)
+	CancelJob(jobID string) (*models.Job, error)
+	RetryJob(jobID string) (*models.Job, error)
+	GetTranscript(jobID string) (*models.Transcript, error)
+}
+
+// UploadServiceInterface defines the interface for UploadService
+type UploadServiceInterface interface {
+	CreateUpload(ctx context.Context, req *models.UploadCreate) (*models.Upload, error)
+	GetUpload(uploadID string) (*models.Upload, error)
+	AppendChunk(ctx context.Context, uploadID string, offset int64, r io.Reader) (*models.Upload, error)
+	CompleteUpload(ctx context.Context, uploadID string) (*models.Job, error)
+	DeleteUpload(ctx context.Context, uploadID string) error
+}
+
+// JobEventServiceInterface defines the interface for JobEventService
+type JobEventServiceInterface interface {
+	Subscribe(jobID string, lastEventID int64) (*events.Subscription, error)
 }
 
 // RecognitionServiceInterface defines the interface for RecognitionService
 type RecognitionServiceInterface interface {
 	Recognize(req *models.RecognitionRequest) (*models.RecognitionResponse, error)
+	RecognizeBatch(req *models.BatchRecognitionRequest) (*models.BatchRecognitionResponse, error)
 }
 
 // EncounterServiceInterface defines the interface for EncounterService
@@ -38,7 +62,30 @@ type EncounterServiceInterface interface {
 	ListEncounters(personID string, limit int, cursor *string) (*models.EncounterList, error)
 }
 
+// SuggestionServiceInterface defines the interface for SuggestionService
+type SuggestionServiceInterface interface {
+	GetSuggestions(ctx context.Context, personID string, refresh bool) (*models.SuggestionList, error)
+}
+
+// ProfileServiceInterface defines the interface for ProfileService
+type ProfileServiceInterface interface {
+	GetProfile(personID string) (*models.PersonProfile, error)
+	ListVersions(personID string, limit int, cursor *string) (*models.ProfileVersionList, error)
+	GetVersion(personID string, version int) (*models.PersonProfile, error)
+	RevertProfile(personID string, version int) (*models.PersonProfile, error)
+}
+
+// ModelServiceInterface defines the interface for ModelService
+type ModelServiceInterface interface {
+	ListModels() (*models.EmbeddingModelList, error)
+	GetModel(modelVersion string) (*models.EmbeddingModel, error)
+	CreateModel(req *models.EmbeddingModelCreate) (*models.EmbeddingModel, error)
+}
+
 // FaceExtractionServiceInterface defines the interface for a service that extracts face embeddings from images.
 type FaceExtractionServiceInterface interface {
-	ExtractEmbedding(file *multipart.FileHeader) ([]float32, error)
+	ExtractEmbedding(ctx context.Context, file *multipart.FileHeader) ([]float32, error)
+	ExtractFaces(ctx context.Context, file *multipart.FileHeader) ([]models.DetectedFace, error)
+	// ModelVersion returns the registered embedding model of the extracted embeddings
+	ModelVersion() string
 }
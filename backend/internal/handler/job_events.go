@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jphacks/os_2522/backend/internal/errors"
+	"github.com/jphacks/os_2522/backend/internal/models"
+)
+
+// reconnectDelay is the retry hint sent to EventSource clients, in milliseconds
+const reconnectDelay = 3000
+
+// JobEventsHandler streams job progress as Server-Sent Events
+type JobEventsHandler struct {
+	jobEventService JobEventServiceInterface
+	heartbeat       time.Duration
+}
+
+// NewJobEventsHandler creates a new JobEventsHandler. Idle streams send a
+// heartbeat comment every heartbeat interval.
+func NewJobEventsHandler(jobEventService JobEventServiceInterface, heartbeat time.Duration) *JobEventsHandler {
+	return &JobEventsHandler{
+		jobEventService: jobEventService,
+		heartbeat:       heartbeat,
+	}
+}
+
+// StreamJobEvents handles GET /jobs/{job_id}/events
+func (h *JobEventsHandler) StreamJobEvents(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	// EventSource sends the Last-Event-ID header when it reconnects; the query
+	// parameter serves clients that cannot set headers. An ID that does not
+	// parse is treated like a fresh connection.
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	resumeFrom, _ := strconv.ParseInt(lastEventID, 10, 64)
+
+	sub, err := h.jobEventService.Subscribe(jobID, resumeFrom)
+	if err != nil {
+		if err.Error() == "job not found" {
+			errors.RespondWithError(c, errors.NotFound("Job not found"))
+			return
+		}
+		errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		return
+	}
+	defer sub.Close()
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	if _, err := fmt.Fprintf(c.Writer, "retry: %d\n\n", reconnectDelay); err != nil {
+		return
+	}
+
+	for _, event := range sub.Replay {
+		if done, err := writeJobEvent(c.Writer, event); done || err != nil {
+			c.Writer.Flush()
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind; the client reconnects and resumes
+				return
+			}
+			done, err := writeJobEvent(c.Writer, event)
+			if err != nil {
+				return
+			}
+			c.Writer.Flush()
+			if done {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeJobEvent writes one event in SSE framing and reports whether it ends the stream.
+// Events of the done stage are named "done" so clients can close the stream; the rest are "progress".
+func writeJobEvent(w io.Writer, event models.JobEvent) (bool, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Warning: failed to encode event for job %s: %v", event.JobID, err)
+		return false, nil
+	}
+
+	done := event.Stage == models.JobStageDone
+	name := "progress"
+	if done {
+		name = "done"
+	}
+	if event.ID > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", event.ID); err != nil {
+			return done, err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+	return done, err
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jphacks/os_2522/backend/internal/events"
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockJobEventService is a mock implementation of JobEventService
+type MockJobEventService struct {
+	mock.Mock
+}
+
+func (m *MockJobEventService) Subscribe(jobID string, lastEventID int64) (*events.Subscription, error) {
+	args := m.Called(jobID, lastEventID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*events.Subscription), args.Error(1)
+}
+
+func newJobEventsRouter(service *MockJobEventService, heartbeat time.Duration) *gin.Engine {
+	router := gin.New()
+	router.GET("/jobs/:job_id/events", NewJobEventsHandler(service, heartbeat).StreamJobEvents)
+	return router
+}
+
+func TestJobEventsHandler_StreamJobEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	eventTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		lastEventID    string
+		query          string
+		replay         []models.JobEvent
+		serviceErr     error
+		expectedResume int64
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "replay ends with the done stage",
+			replay: []models.JobEvent{
+				{ID: 7, JobID: "j-1", Status: models.JobStatusRunning, Stage: models.JobStageTranscribing, Attempts: 1, Time: eventTime},
+				{ID: 8, JobID: "j-1", Status: models.JobStatusSucceeded, Stage: models.JobStageDone, Attempts: 1, Time: eventTime},
+				{ID: 9, JobID: "j-1", Status: models.JobStatusQueued, Stage: models.JobStageUploaded, Time: eventTime},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: "retry: 3000\n\n" +
+				"id: 7\nevent: progress\ndata: {\"job_id\":\"j-1\",\"status\":\"running\",\"stage\":\"transcribing\",\"attempts\":1,\"time\":\"2026-01-02T03:04:05Z\"}\n\n" +
+				"id: 8\nevent: done\ndata: {\"job_id\":\"j-1\",\"status\":\"succeeded\",\"stage\":\"done\",\"attempts\":1,\"time\":\"2026-01-02T03:04:05Z\"}\n\n",
+		},
+		{
+			name:        "resumes from Last-Event-ID",
+			lastEventID: "42",
+			replay: []models.JobEvent{
+				{JobID: "j-1", Status: models.JobStatusCancelled, Stage: models.JobStageDone, Time: eventTime},
+			},
+			expectedResume: 42,
+			expectedStatus: http.StatusOK,
+			expectedBody: "retry: 3000\n\n" +
+				"event: done\ndata: {\"job_id\":\"j-1\",\"status\":\"cancelled\",\"stage\":\"done\",\"attempts\":0,\"time\":\"2026-01-02T03:04:05Z\"}\n\n",
+		},
+		{
+			name:           "resumes from query parameter",
+			query:          "?last_event_id=43",
+			replay:         []models.JobEvent{{JobID: "j-1", Status: models.JobStatusFailed, Stage: models.JobStageDone, Time: eventTime}},
+			expectedResume: 43,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unparsable Last-Event-ID starts over",
+			lastEventID:    "abc",
+			replay:         []models.JobEvent{{JobID: "j-1", Status: models.JobStatusFailed, Stage: models.JobStageDone, Time: eventTime}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "job not found",
+			serviceErr:     errors.New("job not found"),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "service error",
+			serviceErr:     errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockJobEventService)
+			if tt.serviceErr != nil {
+				mockService.On("Subscribe", "j-1", tt.expectedResume).Return(nil, tt.serviceErr)
+			} else {
+				sub := events.NewBroker(events.DefaultConfig()).Subscribe("j-1", 0)
+				sub.Replay = tt.replay
+				mockService.On("Subscribe", "j-1", tt.expectedResume).Return(sub, nil)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/jobs/j-1/events"+tt.query, nil)
+			if tt.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventID)
+			}
+			w := httptest.NewRecorder()
+			newJobEventsRouter(mockService, time.Minute).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+				assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+			}
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestJobEventsHandler_StreamsLiveEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	broker := events.NewBroker(events.DefaultConfig())
+	mockService := new(MockJobEventService)
+	mockService.On("Subscribe", "j-1", int64(0)).Return(broker.Subscribe("j-1", 0), nil)
+	server := httptest.NewServer(newJobEventsRouter(mockService, 10*time.Millisecond))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/jobs/j-1/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+
+	// Idle streams are kept alive with heartbeat comments
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if line == ": heartbeat\n" {
+			break
+		}
+	}
+
+	progress := broker.Publish(models.JobEvent{JobID: "j-1", Status: models.JobStatusRunning, Stage: models.JobStageSummarizing})
+	done := broker.Publish(models.JobEvent{JobID: "j-1", Status: models.JobStatusSucceeded, Stage: models.JobStageDone})
+
+	// The stream ends after the done stage
+	rest, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	body := strings.ReplaceAll(string(rest), ": heartbeat\n\n", "")
+	assert.Contains(t, body, "event: progress\n")
+	assert.Contains(t, body, "\"stage\":\"summarizing\"")
+	assert.Less(t, strings.Index(body, "id: "+strconv.FormatInt(progress.ID, 10)), strings.Index(body, "id: "+strconv.FormatInt(done.ID, 10)))
+	assert.True(t, strings.HasSuffix(body, "\n\n"))
+	assert.Contains(t, body, "event: done\n")
+}
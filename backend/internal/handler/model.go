@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jphacks/os_2522/backend/internal/errors"
+	"github.com/jphacks/os_2522/backend/internal/models"
+)
+
+// ModelHandler handles embedding model registry requests
+type ModelHandler struct {
+	modelService ModelServiceInterface
+}
+
+// NewModelHandler creates a new ModelHandler
+func NewModelHandler(modelService ModelServiceInterface) *ModelHandler {
+	return &ModelHandler{modelService: modelService}
+}
+
+// ListModels handles GET /models
+func (h *ModelHandler) ListModels(c *gin.Context) {
+	modelList, err := h.modelService.ListModels()
+	if err != nil {
+		errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, modelList)
+}
+
+// CreateModel handles POST /models
+func (h *ModelHandler) CreateModel(c *gin.Context) {
+	var req models.EmbeddingModelCreate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.HandleValidationErrors(c, err)
+		return
+	}
+
+	model, err := h.modelService.CreateModel(&req)
+	if err != nil {
+		if err.Error() == "model already exists" {
+			errors.RespondWithError(c, errors.Conflict("Model version is already registered"))
+			return
+		}
+		if err.Error() == "model family mismatch" {
+			errors.RespondWithError(c, errors.Conflict("Model family is already registered with a different dimension or metric"))
+			return
+		}
+		errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		return
+	}
+
+	c.Header("Location", "/v1/models/"+model.ModelVersion)
+	c.JSON(http.StatusCreated, model)
+}
+
+// GetModel handles GET /models/{model_version}
+func (h *ModelHandler) GetModel(c *gin.Context) {
+	modelVersion := c.Param("model_version")
+
+	model, err := h.modelService.GetModel(modelVersion)
+	if err != nil {
+		if err.Error() == "model not found" {
+			errors.RespondWithError(c, errors.NotFound("Model not found"))
+			return
+		}
+		errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, model)
+}
@@ -0,0 +1,285 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jphacks/os_2522/backend/internal/database"
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockModelService is a mock implementation of ModelService
+type MockModelService struct {
+	mock.Mock
+}
+
+func (m *MockModelService) ListModels() (*models.EmbeddingModelList, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EmbeddingModelList), args.Error(1)
+}
+
+func (m *MockModelService) GetModel(modelVersion string) (*models.EmbeddingModel, error) {
+	args := m.Called(modelVersion)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EmbeddingModel), args.Error(1)
+}
+
+func (m *MockModelService) CreateModel(req *models.EmbeddingModelCreate) (*models.EmbeddingModel, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EmbeddingModel), args.Error(1)
+}
+
+func testEmbeddingModel() *models.EmbeddingModel {
+	return &models.EmbeddingModel{
+		ModelVersion:     "facenet-tflite-v1",
+		Family:           "facenet-tflite",
+		Dimension:        512,
+		Metric:           models.DistanceMetricCosine,
+		DefaultThreshold: 0.6,
+		CreatedAt:        time.Now(),
+	}
+}
+
+func TestModelHandler_ListModels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		mockSetup      func(*MockModelService)
+		expectedStatus int
+	}{
+		{
+			name: "successful list",
+			mockSetup: func(m *MockModelService) {
+				m.On("ListModels").Return(&models.EmbeddingModelList{
+					Items: []models.EmbeddingModel{*testEmbeddingModel()},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "service error",
+			mockSetup: func(m *MockModelService) {
+				m.On("ListModels").Return(nil, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockModelService)
+			tt.mockSetup(mockService)
+
+			router := gin.New()
+			handler := NewModelHandler(mockService)
+			router.GET("/models", handler.ListModels)
+
+			req, _ := http.NewRequest(http.MethodGet, "/models", nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestModelHandler_CreateModel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		mockSetup      func(*MockModelService)
+		expectedStatus int
+		checkResponse  func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "successful registration",
+			requestBody: models.EmbeddingModelCreate{
+				ModelVersion:     "facenet-tflite-v1",
+				Family:           "facenet-tflite",
+				Dimension:        512,
+				DefaultThreshold: 0.6,
+			},
+			mockSetup: func(m *MockModelService) {
+				m.On("CreateModel", mock.AnythingOfType("*models.EmbeddingModelCreate")).Return(testEmbeddingModel(), nil)
+			},
+			expectedStatus: http.StatusCreated,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var response models.EmbeddingModel
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, 512, response.Dimension)
+				assert.Equal(t, "/v1/models/facenet-tflite-v1", w.Header().Get("Location"))
+			},
+		},
+		{
+			name: "unsupported metric",
+			requestBody: map[string]interface{}{
+				"model_version":     "facenet-tflite-v1",
+				"family":            "facenet-tflite",
+				"dimension":         512,
+				"metric":            "manhattan",
+				"default_threshold": 0.6,
+			},
+			mockSetup:      func(m *MockModelService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "missing dimension",
+			requestBody: map[string]interface{}{
+				"model_version":     "facenet-tflite-v1",
+				"family":            "facenet-tflite",
+				"default_threshold": 0.6,
+			},
+			mockSetup:      func(m *MockModelService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "already registered",
+			requestBody: models.EmbeddingModelCreate{
+				ModelVersion:     "facenet-tflite-v1",
+				Family:           "facenet-tflite",
+				Dimension:        512,
+				DefaultThreshold: 0.6,
+			},
+			mockSetup: func(m *MockModelService) {
+				m.On("CreateModel", mock.AnythingOfType("*models.EmbeddingModelCreate")).Return(nil, errors.New("model already exists"))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "family registered with another dimension",
+			requestBody: models.EmbeddingModelCreate{
+				ModelVersion:     "facenet-tflite-v2",
+				Family:           "facenet-tflite",
+				Dimension:        128,
+				DefaultThreshold: 0.6,
+			},
+			mockSetup: func(m *MockModelService) {
+				m.On("CreateModel", mock.AnythingOfType("*models.EmbeddingModelCreate")).Return(nil, errors.New("model family mismatch"))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockModelService)
+			tt.mockSetup(mockService)
+
+			router := gin.New()
+			handler := NewModelHandler(mockService)
+			router.POST("/models", handler.CreateModel)
+
+			body, err := json.Marshal(tt.requestBody)
+			assert.NoError(t, err)
+
+			req, _ := http.NewRequest(http.MethodPost, "/models", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkResponse != nil {
+				tt.checkResponse(t, w)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestModelHandler_CreateModel_FamilyMismatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.NewDB(&database.Config{Driver: "sqlite", DBName: "file:model_family?mode=memory&cache=shared"})
+	require.NoError(t, err)
+	require.NoError(t, database.AutoMigrate(db))
+
+	router := gin.New()
+	router.POST("/models", NewModelHandler(service.NewModelService(repository.NewModelRepository(db))).CreateModel)
+	create := func(body string) int {
+		req, _ := http.NewRequest(http.MethodPost, "/models", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusCreated, create(`{"model_version":"facenet-v1","family":"facenet","dimension":512,"default_threshold":0.6}`))
+	// Another dimension or metric would put incomparable vectors into one partition
+	assert.Equal(t, http.StatusConflict, create(`{"model_version":"facenet-v2","family":"facenet","dimension":128,"default_threshold":0.6}`))
+	assert.Equal(t, http.StatusConflict, create(`{"model_version":"facenet-v3","family":"facenet","dimension":512,"metric":"euclidean","default_threshold":0.5}`))
+	assert.Equal(t, http.StatusCreated, create(`{"model_version":"facenet-v4","family":"facenet","dimension":512,"metric":"cosine","default_threshold":0.7}`))
+	assert.Equal(t, http.StatusCreated, create(`{"model_version":"arcface-v1","family":"arcface","dimension":128,"metric":"euclidean","default_threshold":0.5}`))
+}
+
+func TestModelHandler_GetModel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		modelVersion   string
+		mockSetup      func(*MockModelService)
+		expectedStatus int
+	}{
+		{
+			name:         "found",
+			modelVersion: "facenet-tflite-v1",
+			mockSetup: func(m *MockModelService) {
+				m.On("GetModel", "facenet-tflite-v1").Return(testEmbeddingModel(), nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:         "not found",
+			modelVersion: "arcface-v9",
+			mockSetup: func(m *MockModelService) {
+				m.On("GetModel", "arcface-v9").Return(nil, errors.New("model not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockModelService)
+			tt.mockSetup(mockService)
+
+			router := gin.New()
+			handler := NewModelHandler(mockService)
+			router.GET("/models/:model_version", handler.GetModel)
+
+			req, _ := http.NewRequest(http.MethodGet, "/models/"+tt.modelVersion, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
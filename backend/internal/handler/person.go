@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"context"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jphacks/os_2522/backend/internal/errors"
@@ -59,9 +61,9 @@ func (h *PersonHandler) CreatePerson(c *gin.Context) {
 		return
 	}
 
-	person, err := h.personService.CreatePerson(&req)
+	person, err := h.personService.CreatePerson(c.Request.Context(), &req)
 	if err != nil {
-		errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		respondWithCreatePersonError(c, err)
 		return
 	}
 
@@ -70,6 +72,29 @@ func (h *PersonHandler) CreatePerson(c *gin.Context) {
 	c.JSON(http.StatusCreated, person)
 }
 
+// respondWithCreatePersonError maps PersonService.CreatePerson errors to Problem responses
+func respondWithCreatePersonError(c *gin.Context, err error) {
+	switch msg := err.Error(); {
+	case msg == "no face detected":
+		errors.RespondWithError(c, errors.UnprocessableEntity("No face detected in face_image_base64"))
+	case msg == "unknown model version", msg == "embedding dimension does not match model":
+		errors.RespondWithError(c, errors.UnprocessableEntity("Extracted embedding does not match the enrollment model"))
+	case strings.HasPrefix(msg, "invalid face image"):
+		errors.RespondWithError(c, errors.BadRequest("Invalid face_image_base64: "+strings.TrimPrefix(msg, "invalid face image: ")))
+	case strings.HasPrefix(msg, "extraction "):
+		respondWithExtractionError(c, err)
+	case msg == context.Canceled.Error(), msg == context.DeadlineExceeded.Error():
+		if c.Request.Context().Err() != nil {
+			// The client has gone away; there is nobody to respond to
+			c.Abort()
+			return
+		}
+		errors.RespondWithError(c, errors.GatewayTimeout("Face extraction timed out"))
+	default:
+		errors.RespondWithError(c, errors.InternalServerError(msg))
+	}
+}
+
 // GetPerson handles GET /persons/{person_id}
 func (h *PersonHandler) GetPerson(c *gin.Context) {
 	personID := c.Param("person_id")
@@ -119,7 +144,7 @@ func (h *PersonHandler) UpdatePerson(c *gin.Context) {
 func (h *PersonHandler) DeletePerson(c *gin.Context) {
 	personID := c.Param("person_id")
 
-	err := h.personService.DeletePerson(personID)
+	err := h.personService.DeletePerson(c.Request.Context(), personID)
 	if err != nil {
 		if err.Error() == "person not found" {
 			errors.RespondWithError(c, errors.NotFound("Person not found"))
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -36,7 +37,7 @@ func (m *MockPersonService) GetPerson(personID string) (*models.Person, error) {
 	return args.Get(0).(*models.Person), args.Error(1)
 }
 
-func (m *MockPersonService) CreatePerson(req *models.PersonCreate) (*models.Person, error) {
+func (m *MockPersonService) CreatePerson(ctx context.Context, req *models.PersonCreate) (*models.Person, error) {
 	args := m.Called(req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -52,7 +53,7 @@ func (m *MockPersonService) UpdatePerson(personID string, req *models.PersonUpda
 	return args.Get(0).(*models.Person), args.Error(1)
 }
 
-func (m *MockPersonService) DeletePerson(personID string) error {
+func (m *MockPersonService) DeletePerson(ctx context.Context, personID string) error {
 	args := m.Called(personID)
 	return args.Error(0)
 }
@@ -178,6 +179,78 @@ func TestPersonHandler_CreatePerson(t *testing.T) {
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
+		{
+			name: "creation with face image",
+			requestBody: models.PersonCreate{
+				Name:            "Test User",
+				FaceImageBase64: stringPtr("aGVsbG8="),
+			},
+			mockSetup: func(m *MockPersonService) {
+				m.On("CreatePerson", mock.MatchedBy(func(req *models.PersonCreate) bool {
+					return req.FaceImageBase64 != nil && *req.FaceImageBase64 == "aGVsbG8="
+				})).Return(&models.Person{
+					PersonID:   "p-123",
+					Name:       "Test User",
+					FacesCount: 1,
+				}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "no face in face image",
+			requestBody: models.PersonCreate{
+				Name:            "Test User",
+				FaceImageBase64: stringPtr("aGVsbG8="),
+			},
+			mockSetup: func(m *MockPersonService) {
+				m.On("CreatePerson", mock.AnythingOfType("*models.PersonCreate")).Return(nil, errors.New("no face detected"))
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "undecodable face image",
+			requestBody: models.PersonCreate{
+				Name:            "Test User",
+				FaceImageBase64: stringPtr("!!!"),
+			},
+			mockSetup: func(m *MockPersonService) {
+				m.On("CreatePerson", mock.AnythingOfType("*models.PersonCreate")).Return(nil, errors.New("invalid face image: illegal base64 data at input byte 0"))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "extraction busy",
+			requestBody: models.PersonCreate{
+				Name:            "Test User",
+				FaceImageBase64: stringPtr("aGVsbG8="),
+			},
+			mockSetup: func(m *MockPersonService) {
+				m.On("CreatePerson", mock.AnythingOfType("*models.PersonCreate")).Return(nil, errors.New("extraction queue is full"))
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name: "extraction cancelled",
+			requestBody: models.PersonCreate{
+				Name:            "Test User",
+				FaceImageBase64: stringPtr("aGVsbG8="),
+			},
+			mockSetup: func(m *MockPersonService) {
+				m.On("CreatePerson", mock.AnythingOfType("*models.PersonCreate")).Return(nil, context.Canceled)
+			},
+			expectedStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name: "extraction deadline exceeded",
+			requestBody: models.PersonCreate{
+				Name:            "Test User",
+				FaceImageBase64: stringPtr("aGVsbG8="),
+			},
+			mockSetup: func(m *MockPersonService) {
+				m.On("CreatePerson", mock.AnythingOfType("*models.PersonCreate")).Return(nil, context.DeadlineExceeded)
+			},
+			expectedStatus: http.StatusGatewayTimeout,
+		},
 	}
 
 	for _, tt := range tests {
@@ -205,6 +278,30 @@ func TestPersonHandler_CreatePerson(t *testing.T) {
 	}
 }
 
+func TestPersonHandler_CreatePerson_ClientGone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockPersonService)
+	mockService.On("CreatePerson", mock.AnythingOfType("*models.PersonCreate")).Return(nil, context.Canceled)
+
+	router := gin.New()
+	handler := NewPersonHandler(mockService)
+	router.POST("/persons", handler.CreatePerson)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	body, _ := json.Marshal(models.PersonCreate{Name: "Test User", FaceImageBase64: stringPtr("aGVsbG8=")})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "/persons", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	// No response is written for a client that has disconnected
+	assert.Zero(t, w.Body.Len())
+	mockService.AssertExpectations(t)
+}
+
 func TestPersonHandler_GetPerson(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
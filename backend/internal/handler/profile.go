@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jphacks/os_2522/backend/internal/errors"
+	"github.com/jphacks/os_2522/backend/internal/models"
+)
+
+// ProfileHandler handles person profile requests
+type ProfileHandler struct {
+	profileService ProfileServiceInterface
+}
+
+// NewProfileHandler creates a new ProfileHandler
+func NewProfileHandler(profileService ProfileServiceInterface) *ProfileHandler {
+	return &ProfileHandler{profileService: profileService}
+}
+
+// GetProfile handles GET /persons/{person_id}/profile
+func (h *ProfileHandler) GetProfile(c *gin.Context) {
+	profile, err := h.profileService.GetProfile(c.Param("person_id"))
+	if err != nil {
+		respondWithProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// ListVersions handles GET /persons/{person_id}/profile/versions
+func (h *ProfileHandler) ListVersions(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		errors.RespondWithError(c, errors.BadRequest("Invalid limit parameter"))
+		return
+	}
+
+	var cursor *string
+	if c := c.Query("cursor"); c != "" {
+		cursor = &c
+	}
+
+	versions, err := h.profileService.ListVersions(c.Param("person_id"), limit, cursor)
+	if err != nil {
+		respondWithProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// GetVersion handles GET /persons/{person_id}/profile/versions/{version}
+func (h *ProfileHandler) GetVersion(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		errors.RespondWithError(c, errors.BadRequest("Invalid version"))
+		return
+	}
+
+	profile, err := h.profileService.GetVersion(c.Param("person_id"), version)
+	if err != nil {
+		respondWithProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// RevertProfile handles POST /persons/{person_id}/profile/revert
+func (h *ProfileHandler) RevertProfile(c *gin.Context) {
+	var req models.ProfileRevertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.HandleValidationErrors(c, err)
+		return
+	}
+
+	profile, err := h.profileService.RevertProfile(c.Param("person_id"), req.Version)
+	if err != nil {
+		respondWithProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, profile)
+}
+
+func respondWithProfileError(c *gin.Context, err error) {
+	switch msg := err.Error(); {
+	case msg == "person not found":
+		errors.RespondWithError(c, errors.NotFound("Person not found"))
+	case msg == "profile not found":
+		errors.RespondWithError(c, errors.NotFound("Profile not found; it is created once a conversation with the person has been summarized"))
+	case msg == "profile version not found":
+		errors.RespondWithError(c, errors.NotFound("Profile version not found"))
+	case msg == "profile version is already current":
+		errors.RespondWithError(c, errors.Conflict("Profile version is already current"))
+	case msg == "profile was updated concurrently":
+		errors.RespondWithError(c, errors.Conflict("Profile was updated concurrently; retry"))
+	case strings.HasPrefix(msg, "invalid cursor"):
+		errors.RespondWithError(c, errors.BadRequest("Invalid cursor parameter"))
+	default:
+		errors.RespondWithError(c, errors.InternalServerError(msg))
+	}
+}
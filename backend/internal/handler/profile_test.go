@@ -0,0 +1,370 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jphacks/os_2522/backend/internal/database"
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockProfileService is a mock implementation of ProfileService
+type MockProfileService struct {
+	mock.Mock
+}
+
+func (m *MockProfileService) GetProfile(personID string) (*models.PersonProfile, error) {
+	args := m.Called(personID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PersonProfile), args.Error(1)
+}
+
+func (m *MockProfileService) ListVersions(personID string, limit int, cursor *string) (*models.ProfileVersionList, error) {
+	args := m.Called(personID, limit, cursor)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ProfileVersionList), args.Error(1)
+}
+
+func (m *MockProfileService) GetVersion(personID string, version int) (*models.PersonProfile, error) {
+	args := m.Called(personID, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PersonProfile), args.Error(1)
+}
+
+func (m *MockProfileService) RevertProfile(personID string, version int) (*models.PersonProfile, error) {
+	args := m.Called(personID, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PersonProfile), args.Error(1)
+}
+
+func testProfile(version int) *models.PersonProfile {
+	encounterID := "e-1"
+	return &models.PersonProfile{
+		PersonID: "p-123",
+		Version:  version,
+		ProfileDocument: models.ProfileDocument{
+			Overview:  "大学時代の友人",
+			Facts:     []models.PersonFact{{Category: models.FactCategoryFamily, Fact: "子どもが2人いる"}},
+			Interests: []string{"登山"},
+			FollowUps: []models.FollowUp{},
+		},
+		EncounterID: &encounterID,
+		CreatedAt:   time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestProfileHandler_GetProfile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		personID       string
+		mockSetup      func(*MockProfileService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:     "successful get",
+			personID: "p-123",
+			mockSetup: func(m *MockProfileService) {
+				profile := testProfile(2)
+				pending := 1
+				profile.PendingEncounters = &pending
+				m.On("GetProfile", "p-123").Return(profile, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"person_id":"p-123","version":2,"overview":"大学時代の友人",` +
+				`"facts":[{"category":"family","fact":"子どもが2人いる"}],"interests":["登山"],"follow_ups":[],` +
+				`"encounter_id":"e-1","created_at":"2025-10-18T09:00:00Z","pending_encounters":1}`,
+		},
+		{
+			name:     "person not found",
+			personID: "p-999",
+			mockSetup: func(m *MockProfileService) {
+				m.On("GetProfile", "p-999").Return(nil, errors.New("person not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:     "no profile yet",
+			personID: "p-123",
+			mockSetup: func(m *MockProfileService) {
+				m.On("GetProfile", "p-123").Return(nil, errors.New("profile not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:     "internal error",
+			personID: "p-123",
+			mockSetup: func(m *MockProfileService) {
+				m.On("GetProfile", "p-123").Return(nil, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockProfileService)
+			tt.mockSetup(mockService)
+
+			handler := NewProfileHandler(mockService)
+			router := gin.New()
+			router.GET("/persons/:person_id/profile", handler.GetProfile)
+
+			req, _ := http.NewRequest(http.MethodGet, "/persons/"+tt.personID+"/profile", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestProfileHandler_ListVersions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		queryParams    string
+		mockSetup      func(*MockProfileService)
+		expectedStatus int
+	}{
+		{
+			name:        "successful list with defaults",
+			queryParams: "",
+			mockSetup: func(m *MockProfileService) {
+				next := "2"
+				m.On("ListVersions", "p-123", 20, (*string)(nil)).Return(&models.ProfileVersionList{
+					Items:      []models.PersonProfile{*testProfile(3), *testProfile(2)},
+					NextCursor: &next,
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "successful list with cursor",
+			queryParams: "?limit=5&cursor=2",
+			mockSetup: func(m *MockProfileService) {
+				m.On("ListVersions", "p-123", 5, mock.AnythingOfType("*string")).Return(&models.ProfileVersionList{
+					Items: []models.PersonProfile{*testProfile(1)},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid limit",
+			queryParams:    "?limit=0",
+			mockSetup:      func(m *MockProfileService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "invalid cursor",
+			queryParams: "?cursor=abc",
+			mockSetup: func(m *MockProfileService) {
+				m.On("ListVersions", "p-123", 20, mock.AnythingOfType("*string")).Return(nil, errors.New(`invalid cursor: strconv.Atoi: parsing "abc": invalid syntax`))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockProfileService)
+			tt.mockSetup(mockService)
+
+			handler := NewProfileHandler(mockService)
+			router := gin.New()
+			router.GET("/persons/:person_id/profile/versions", handler.ListVersions)
+
+			req, _ := http.NewRequest(http.MethodGet, "/persons/p-123/profile/versions"+tt.queryParams, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestProfileHandler_GetVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		version        string
+		mockSetup      func(*MockProfileService)
+		expectedStatus int
+	}{
+		{
+			name:    "successful get",
+			version: "1",
+			mockSetup: func(m *MockProfileService) {
+				m.On("GetVersion", "p-123", 1).Return(testProfile(1), nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid version",
+			version:        "latest",
+			mockSetup:      func(m *MockProfileService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:    "version not found",
+			version: "9",
+			mockSetup: func(m *MockProfileService) {
+				m.On("GetVersion", "p-123", 9).Return(nil, errors.New("profile version not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockProfileService)
+			tt.mockSetup(mockService)
+
+			handler := NewProfileHandler(mockService)
+			router := gin.New()
+			router.GET("/persons/:person_id/profile/versions/:version", handler.GetVersion)
+
+			req, _ := http.NewRequest(http.MethodGet, "/persons/p-123/profile/versions/"+tt.version, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestProfileHandler_RevertProfile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		mockSetup      func(*MockProfileService)
+		expectedStatus int
+	}{
+		{
+			name: "successful revert",
+			body: `{"version":1}`,
+			mockSetup: func(m *MockProfileService) {
+				profile := testProfile(4)
+				revertedFrom := 1
+				profile.RevertedFrom = &revertedFrom
+				m.On("RevertProfile", "p-123", 1).Return(profile, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "missing version",
+			body:           `{}`,
+			mockSetup:      func(m *MockProfileService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "version not found",
+			body: `{"version":9}`,
+			mockSetup: func(m *MockProfileService) {
+				m.On("RevertProfile", "p-123", 9).Return(nil, errors.New("profile version not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "already current",
+			body: `{"version":3}`,
+			mockSetup: func(m *MockProfileService) {
+				m.On("RevertProfile", "p-123", 3).Return(nil, errors.New("profile version is already current"))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "concurrent update",
+			body: `{"version":2}`,
+			mockSetup: func(m *MockProfileService) {
+				m.On("RevertProfile", "p-123", 2).Return(nil, errors.New("profile was updated concurrently"))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockProfileService)
+			tt.mockSetup(mockService)
+
+			handler := NewProfileHandler(mockService)
+			router := gin.New()
+			router.POST("/persons/:person_id/profile/revert", handler.RevertProfile)
+
+			req, _ := http.NewRequest(http.MethodPost, "/persons/p-123/profile/revert", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestProfileHandler_RevertProfile_DuringMerge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.NewDB(&database.Config{Driver: "sqlite", DBName: "file:profile_revert?mode=memory&cache=shared"})
+	require.NoError(t, err)
+	require.NoError(t, database.AutoMigrate(db))
+	personRepo := repository.NewPersonRepository(db)
+	profileRepo := repository.NewProfileRepository(db)
+	now := time.Now()
+	require.NoError(t, db.Create(&repository.PersonEntity{PersonID: "p-1", Name: "Alice", CreatedAt: now, UpdatedAt: now}).Error)
+	for _, document := range []string{`{"overview":"v1"}`, `{"overview":"v2"}`} {
+		require.NoError(t, profileRepo.AppendVersion(&repository.ProfileVersionEntity{
+			PersonID: "p-1", Document: document, CreatedAt: now,
+		}, func(int) error { return nil }))
+	}
+
+	router := gin.New()
+	router.POST("/persons/:person_id/profile/revert", NewProfileHandler(service.NewProfileService(personRepo, profileRepo, nil)).RevertProfile)
+	req, _ := http.NewRequest(http.MethodPost, "/persons/p-1/profile/revert", bytes.NewBufferString(`{"version":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var profile models.PersonProfile
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &profile))
+	assert.Equal(t, 3, profile.Version)
+
+	// A merge that started from version 2 must not overwrite the revert
+	encounterID := "e-1"
+	merged := &repository.ProfileVersionEntity{PersonID: "p-1", Document: `{"overview":"merged"}`, EncounterID: &encounterID, CreatedAt: now}
+	assert.ErrorIs(t, profileRepo.CommitUpdate(merged, 2, now), repository.ErrProfileConflict)
+	require.NoError(t, profileRepo.CommitUpdate(merged, 3, now))
+	assert.Equal(t, 4, merged.Version)
+}
@@ -40,7 +40,38 @@ func (h *RecognitionHandler) PostRecognize(c *gin.Context) {
 
 	response, err := h.recognitionService.Recognize(&req)
 	if err != nil {
-		errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		respondWithRecognitionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// PostRecognizeBatch handles POST /recognize/batch for several faces in one frame
+func (h *RecognitionHandler) PostRecognizeBatch(c *gin.Context) {
+	var req models.BatchRecognitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.RespondWithError(c, errors.BadRequest("Invalid request body: "+err.Error()))
+		return
+	}
+
+	// Validate embedding dimensions and track IDs
+	seen := make(map[string]bool, len(req.Items))
+	for _, item := range req.Items {
+		if len(item.Embedding) != item.EmbeddingDim {
+			errors.RespondWithError(c, errors.UnprocessableEntity("Embedding length does not match embedding_dim for track_id "+item.TrackID))
+			return
+		}
+		if seen[item.TrackID] {
+			errors.RespondWithError(c, errors.BadRequest("Duplicate track_id: "+item.TrackID))
+			return
+		}
+		seen[item.TrackID] = true
+	}
+
+	response, err := h.recognitionService.RecognizeBatch(&req)
+	if err != nil {
+		respondWithRecognitionError(c, err)
 		return
 	}
 
@@ -57,13 +88,51 @@ func (h *RecognitionHandler) PostRecognizeImage(c *gin.Context) {
 		return
 	}
 
-	minScoreStr := c.DefaultPostForm("min_score", "0.6")
+	// 0 leaves the threshold to the model's default_threshold
+	minScoreStr := c.DefaultPostForm("min_score", "0")
 	minScore, err := strconv.ParseFloat(minScoreStr, 64)
 	if err != nil || minScore < 0 || minScore > 1 {
 		errors.RespondWithError(c, errors.BadRequest("Invalid min_score parameter"))
 		return
 	}
 
+	var ambiguityMargin *float64
+	if marginStr := c.PostForm("ambiguity_margin"); marginStr != "" {
+		margin, err := strconv.ParseFloat(marginStr, 64)
+		if err != nil || margin < 0 || margin > 1 {
+			errors.RespondWithError(c, errors.BadRequest("Invalid ambiguity_margin parameter"))
+			return
+		}
+		ambiguityMargin = &margin
+	}
+
+	var logEncounter *bool
+	if logStr := c.PostForm("log_encounter"); logStr != "" {
+		value, err := strconv.ParseBool(logStr)
+		if err != nil {
+			errors.RespondWithError(c, errors.BadRequest("Invalid log_encounter parameter"))
+			return
+		}
+		logEncounter = &value
+	}
+
+	aggregation := models.ScoreAggregation(c.PostForm("aggregation"))
+	switch aggregation {
+	case "", models.ScoreAggregationMax, models.ScoreAggregationMeanTopN, models.ScoreAggregationCentroid:
+	default:
+		errors.RespondWithError(c, errors.BadRequest("Invalid aggregation parameter"))
+		return
+	}
+
+	var aggregationTopN int
+	if topNStr := c.PostForm("aggregation_top_n"); topNStr != "" {
+		aggregationTopN, err = strconv.Atoi(topNStr)
+		if err != nil || aggregationTopN < 1 || aggregationTopN > 20 {
+			errors.RespondWithError(c, errors.BadRequest("Invalid aggregation_top_n parameter"))
+			return
+		}
+	}
+
 	// Get image file
 	imageFile, err := c.FormFile("image")
 	if err != nil {
@@ -71,29 +140,84 @@ func (h *RecognitionHandler) PostRecognizeImage(c *gin.Context) {
 		return
 	}
 
-	// Extract embedding from image
-	embedding, err := h.faceExtractionService.ExtractEmbedding(imageFile)
+	// Detect every face in the image
+	faces, err := h.faceExtractionService.ExtractFaces(c.Request.Context(), imageFile)
 	if err != nil {
-		// This could be because no face was found, or the image was invalid.
-		errors.RespondWithError(c, errors.BadRequest(fmt.Sprintf("Failed to process image: %v", err)))
+		respondWithExtractionError(c, err)
 		return
 	}
 
-	// Create a recognition request similar to the embedding-based endpoint
-	req := &models.RecognitionRequest{
-		Embedding:    embedding,
-		EmbeddingDim: 512,                 // Assuming 512, should be a constant
-		ModelVersion: "facenet-tflite-v1", // This should probably come from the extraction service
-		TopK:         topK,
-		MinScore:     minScore,
+	if len(faces) == 0 {
+		c.JSON(http.StatusOK, &models.ImageRecognitionResponse{
+			Faces: []models.RecognizedFace{},
+		})
+		return
+	}
+
+	// Recognize all faces together so that one person is never assigned to two faces
+	req := &models.BatchRecognitionRequest{
+		Items:           make([]models.BatchRecognitionItem, len(faces)),
+		ModelVersion:    h.faceExtractionService.ModelVersion(),
+		TopK:            topK,
+		MinScore:        minScore,
+		Aggregation:     aggregation,
+		AggregationTopN: aggregationTopN,
+		AmbiguityMargin: ambiguityMargin,
+		LogEncounter:    logEncounter,
+	}
+	for i, face := range faces {
+		req.Items[i] = models.BatchRecognitionItem{
+			TrackID:      strconv.Itoa(i),
+			Embedding:    face.Embedding,
+			EmbeddingDim: len(face.Embedding),
+		}
 	}
 
 	// Perform recognition
-	response, err := h.recognitionService.Recognize(req)
+	batch, err := h.recognitionService.RecognizeBatch(req)
 	if err != nil {
-		errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		respondWithRecognitionError(c, err)
 		return
 	}
 
+	// Results come back in request order
+	response := &models.ImageRecognitionResponse{
+		Faces: make([]models.RecognizedFace, len(batch.Items)),
+	}
+	for i, item := range batch.Items {
+		response.Faces[i] = models.RecognizedFace{
+			BoundingBox:         faces[i].BoundingBox,
+			DetectionConfidence: faces[i].Confidence,
+			RecognitionResponse: item.RecognitionResponse,
+		}
+	}
+
 	c.JSON(http.StatusOK, response)
 }
+
+// respondWithExtractionError maps face extraction errors to Problem responses
+func respondWithExtractionError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "extraction queue is full":
+		errors.RespondWithError(c, errors.ServiceUnavailable("Face extraction is busy; retry later"))
+	case "extraction worker unavailable", "extraction pool is closed":
+		errors.RespondWithError(c, errors.ServiceUnavailable("Face extraction is unavailable"))
+	case "extraction timed out":
+		errors.RespondWithError(c, errors.GatewayTimeout("Face extraction timed out"))
+	default:
+		// This could be because no face was found, or the image was invalid.
+		errors.RespondWithError(c, errors.BadRequest(fmt.Sprintf("Failed to process image: %v", err)))
+	}
+}
+
+// respondWithRecognitionError maps recognition service errors to Problem responses
+func respondWithRecognitionError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "unknown model version":
+		errors.RespondWithError(c, errors.UnprocessableEntity("Unknown model_version; register it via /v1/models"))
+	case "embedding dimension does not match model":
+		errors.RespondWithError(c, errors.UnprocessableEntity("Embedding length does not match the model dimension"))
+	default:
+		errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+	}
+}
@@ -2,17 +2,26 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jphacks/os_2522/backend/internal/database"
 	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/service"
+	"github.com/jphacks/os_2522/backend/internal/vectorindex"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockRecognitionService is a mock implementation of RecognitionService
@@ -28,12 +37,20 @@ func (m *MockRecognitionService) Recognize(req *models.RecognitionRequest) (*mod
 	return args.Get(0).(*models.RecognitionResponse), args.Error(1)
 }
 
+func (m *MockRecognitionService) RecognizeBatch(req *models.BatchRecognitionRequest) (*models.BatchRecognitionResponse, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BatchRecognitionResponse), args.Error(1)
+}
+
 // MockFaceExtractionService is a mock implementation of FaceExtractionService
 type MockFaceExtractionService struct {
 	mock.Mock
 }
 
-func (m *MockFaceExtractionService) ExtractEmbedding(file *multipart.FileHeader) ([]float32, error) {
+func (m *MockFaceExtractionService) ExtractEmbedding(ctx context.Context, file *multipart.FileHeader) ([]float32, error) {
 	args := m.Called(file)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -41,6 +58,18 @@ func (m *MockFaceExtractionService) ExtractEmbedding(file *multipart.FileHeader)
 	return args.Get(0).([]float32), args.Error(1)
 }
 
+func (m *MockFaceExtractionService) ExtractFaces(ctx context.Context, file *multipart.FileHeader) ([]models.DetectedFace, error) {
+	args := m.Called(file)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DetectedFace), args.Error(1)
+}
+
+func (m *MockFaceExtractionService) ModelVersion() string {
+	return "facenet-tflite-v1"
+}
+
 func TestRecognitionHandler_PostRecognize(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -80,6 +109,12 @@ func TestRecognitionHandler_PostRecognize(t *testing.T) {
 					Candidates: []models.RecognitionCandidate{
 						{PersonID: "p-123", Name: "Test User", Score: 0.95},
 					},
+					CreatedEncounter: &models.Encounter{
+						EncounterID:  "e-123",
+						PersonID:     "p-123",
+						RecognizedAt: time.Now(),
+						Score:        0.95,
+					},
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -91,6 +126,8 @@ func TestRecognitionHandler_PostRecognize(t *testing.T) {
 				assert.NotNil(t, response.BestMatch)
 				assert.Equal(t, "p-123", response.BestMatch.PersonID)
 				assert.Equal(t, 0.95, response.BestMatch.Score)
+				assert.NotNil(t, response.CreatedEncounter)
+				assert.Equal(t, "e-123", response.CreatedEncounter.EncounterID)
 			},
 		},
 		{
@@ -118,6 +155,32 @@ func TestRecognitionHandler_PostRecognize(t *testing.T) {
 				assert.Nil(t, response.BestMatch)
 			},
 		},
+		{
+			name: "successful recognition - ambiguous",
+			requestBody: models.RecognitionRequest{
+				Embedding:    createTestEmbedding(),
+				EmbeddingDim: 512,
+				ModelVersion: "facenet-tflite-v1",
+			},
+			mockSetup: func(m *MockRecognitionService) {
+				m.On("Recognize", mock.AnythingOfType("*models.RecognitionRequest")).Return(&models.RecognitionResponse{
+					Status: models.RecognitionStatusAmbiguous,
+					Candidates: []models.RecognitionCandidate{
+						{PersonID: "p-123", Name: "Test User", Score: 0.81},
+						{PersonID: "p-456", Name: "Other User", Score: 0.80},
+					},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var response models.RecognitionResponse
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, models.RecognitionStatusAmbiguous, response.Status)
+				assert.Nil(t, response.BestMatch)
+				assert.Len(t, response.Candidates, 2)
+			},
+		},
 		{
 			name: "custom top_k and min_score",
 			requestBody: models.RecognitionRequest{
@@ -155,7 +218,7 @@ func TestRecognitionHandler_PostRecognize(t *testing.T) {
 				ModelVersion: "facenet-tflite-v1",
 			},
 			mockSetup:      func(m *MockRecognitionService) {},
-			expectedStatus: http.StatusBadRequest, // Gin validation returns 400
+			expectedStatus: http.StatusUnprocessableEntity, // Length checked against embedding_dim
 		},
 		{
 			name: "invalid embedding dimension - mismatch",
@@ -165,7 +228,7 @@ func TestRecognitionHandler_PostRecognize(t *testing.T) {
 				ModelVersion: "facenet-tflite-v1",
 			},
 			mockSetup:      func(m *MockRecognitionService) {},
-			expectedStatus: http.StatusBadRequest, // Gin validation returns 400
+			expectedStatus: http.StatusUnprocessableEntity, // Length checked against embedding_dim
 		},
 		{
 			name: "missing model version",
@@ -176,6 +239,41 @@ func TestRecognitionHandler_PostRecognize(t *testing.T) {
 			mockSetup:      func(m *MockRecognitionService) {},
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name: "invalid aggregation strategy",
+			requestBody: map[string]interface{}{
+				"embedding":     createTestEmbedding(),
+				"embedding_dim": 512,
+				"model_version": "facenet-tflite-v1",
+				"aggregation":   "median",
+			},
+			mockSetup:      func(m *MockRecognitionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "unknown model version",
+			requestBody: models.RecognitionRequest{
+				Embedding:    createTestEmbedding(),
+				EmbeddingDim: 512,
+				ModelVersion: "arcface-v9",
+			},
+			mockSetup: func(m *MockRecognitionService) {
+				m.On("Recognize", mock.AnythingOfType("*models.RecognitionRequest")).Return(nil, errors.New("unknown model version"))
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "dimension does not match registered model",
+			requestBody: models.RecognitionRequest{
+				Embedding:    make([]float32, 128),
+				EmbeddingDim: 128,
+				ModelVersion: "facenet-tflite-v1",
+			},
+			mockSetup: func(m *MockRecognitionService) {
+				m.On("Recognize", mock.AnythingOfType("*models.RecognitionRequest")).Return(nil, errors.New("embedding dimension does not match model"))
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
 		{
 			name:           "invalid JSON",
 			requestBody:    "invalid json",
@@ -247,6 +345,167 @@ func TestRecognitionHandler_PostRecognize(t *testing.T) {
 	}
 }
 
+func TestRecognitionHandler_PostRecognizeBatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	createTestEmbedding := func() []float32 {
+		embedding := make([]float32, 512)
+		for i := range embedding {
+			embedding[i] = 0.5
+		}
+		return embedding
+	}
+
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		mockSetup      func(*MockRecognitionService)
+		expectedStatus int
+		checkResponse  func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "successful batch recognition",
+			requestBody: models.BatchRecognitionRequest{
+				Items: []models.BatchRecognitionItem{
+					{TrackID: "t-1", Embedding: createTestEmbedding(), EmbeddingDim: 512},
+					{TrackID: "t-2", Embedding: createTestEmbedding(), EmbeddingDim: 512},
+				},
+				ModelVersion: "facenet-tflite-v1",
+			},
+			mockSetup: func(m *MockRecognitionService) {
+				m.On("RecognizeBatch", mock.AnythingOfType("*models.BatchRecognitionRequest")).Return(&models.BatchRecognitionResponse{
+					Items: []models.BatchRecognitionItemResult{
+						{
+							TrackID: "t-1",
+							RecognitionResponse: models.RecognitionResponse{
+								Status:     models.RecognitionStatusKnown,
+								BestMatch:  &models.RecognitionCandidate{PersonID: "p-123", Name: "Test User", Score: 0.95},
+								Candidates: []models.RecognitionCandidate{{PersonID: "p-123", Name: "Test User", Score: 0.95}},
+							},
+						},
+						{
+							TrackID: "t-2",
+							RecognitionResponse: models.RecognitionResponse{
+								Status:     models.RecognitionStatusUnknown,
+								Candidates: []models.RecognitionCandidate{},
+							},
+						},
+					},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var response models.BatchRecognitionResponse
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Len(t, response.Items, 2)
+				assert.Equal(t, "t-1", response.Items[0].TrackID)
+				assert.Equal(t, models.RecognitionStatusKnown, response.Items[0].Status)
+				assert.Equal(t, "p-123", response.Items[0].BestMatch.PersonID)
+				assert.Equal(t, "t-2", response.Items[1].TrackID)
+				assert.Equal(t, models.RecognitionStatusUnknown, response.Items[1].Status)
+			},
+		},
+		{
+			name: "empty items",
+			requestBody: models.BatchRecognitionRequest{
+				Items:        []models.BatchRecognitionItem{},
+				ModelVersion: "facenet-tflite-v1",
+			},
+			mockSetup:      func(m *MockRecognitionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "missing track_id",
+			requestBody: models.BatchRecognitionRequest{
+				Items: []models.BatchRecognitionItem{
+					{Embedding: createTestEmbedding(), EmbeddingDim: 512},
+				},
+				ModelVersion: "facenet-tflite-v1",
+			},
+			mockSetup:      func(m *MockRecognitionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "duplicate track_id",
+			requestBody: models.BatchRecognitionRequest{
+				Items: []models.BatchRecognitionItem{
+					{TrackID: "t-1", Embedding: createTestEmbedding(), EmbeddingDim: 512},
+					{TrackID: "t-1", Embedding: createTestEmbedding(), EmbeddingDim: 512},
+				},
+				ModelVersion: "facenet-tflite-v1",
+			},
+			mockSetup:      func(m *MockRecognitionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "embedding dimension mismatch in one item",
+			requestBody: models.BatchRecognitionRequest{
+				Items: []models.BatchRecognitionItem{
+					{TrackID: "t-1", Embedding: createTestEmbedding(), EmbeddingDim: 512},
+					{TrackID: "t-2", Embedding: createTestEmbedding(), EmbeddingDim: 128},
+				},
+				ModelVersion: "facenet-tflite-v1",
+			},
+			mockSetup:      func(m *MockRecognitionService) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "unknown model version",
+			requestBody: models.BatchRecognitionRequest{
+				Items: []models.BatchRecognitionItem{
+					{TrackID: "t-1", Embedding: createTestEmbedding(), EmbeddingDim: 512},
+				},
+				ModelVersion: "unknown-v1",
+			},
+			mockSetup: func(m *MockRecognitionService) {
+				m.On("RecognizeBatch", mock.AnythingOfType("*models.BatchRecognitionRequest")).Return(nil, errors.New("unknown model version"))
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "service error",
+			requestBody: models.BatchRecognitionRequest{
+				Items: []models.BatchRecognitionItem{
+					{TrackID: "t-1", Embedding: createTestEmbedding(), EmbeddingDim: 512},
+				},
+				ModelVersion: "facenet-tflite-v1",
+			},
+			mockSetup: func(m *MockRecognitionService) {
+				m.On("RecognizeBatch", mock.AnythingOfType("*models.BatchRecognitionRequest")).Return(nil, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRecogService := new(MockRecognitionService)
+			mockExtractService := new(MockFaceExtractionService)
+			tt.mockSetup(mockRecogService)
+
+			router := gin.New()
+			handler := NewRecognitionHandler(mockRecogService, mockExtractService)
+			router.POST("/recognize/batch", handler.PostRecognizeBatch)
+
+			body, err := json.Marshal(tt.requestBody)
+			assert.NoError(t, err)
+
+			req, _ := http.NewRequest(http.MethodPost, "/recognize/batch", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkResponse != nil {
+				tt.checkResponse(t, w)
+			}
+			mockRecogService.AssertExpectations(t)
+		})
+	}
+}
+
 func TestRecognitionHandler_PostRecognizeImage(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -258,6 +517,23 @@ func TestRecognitionHandler_PostRecognizeImage(t *testing.T) {
 		return embedding
 	}
 
+	createTestFaces := func() []models.DetectedFace {
+		return []models.DetectedFace{
+			{BoundingBox: models.BoundingBox{X: 10, Y: 20, Width: 100, Height: 120}, Confidence: 0.98, Embedding: createTestEmbedding()},
+		}
+	}
+
+	batchResponse := func(statuses ...models.RecognitionStatus) *models.BatchRecognitionResponse {
+		response := &models.BatchRecognitionResponse{}
+		for i, status := range statuses {
+			response.Items = append(response.Items, models.BatchRecognitionItemResult{
+				TrackID:             strconv.Itoa(i),
+				RecognitionResponse: models.RecognitionResponse{Status: status, Candidates: []models.RecognitionCandidate{}},
+			})
+		}
+		return response
+	}
+
 	tests := []struct {
 		name           string
 		formData       map[string]string
@@ -265,6 +541,7 @@ func TestRecognitionHandler_PostRecognizeImage(t *testing.T) {
 		fileContent    string
 		mockSetup      func(*MockRecognitionService, *MockFaceExtractionService)
 		expectedStatus int
+		checkResponse  func(*testing.T, *httptest.ResponseRecorder)
 	}{
 		{
 			name:        "successful recognition",
@@ -272,13 +549,154 @@ func TestRecognitionHandler_PostRecognizeImage(t *testing.T) {
 			fileName:    "test.jpg",
 			fileContent: "fake-image-data",
 			mockSetup: func(mrs *MockRecognitionService, mfes *MockFaceExtractionService) {
-				mfes.On("ExtractEmbedding", mock.AnythingOfType("*multipart.FileHeader")).Return(createTestEmbedding(), nil)
-				mrs.On("Recognize", mock.MatchedBy(func(req *models.RecognitionRequest) bool {
+				mfes.On("ExtractFaces", mock.AnythingOfType("*multipart.FileHeader")).Return(createTestFaces(), nil)
+				mrs.On("RecognizeBatch", mock.MatchedBy(func(req *models.BatchRecognitionRequest) bool {
 					return req.TopK == 5 && req.MinScore == 0.7
-				})).Return(&models.RecognitionResponse{Status: models.RecognitionStatusKnown}, nil)
+				})).Return(batchResponse(models.RecognitionStatusKnown), nil)
 			},
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name:        "min_score defaults to the model threshold",
+			formData:    map[string]string{},
+			fileName:    "test.jpg",
+			fileContent: "fake-image-data",
+			mockSetup: func(mrs *MockRecognitionService, mfes *MockFaceExtractionService) {
+				mfes.On("ExtractFaces", mock.AnythingOfType("*multipart.FileHeader")).Return(createTestFaces(), nil)
+				mrs.On("RecognizeBatch", mock.MatchedBy(func(req *models.BatchRecognitionRequest) bool {
+					return req.MinScore == 0
+				})).Return(batchResponse(models.RecognitionStatusUnknown), nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "multiple faces",
+			formData:    map[string]string{},
+			fileName:    "group.jpg",
+			fileContent: "fake-image-data",
+			mockSetup: func(mrs *MockRecognitionService, mfes *MockFaceExtractionService) {
+				mfes.On("ExtractFaces", mock.AnythingOfType("*multipart.FileHeader")).Return([]models.DetectedFace{
+					{BoundingBox: models.BoundingBox{X: 10, Y: 20, Width: 100, Height: 120}, Confidence: 0.98, Embedding: createTestEmbedding()},
+					{BoundingBox: models.BoundingBox{X: 300, Y: 40, Width: 80, Height: 90}, Confidence: 0.75, Embedding: createTestEmbedding()},
+				}, nil)
+				mrs.On("RecognizeBatch", mock.MatchedBy(func(req *models.BatchRecognitionRequest) bool {
+					return len(req.Items) == 2 && req.Items[0].TrackID == "0" && req.Items[1].EmbeddingDim == 512
+				})).Return(batchResponse(models.RecognitionStatusKnown, models.RecognitionStatusUnknown), nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var response models.ImageRecognitionResponse
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Len(t, response.Faces, 2)
+				assert.Equal(t, models.RecognitionStatusKnown, response.Faces[0].Status)
+				assert.Equal(t, 100, response.Faces[0].BoundingBox.Width)
+				assert.Equal(t, 0.98, response.Faces[0].DetectionConfidence)
+				assert.Equal(t, models.RecognitionStatusUnknown, response.Faces[1].Status)
+				assert.Equal(t, 300, response.Faces[1].BoundingBox.X)
+			},
+		},
+		{
+			name:        "no faces detected",
+			formData:    map[string]string{},
+			fileName:    "empty.jpg",
+			fileContent: "fake-image-data",
+			mockSetup: func(mrs *MockRecognitionService, mfes *MockFaceExtractionService) {
+				mfes.On("ExtractFaces", mock.AnythingOfType("*multipart.FileHeader")).Return([]models.DetectedFace{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var response models.ImageRecognitionResponse
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.NotNil(t, response.Faces)
+				assert.Empty(t, response.Faces)
+			},
+		},
+		{
+			name:        "with aggregation strategy",
+			formData:    map[string]string{"aggregation": "centroid"},
+			fileName:    "test.jpg",
+			fileContent: "fake-image-data",
+			mockSetup: func(mrs *MockRecognitionService, mfes *MockFaceExtractionService) {
+				mfes.On("ExtractFaces", mock.AnythingOfType("*multipart.FileHeader")).Return(createTestFaces(), nil)
+				mrs.On("RecognizeBatch", mock.MatchedBy(func(req *models.BatchRecognitionRequest) bool {
+					return req.Aggregation == models.ScoreAggregationCentroid
+				})).Return(batchResponse(models.RecognitionStatusKnown), nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "with mean_top_n aggregation",
+			formData:    map[string]string{"aggregation": "mean_top_n", "aggregation_top_n": "5"},
+			fileName:    "test.jpg",
+			fileContent: "fake-image-data",
+			mockSetup: func(mrs *MockRecognitionService, mfes *MockFaceExtractionService) {
+				mfes.On("ExtractFaces", mock.AnythingOfType("*multipart.FileHeader")).Return(createTestFaces(), nil)
+				mrs.On("RecognizeBatch", mock.MatchedBy(func(req *models.BatchRecognitionRequest) bool {
+					return req.Aggregation == models.ScoreAggregationMeanTopN && req.AggregationTopN == 5
+				})).Return(batchResponse(models.RecognitionStatusKnown), nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "with ambiguity margin",
+			formData:    map[string]string{"ambiguity_margin": "0.1"},
+			fileName:    "test.jpg",
+			fileContent: "fake-image-data",
+			mockSetup: func(mrs *MockRecognitionService, mfes *MockFaceExtractionService) {
+				mfes.On("ExtractFaces", mock.AnythingOfType("*multipart.FileHeader")).Return(createTestFaces(), nil)
+				mrs.On("RecognizeBatch", mock.MatchedBy(func(req *models.BatchRecognitionRequest) bool {
+					return req.AmbiguityMargin != nil && *req.AmbiguityMargin == 0.1
+				})).Return(batchResponse(models.RecognitionStatusAmbiguous), nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "opt out of encounter logging",
+			formData:    map[string]string{"log_encounter": "false"},
+			fileName:    "test.jpg",
+			fileContent: "fake-image-data",
+			mockSetup: func(mrs *MockRecognitionService, mfes *MockFaceExtractionService) {
+				mfes.On("ExtractFaces", mock.AnythingOfType("*multipart.FileHeader")).Return(createTestFaces(), nil)
+				mrs.On("RecognizeBatch", mock.MatchedBy(func(req *models.BatchRecognitionRequest) bool {
+					return req.LogEncounter != nil && !*req.LogEncounter
+				})).Return(batchResponse(models.RecognitionStatusKnown), nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid log_encounter",
+			formData:       map[string]string{"log_encounter": "maybe"},
+			fileName:       "test.jpg",
+			fileContent:    "fake-image-data",
+			mockSetup:      func(mrs *MockRecognitionService, mfes *MockFaceExtractionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid ambiguity margin",
+			formData:       map[string]string{"ambiguity_margin": "1.5"},
+			fileName:       "test.jpg",
+			fileContent:    "fake-image-data",
+			mockSetup:      func(mrs *MockRecognitionService, mfes *MockFaceExtractionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid aggregation_top_n",
+			formData:       map[string]string{"aggregation": "mean_top_n", "aggregation_top_n": "21"},
+			fileName:       "test.jpg",
+			fileContent:    "fake-image-data",
+			mockSetup:      func(mrs *MockRecognitionService, mfes *MockFaceExtractionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid aggregation",
+			formData:       map[string]string{"aggregation": "median"},
+			fileName:       "test.jpg",
+			fileContent:    "fake-image-data",
+			mockSetup:      func(mrs *MockRecognitionService, mfes *MockFaceExtractionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name:           "missing image file",
 			formData:       map[string]string{},
@@ -293,10 +711,30 @@ func TestRecognitionHandler_PostRecognizeImage(t *testing.T) {
 			fileName:    "test.jpg",
 			fileContent: "fake-image-data",
 			mockSetup: func(mrs *MockRecognitionService, mfes *MockFaceExtractionService) {
-				mfes.On("ExtractEmbedding", mock.AnythingOfType("*multipart.FileHeader")).Return(nil, errors.New("face not found"))
+				mfes.On("ExtractFaces", mock.AnythingOfType("*multipart.FileHeader")).Return(nil, errors.New("invalid image"))
 			},
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name:        "extraction queue full",
+			formData:    map[string]string{},
+			fileName:    "test.jpg",
+			fileContent: "fake-image-data",
+			mockSetup: func(mrs *MockRecognitionService, mfes *MockFaceExtractionService) {
+				mfes.On("ExtractFaces", mock.AnythingOfType("*multipart.FileHeader")).Return(nil, errors.New("extraction queue is full"))
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:        "extraction timeout",
+			formData:    map[string]string{},
+			fileName:    "test.jpg",
+			fileContent: "fake-image-data",
+			mockSetup: func(mrs *MockRecognitionService, mfes *MockFaceExtractionService) {
+				mfes.On("ExtractFaces", mock.AnythingOfType("*multipart.FileHeader")).Return(nil, errors.New("extraction timed out"))
+			},
+			expectedStatus: http.StatusGatewayTimeout,
+		},
 		{
 			name:           "invalid top_k",
 			formData:       map[string]string{"top_k": "invalid"},
@@ -311,8 +749,8 @@ func TestRecognitionHandler_PostRecognizeImage(t *testing.T) {
 			fileName:    "test.jpg",
 			fileContent: "fake-image-data",
 			mockSetup: func(mrs *MockRecognitionService, mfes *MockFaceExtractionService) {
-				mfes.On("ExtractEmbedding", mock.AnythingOfType("*multipart.FileHeader")).Return(createTestEmbedding(), nil)
-				mrs.On("Recognize", mock.AnythingOfType("*models.RecognitionRequest")).Return(nil, errors.New("database error"))
+				mfes.On("ExtractFaces", mock.AnythingOfType("*multipart.FileHeader")).Return(createTestFaces(), nil)
+				mrs.On("RecognizeBatch", mock.AnythingOfType("*models.BatchRecognitionRequest")).Return(nil, errors.New("database error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -348,8 +786,59 @@ func TestRecognitionHandler_PostRecognizeImage(t *testing.T) {
 			router.ServeHTTP(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkResponse != nil {
+				tt.checkResponse(t, w)
+			}
 			mockRecogService.AssertExpectations(t)
 			mockExtractService.AssertExpectations(t)
 		})
 	}
 }
+
+func TestRecognitionHandler_PostRecognizeImage_ModelThreshold(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.NewDB(&database.Config{Driver: "sqlite", DBName: "file:recognize_image_threshold?mode=memory&cache=shared"})
+	require.NoError(t, err)
+	require.NoError(t, database.AutoMigrate(db))
+	now := time.Now()
+	modelRepo := repository.NewModelRepository(db)
+	personRepo := repository.NewPersonRepository(db)
+	// Re-register the seeded model as a euclidean one with its own threshold
+	require.NoError(t, db.Model(&repository.EmbeddingModelEntity{}).Where("model_version = ?", "facenet-tflite-v1").Updates(map[string]interface{}{
+		"dimension": 2, "metric": string(models.DistanceMetricEuclidean), "default_threshold": 0.7,
+	}).Error)
+	require.NoError(t, personRepo.Create(&repository.PersonEntity{PersonID: "p-1", Name: "Alice", CreatedAt: now, UpdatedAt: now}))
+
+	// Cosine 0.9 is a euclidean score of about 0.69: above 0.6 but below the model's threshold
+	faceIndex := vectorindex.NewCollection(vectorindex.DefaultConfig())
+	faceIndex.Add("facenet-tflite", vectorindex.Entry{FaceID: "f-1", PersonID: "p-1", Embedding: []float32{0.9, float32(math.Sqrt(1 - 0.81))}})
+	recognitionService := service.NewRecognitionService(faceIndex, service.NewModelService(modelRepo), personRepo,
+		repository.NewEncounterRepository(db), service.GetRecognitionConfigFromEnv())
+
+	extractService := new(MockFaceExtractionService)
+	extractService.On("ExtractFaces", mock.AnythingOfType("*multipart.FileHeader")).Return([]models.DetectedFace{
+		{BoundingBox: models.BoundingBox{Width: 100, Height: 100}, Confidence: 0.98, Embedding: []float32{1, 0}},
+	}, nil)
+
+	router := gin.New()
+	router.POST("/recognize-image", NewRecognitionHandler(recognitionService, extractService).PostRecognizeImage)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("image", "test.jpg")
+	_, _ = part.Write([]byte("fake-image-data"))
+	_ = writer.WriteField("log_encounter", "false")
+	writer.Close()
+	req, _ := http.NewRequest(http.MethodPost, "/recognize-image", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var response models.ImageRecognitionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Faces, 1)
+	assert.Equal(t, models.RecognitionStatusUnknown, response.Faces[0].Status)
+	assert.Empty(t, response.Faces[0].Candidates)
+}
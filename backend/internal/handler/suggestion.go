@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jphacks/os_2522/backend/internal/errors"
+)
+
+// SuggestionHandler handles conversation suggestion requests
+type SuggestionHandler struct {
+	suggestionService SuggestionServiceInterface
+}
+
+// NewSuggestionHandler creates a new SuggestionHandler
+func NewSuggestionHandler(suggestionService SuggestionServiceInterface) *SuggestionHandler {
+	return &SuggestionHandler{suggestionService: suggestionService}
+}
+
+// GetSuggestions handles GET /persons/{person_id}/suggestions
+func (h *SuggestionHandler) GetSuggestions(c *gin.Context) {
+	personID := c.Param("person_id")
+
+	refresh, err := strconv.ParseBool(c.DefaultQuery("refresh", "false"))
+	if err != nil {
+		errors.RespondWithError(c, errors.BadRequest("Invalid refresh parameter"))
+		return
+	}
+
+	suggestions, err := h.suggestionService.GetSuggestions(c.Request.Context(), personID, refresh)
+	if err != nil {
+		switch msg := err.Error(); {
+		case msg == "person not found":
+			errors.RespondWithError(c, errors.NotFound("Person not found"))
+		case msg == "suggestions are not configured":
+			errors.RespondWithError(c, errors.ServiceUnavailable("Suggestions are not configured"))
+		case strings.HasPrefix(msg, "suggestions are unavailable"):
+			errors.RespondWithError(c, errors.ServiceUnavailable("Suggestions are unavailable; retry later"))
+		default:
+			errors.RespondWithError(c, errors.InternalServerError(msg))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSuggestionService is a mock implementation of SuggestionService
+type MockSuggestionService struct {
+	mock.Mock
+}
+
+func (m *MockSuggestionService) GetSuggestions(ctx context.Context, personID string, refresh bool) (*models.SuggestionList, error) {
+	args := m.Called(personID, refresh)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SuggestionList), args.Error(1)
+}
+
+func TestSuggestionHandler_GetSuggestions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	lastMet := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	days := 14
+	generated := time.Date(2025, 10, 15, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		personID       string
+		queryParams    string
+		mockSetup      func(*MockSuggestionService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:     "successful suggestions",
+			personID: "p-123",
+			mockSetup: func(m *MockSuggestionService) {
+				m.On("GetSuggestions", "p-123", false).Return(&models.SuggestionList{
+					PersonID:         "p-123",
+					LastMetAt:        &lastMet,
+					DaysSinceLastMet: &days,
+					Items: []models.Suggestion{
+						{Rank: 1, Opener: "旅行の写真、見ましたよ！", Reason: "写真を送ると約束していた"},
+					},
+					GeneratedAt: generated,
+					Cached:      true,
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"person_id":"p-123","last_met_at":"2025-10-01T12:00:00Z","days_since_last_met":14,` +
+				`"items":[{"rank":1,"opener":"旅行の写真、見ましたよ！","reason":"写真を送ると約束していた"}],` +
+				`"generated_at":"2025-10-15T09:00:00Z","cached":true}`,
+		},
+		{
+			name:        "refresh",
+			personID:    "p-123",
+			queryParams: "?refresh=true",
+			mockSetup: func(m *MockSuggestionService) {
+				m.On("GetSuggestions", "p-123", true).Return(&models.SuggestionList{
+					PersonID: "p-123",
+					Items:    []models.Suggestion{},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid refresh",
+			personID:       "p-123",
+			queryParams:    "?refresh=maybe",
+			mockSetup:      func(m *MockSuggestionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:     "person not found",
+			personID: "p-999",
+			mockSetup: func(m *MockSuggestionService) {
+				m.On("GetSuggestions", "p-999", false).Return(nil, errors.New("person not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:     "not configured",
+			personID: "p-123",
+			mockSetup: func(m *MockSuggestionService) {
+				m.On("GetSuggestions", "p-123", false).Return(nil, errors.New("suggestions are not configured"))
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:     "model unavailable",
+			personID: "p-123",
+			mockSetup: func(m *MockSuggestionService) {
+				m.On("GetSuggestions", "p-123", false).Return(nil, errors.New("suggestions are unavailable: language model unavailable: timeout"))
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:     "internal error",
+			personID: "p-123",
+			mockSetup: func(m *MockSuggestionService) {
+				m.On("GetSuggestions", "p-123", false).Return(nil, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockSuggestionService)
+			tt.mockSetup(mockService)
+
+			handler := NewSuggestionHandler(mockService)
+			router := gin.New()
+			router.GET("/persons/:person_id/suggestions", handler.GetSuggestions)
+
+			req, _ := http.NewRequest(http.MethodGet, "/persons/"+tt.personID+"/suggestions"+tt.queryParams, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jphacks/os_2522/backend/internal/errors"
@@ -34,14 +36,35 @@ func (h *SummarizeHandler) PostSummarize(c *gin.Context) {
 		return
 	}
 
-	summary, err := h.summarizeService.Summarize(c.Request.Context(), req.Text)
+	date := time.Now()
+	if req.Date != "" {
+		parsed, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			errors.RespondWithError(c, errors.BadRequest("date must be in YYYY-MM-DD format"))
+			return
+		}
+		date = parsed
+	}
+
+	summary, metadata, err := h.summarizeService.Summarize(c.Request.Context(), req.Text, date)
 	if err != nil {
-		errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		switch msg := err.Error(); {
+		case strings.HasPrefix(msg, "text is too long"):
+			errors.RespondWithError(c, errors.PayloadTooLarge(msg))
+		case msg == "summarization is not configured":
+			errors.RespondWithError(c, errors.ServiceUnavailable("Summarization is not configured"))
+		case strings.HasPrefix(msg, "summarization is unavailable"):
+			errors.RespondWithError(c, errors.ServiceUnavailable("Summarization is unavailable; retry later"))
+		default:
+			errors.RespondWithError(c, errors.InternalServerError(msg))
+		}
 		return
 	}
 
 	resp := models.SummarizeResponse{
-		Summary: summary,
+		Summary:  summary.Headline,
+		Details:  *summary,
+		Metadata: *metadata,
 	}
 
 	c.JSON(http.StatusOK, resp)
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jphacks/os_2522/backend/internal/llm"
+	"github.com/jphacks/os_2522/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingProvider is an llm.Provider whose requests always fail
+type failingProvider struct {
+	err error
+}
+
+func (p *failingProvider) Name() string { return "failing" }
+func (p *failingProvider) Close()       {}
+func (p *failingProvider) Generate(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	return nil, p.err
+}
+
+func TestSummarizeHandler_PostSummarize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		provider       llm.Provider
+		config         *service.SummarizeConfig
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "successful summary",
+			provider: llm.NewFakeProvider(llm.FakeConfig{
+				Response: "```json\n" + `{"headline":" 要約 ","topics":["旅行"],"facts":[{"category":"family","fact":"子どもが2人いる"}],"follow_ups":[{"description":"写真を送る","due_date":"2025-10-20"},{"description":"また会う","due_date":null}]}` + "\n```",
+			}),
+			body:           `{"text":"長い文章","date":"2025-10-18"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"summary":"要約","details":{"headline":"要約","topics":["旅行"],` +
+				`"facts":[{"category":"family","fact":"子どもが2人いる"}],` +
+				`"follow_ups":[{"description":"写真を送る","due_date":"2025-10-20"},{"description":"また会う","due_date":null}]},` +
+				`"metadata":{"estimated_tokens":4,"chunks":1,"reduce_rounds":0}}`,
+		},
+		{
+			name:           "summary from fake provider matches the schema",
+			provider:       llm.NewFakeProvider(llm.FakeConfig{}),
+			body:           `{"text":"長い文章","date":"2025-10-18"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"summary":"[fake] 会話の日付: 2025-10-18 長い文章","details":{"headline":"[fake] 会話の日付: 2025-10-18 長い文章","topics":[],"facts":[],"follow_ups":[]},"metadata":{"estimated_tokens":4,"chunks":1,"reduce_rounds":0}}`,
+		},
+		{
+			name:           "long text is summarized in chunks",
+			provider:       llm.NewFakeProvider(llm.FakeConfig{Response: `{"headline":"要約","topics":[],"facts":[],"follow_ups":[]}`}),
+			config:         &service.SummarizeConfig{MaxAttempts: 2, ChunkTokens: 10, MaxChunks: 3},
+			body:           `{"text":"一行目の話です。\n二行目の話です。\n三行目の話です。"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"summary":"要約","details":{"headline":"要約","topics":[],"facts":[],"follow_ups":[]},"metadata":{"estimated_tokens":25,"chunks":3,"reduce_rounds":2}}`,
+		},
+		{
+			name:           "text longer than the chunk limit",
+			provider:       llm.NewFakeProvider(llm.FakeConfig{}),
+			config:         &service.SummarizeConfig{MaxAttempts: 2, ChunkTokens: 10, MaxChunks: 2},
+			body:           `{"text":"一行目の話です。\n二行目の話です。\n三行目の話です。"}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "malformed output",
+			provider:       llm.NewFakeProvider(llm.FakeConfig{Response: `{"headline":"要約","topics":[],"facts":[{"category":"pets","fact":"犬を飼っている"}],"follow_ups":[]}`}),
+			body:           `{"text":"長い文章"}`,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "invalid date",
+			provider:       llm.NewFakeProvider(llm.FakeConfig{}),
+			body:           `{"text":"長い文章","date":"18/10/2025"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing text",
+			provider:       llm.NewFakeProvider(llm.FakeConfig{}),
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "no provider configured",
+			provider:       nil,
+			body:           `{"text":"長い文章"}`,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "provider unavailable",
+			provider:       &failingProvider{err: fmt.Errorf("%w: 503 Service Unavailable", llm.ErrUnavailable)},
+			body:           `{"text":"長い文章"}`,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "unexpected error",
+			provider:       &failingProvider{err: fmt.Errorf("boom")},
+			body:           `{"text":"長い文章"}`,
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			if config == nil {
+				config = &service.SummarizeConfig{MaxAttempts: 2}
+			}
+			router := gin.New()
+			handler := NewSummarizeHandler(service.NewSummarizeService(tt.provider, config))
+			router.POST("/summarize", handler.PostSummarize)
+
+			req, _ := http.NewRequest(http.MethodPost, "/summarize", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
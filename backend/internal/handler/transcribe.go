@@ -1,10 +1,16 @@
 package handler
 
 import (
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jphacks/os_2522/backend/internal/errors"
+	"github.com/jphacks/os_2522/backend/internal/models"
 )
 
 // TranscribeHandler handles transcription requests
@@ -35,9 +41,18 @@ func (h *TranscribeHandler) PostTranscribe(c *gin.Context) {
 		webhookURL = &wh
 	}
 
-	job, err := h.jobService.CreateTranscriptionJob(personID, file, webhookURL)
+	job, err := h.jobService.CreateTranscriptionJob(c.Request.Context(), personID, file, webhookURL)
 	if err != nil {
-		errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		switch msg := err.Error(); {
+		case strings.HasPrefix(msg, "invalid webhook_url"):
+			errors.RespondWithError(c, errors.BadRequest(msg))
+		case strings.HasPrefix(msg, "unsupported audio"):
+			errors.RespondWithError(c, errors.UnsupportedMediaType(msg))
+		case msg == "audio is too large", msg == "audio is too long":
+			errors.RespondWithError(c, errors.PayloadTooLarge(msg))
+		default:
+			errors.RespondWithError(c, errors.InternalServerError(msg))
+		}
 		return
 	}
 
@@ -60,3 +75,217 @@ func (h *TranscribeHandler) GetJob(c *gin.Context) {
 
 	c.JSON(http.StatusOK, job)
 }
+
+// ListJobs handles GET /jobs
+func (h *TranscribeHandler) ListJobs(c *gin.Context) {
+	limitStr := c.DefaultQuery("limit", "20")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > 100 {
+		errors.RespondWithError(c, errors.BadRequest("Invalid limit parameter"))
+		return
+	}
+
+	var cursor *string
+	if c := c.Query("cursor"); c != "" {
+		cursor = &c
+	}
+
+	filter := &models.JobFilter{}
+	if statusParam := c.Query("status"); statusParam != "" {
+		status := models.JobStatus(statusParam)
+		if !status.IsValid() {
+			errors.RespondWithError(c, errors.BadRequest("Invalid status parameter"))
+			return
+		}
+		filter.Status = &status
+	}
+	if personID := c.Query("person_id"); personID != "" {
+		filter.PersonID = &personID
+	}
+	if filter.CreatedAfter, err = parseTimeQuery(c, "created_after"); err != nil {
+		errors.RespondWithError(c, errors.BadRequest("Invalid created_after parameter (expected RFC 3339)"))
+		return
+	}
+	if filter.CreatedBefore, err = parseTimeQuery(c, "created_before"); err != nil {
+		errors.RespondWithError(c, errors.BadRequest("Invalid created_before parameter (expected RFC 3339)"))
+		return
+	}
+
+	jobs, err := h.jobService.ListJobs(filter, limit, cursor)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid cursor") {
+			errors.RespondWithError(c, errors.BadRequest("Invalid cursor parameter"))
+			return
+		}
+		errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, jobs)
+}
+
+// parseTimeQuery parses an optional RFC 3339 query parameter
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CancelJob handles POST /jobs/{job_id}/cancel
+func (h *TranscribeHandler) CancelJob(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	job, err := h.jobService.CancelJob(jobID)
+	if err != nil {
+		switch err.Error() {
+		case "job not found":
+			errors.RespondWithError(c, errors.NotFound("Job not found"))
+		case "job cannot be cancelled":
+			errors.RespondWithError(c, errors.Conflict("Only queued or running jobs can be cancelled"))
+		default:
+			errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// RetryJob handles POST /jobs/{job_id}/retry
+func (h *TranscribeHandler) RetryJob(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	job, err := h.jobService.RetryJob(jobID)
+	if err != nil {
+		switch err.Error() {
+		case "job not found":
+			errors.RespondWithError(c, errors.NotFound("Job not found"))
+		case "job cannot be retried":
+			errors.RespondWithError(c, errors.Conflict("Only failed jobs can be retried"))
+		case "job audio is no longer available":
+			errors.RespondWithError(c, errors.Conflict("The job's audio has been deleted"))
+		default:
+			errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// DeleteJob handles DELETE /jobs/{job_id}
+func (h *TranscribeHandler) DeleteJob(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	err := h.jobService.DeleteJob(c.Request.Context(), jobID)
+	if err != nil {
+		switch err.Error() {
+		case "job not found":
+			errors.RespondWithError(c, errors.NotFound("Job not found"))
+		case "job is running":
+			errors.RespondWithError(c, errors.Conflict("Job is running and cannot be deleted"))
+		default:
+			errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries handles GET /jobs/{job_id}/deliveries
+func (h *TranscribeHandler) ListDeliveries(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	deliveries, err := h.jobService.ListDeliveries(jobID)
+	if err != nil {
+		if err.Error() == "job not found" {
+			errors.RespondWithError(c, errors.NotFound("Job not found"))
+			return
+		}
+		errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// GetTranscript handles GET /jobs/{job_id}/transcript
+func (h *TranscribeHandler) GetTranscript(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "srt" && format != "vtt" {
+		errors.RespondWithError(c, errors.BadRequest("format must be json, srt or vtt"))
+		return
+	}
+
+	transcript, err := h.jobService.GetTranscript(jobID)
+	if err != nil {
+		switch err.Error() {
+		case "job not found":
+			errors.RespondWithError(c, errors.NotFound("Job not found"))
+		case "transcript is not available":
+			errors.RespondWithError(c, errors.Conflict("The transcript is available once the job has succeeded"))
+		default:
+			errors.RespondWithError(c, errors.InternalServerError(err.Error()))
+		}
+		return
+	}
+
+	switch format {
+	case "srt":
+		c.Data(http.StatusOK, "application/x-subrip; charset=utf-8", []byte(formatSRT(transcript.Segments)))
+	case "vtt":
+		c.Data(http.StatusOK, "text/vtt; charset=utf-8", []byte(formatVTT(transcript.Segments)))
+	default:
+		c.JSON(http.StatusOK, transcript)
+	}
+}
+
+// formatSRT renders segments as SubRip subtitles, prefixing each cue with its speaker
+func formatSRT(segments []models.TranscriptSegment) string {
+	var b strings.Builder
+	for i, segment := range segments {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n", i+1, subtitleTime(segment.Start, ','), subtitleTime(segment.End, ','))
+		text := cueText(segment.Text)
+		if segment.Speaker != "" {
+			text = segment.Speaker + ": " + text
+		}
+		b.WriteString(text + "\n\n")
+	}
+	return b.String()
+}
+
+// formatVTT renders segments as WebVTT, marking speakers with voice spans
+func formatVTT(segments []models.TranscriptSegment) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	escaper := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	for _, segment := range segments {
+		fmt.Fprintf(&b, "\n%s --> %s\n", subtitleTime(segment.Start, '.'), subtitleTime(segment.End, '.'))
+		text := escaper.Replace(cueText(segment.Text))
+		if segment.Speaker != "" {
+			text = "<v " + escaper.Replace(segment.Speaker) + ">" + text
+		}
+		b.WriteString(text + "\n")
+	}
+	return b.String()
+}
+
+// cueText keeps a cue on one line, since blank lines end a cue in both formats
+func cueText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// subtitleTime formats seconds as HH:MM:SS followed by sep and milliseconds
+func subtitleTime(seconds float64, sep rune) string {
+	ms := int64(math.Round(math.Max(seconds, 0) * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
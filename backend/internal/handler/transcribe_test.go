@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"mime/multipart"
 	"net/http"
@@ -10,9 +11,14 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jphacks/os_2522/backend/internal/database"
+	"github.com/jphacks/os_2522/backend/internal/events"
 	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockJobService is a mock implementation of JobService
@@ -20,7 +26,7 @@ type MockJobService struct {
 	mock.Mock
 }
 
-func (m *MockJobService) CreateTranscriptionJob(personID *string, file *multipart.FileHeader, webhookURL *string) (*models.Job, error) {
+func (m *MockJobService) CreateTranscriptionJob(ctx context.Context, personID *string, file *multipart.FileHeader, webhookURL *string) (*models.Job, error) {
 	args := m.Called(personID, file, webhookURL)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -36,6 +42,43 @@ func (m *MockJobService) GetJob(jobID string) (*models.Job, error) {
 	return args.Get(0).(*models.Job), args.Error(1)
 }
 
+func (m *MockJobService) DeleteJob(ctx context.Context, jobID string) error {
+	args := m.Called(jobID)
+	return args.Error(0)
+}
+
+func (m *MockJobService) ListJobs(filter *models.JobFilter, limit int, cursor *string) (*models.JobList, error) {
+	args := m.Called(filter, limit, cursor)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.JobList), args.Error(1)
+}
+
+func (m *MockJobService) CancelJob(jobID string) (*models.Job, error) {
+	args := m.Called(jobID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Job), args.Error(1)
+}
+
+func (m *MockJobService) RetryJob(jobID string) (*models.Job, error) {
+	args := m.Called(jobID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Job), args.Error(1)
+}
+
+func (m *MockJobService) ListDeliveries(jobID string) (*models.WebhookDeliveryList, error) {
+	args := m.Called(jobID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookDeliveryList), args.Error(1)
+}
+
 func TestTranscribeHandler_PostTranscribe(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -95,6 +138,82 @@ func TestTranscribeHandler_PostTranscribe(t *testing.T) {
 			mockSetup:      func(m *MockJobService) {},
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name: "webhook_url rejected",
+			setupRequest: func() (*bytes.Buffer, string) {
+				body := &bytes.Buffer{}
+				writer := multipart.NewWriter(body)
+				part, _ := writer.CreateFormFile("audio", "test.mp3")
+				part.Write([]byte("fake audio data"))
+				writer.WriteField("webhook_url", "http://127.0.0.1/hook")
+				writer.Close()
+				return body, writer.FormDataContentType()
+			},
+			mockSetup: func(m *MockJobService) {
+				m.On("CreateTranscriptionJob", (*string)(nil), mock.AnythingOfType("*multipart.FileHeader"), mock.AnythingOfType("*string")).Return(nil, errors.New("invalid webhook_url: webhook target address is not allowed"))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "unsupported audio",
+			setupRequest: func() (*bytes.Buffer, string) {
+				body := &bytes.Buffer{}
+				writer := multipart.NewWriter(body)
+				part, _ := writer.CreateFormFile("audio", "test.mp3")
+				part.Write([]byte("fake audio data"))
+				writer.Close()
+				return body, writer.FormDataContentType()
+			},
+			mockSetup: func(m *MockJobService) {
+				m.On("CreateTranscriptionJob", (*string)(nil), mock.AnythingOfType("*multipart.FileHeader"), (*string)(nil)).Return(nil, errors.New("unsupported audio: expected WAV, FLAC, Ogg (Opus or Vorbis) or MP3"))
+			},
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "corrupt audio",
+			setupRequest: func() (*bytes.Buffer, string) {
+				body := &bytes.Buffer{}
+				writer := multipart.NewWriter(body)
+				part, _ := writer.CreateFormFile("audio", "test.mp3")
+				part.Write([]byte("fake audio data"))
+				writer.Close()
+				return body, writer.FormDataContentType()
+			},
+			mockSetup: func(m *MockJobService) {
+				m.On("CreateTranscriptionJob", (*string)(nil), mock.AnythingOfType("*multipart.FileHeader"), (*string)(nil)).Return(nil, errors.New("unsupported audio: corrupt audio: Ogg page checksum mismatch"))
+			},
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "audio too large",
+			setupRequest: func() (*bytes.Buffer, string) {
+				body := &bytes.Buffer{}
+				writer := multipart.NewWriter(body)
+				part, _ := writer.CreateFormFile("audio", "test.mp3")
+				part.Write([]byte("fake audio data"))
+				writer.Close()
+				return body, writer.FormDataContentType()
+			},
+			mockSetup: func(m *MockJobService) {
+				m.On("CreateTranscriptionJob", (*string)(nil), mock.AnythingOfType("*multipart.FileHeader"), (*string)(nil)).Return(nil, errors.New("audio is too large"))
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "audio too long",
+			setupRequest: func() (*bytes.Buffer, string) {
+				body := &bytes.Buffer{}
+				writer := multipart.NewWriter(body)
+				part, _ := writer.CreateFormFile("audio", "test.mp3")
+				part.Write([]byte("fake audio data"))
+				writer.Close()
+				return body, writer.FormDataContentType()
+			},
+			mockSetup: func(m *MockJobService) {
+				m.On("CreateTranscriptionJob", (*string)(nil), mock.AnythingOfType("*multipart.FileHeader"), (*string)(nil)).Return(nil, errors.New("audio is too long"))
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
 		{
 			name: "service error",
 			setupRequest: func() (*bytes.Buffer, string) {
@@ -134,6 +253,14 @@ func TestTranscribeHandler_PostTranscribe(t *testing.T) {
 	}
 }
 
+func (m *MockJobService) GetTranscript(jobID string) (*models.Transcript, error) {
+	args := m.Called(jobID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Transcript), args.Error(1)
+}
+
 func TestTranscribeHandler_GetJob(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -212,3 +339,433 @@ func TestTranscribeHandler_GetJob(t *testing.T) {
 		})
 	}
 }
+
+func TestTranscribeHandler_DeleteJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		jobID          string
+		mockSetup      func(*MockJobService)
+		expectedStatus int
+	}{
+		{
+			name:  "successful deletion",
+			jobID: "j-123",
+			mockSetup: func(m *MockJobService) {
+				m.On("DeleteJob", "j-123").Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:  "job not found",
+			jobID: "j-999",
+			mockSetup: func(m *MockJobService) {
+				m.On("DeleteJob", "j-999").Return(errors.New("job not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:  "job is running",
+			jobID: "j-123",
+			mockSetup: func(m *MockJobService) {
+				m.On("DeleteJob", "j-123").Return(errors.New("job is running"))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockJobService)
+			tt.mockSetup(mockService)
+
+			router := gin.New()
+			handler := NewTranscribeHandler(mockService)
+			router.DELETE("/jobs/:job_id", handler.DeleteJob)
+
+			req, _ := http.NewRequest(http.MethodDelete, "/jobs/"+tt.jobID, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTranscribeHandler_ListDeliveries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	statusCode := http.StatusOK
+	tests := []struct {
+		name           string
+		jobID          string
+		mockSetup      func(*MockJobService)
+		expectedStatus int
+	}{
+		{
+			name:  "successful listing",
+			jobID: "j-123",
+			mockSetup: func(m *MockJobService) {
+				m.On("ListDeliveries", "j-123").Return(&models.WebhookDeliveryList{
+					Items: []models.WebhookDelivery{{
+						DeliveryID:     "d-123",
+						Event:          "job.succeeded",
+						URL:            "https://example.com/hook",
+						Status:         models.WebhookDeliverySucceeded,
+						Attempts:       1,
+						LastStatusCode: &statusCode,
+						CreatedAt:      time.Now(),
+					}},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "job not found",
+			jobID: "j-999",
+			mockSetup: func(m *MockJobService) {
+				m.On("ListDeliveries", "j-999").Return(nil, errors.New("job not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:  "service error",
+			jobID: "j-123",
+			mockSetup: func(m *MockJobService) {
+				m.On("ListDeliveries", "j-123").Return(nil, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockJobService)
+			tt.mockSetup(mockService)
+
+			router := gin.New()
+			handler := NewTranscribeHandler(mockService)
+			router.GET("/jobs/:job_id/deliveries", handler.ListDeliveries)
+
+			req, _ := http.NewRequest(http.MethodGet, "/jobs/"+tt.jobID+"/deliveries", nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTranscribeHandler_ListJobs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		query          string
+		mockSetup      func(*MockJobService)
+		expectedStatus int
+	}{
+		{
+			name:  "without filters",
+			query: "",
+			mockSetup: func(m *MockJobService) {
+				m.On("ListJobs", &models.JobFilter{}, 20, (*string)(nil)).Return(&models.JobList{
+					Items: []models.Job{{JobID: "j-123", Status: models.JobStatusQueued, CreatedAt: time.Now()}},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "with filters and cursor",
+			query: "?status=failed&person_id=p-123&created_after=2025-01-01T00:00:00Z&created_before=2025-02-01T00:00:00%2B09:00&limit=5&cursor=abc",
+			mockSetup: func(m *MockJobService) {
+				m.On("ListJobs", mock.MatchedBy(func(f *models.JobFilter) bool {
+					return *f.Status == models.JobStatusFailed && *f.PersonID == "p-123" &&
+						f.CreatedAfter.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) &&
+						f.CreatedBefore.Equal(time.Date(2025, 1, 31, 15, 0, 0, 0, time.UTC))
+				}), 5, mock.AnythingOfType("*string")).Return(&models.JobList{Items: []models.Job{}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid status",
+			query:          "?status=done",
+			mockSetup:      func(m *MockJobService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid time",
+			query:          "?created_after=yesterday",
+			mockSetup:      func(m *MockJobService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid limit",
+			query:          "?limit=0",
+			mockSetup:      func(m *MockJobService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "invalid cursor",
+			query: "?cursor=!!!",
+			mockSetup: func(m *MockJobService) {
+				m.On("ListJobs", &models.JobFilter{}, 20, mock.AnythingOfType("*string")).Return(nil, errors.New("invalid cursor: illegal base64 data"))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockJobService)
+			tt.mockSetup(mockService)
+
+			router := gin.New()
+			handler := NewTranscribeHandler(mockService)
+			router.GET("/jobs", handler.ListJobs)
+
+			req, _ := http.NewRequest(http.MethodGet, "/jobs"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTranscribeHandler_CancelAndRetryJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		path           string
+		mockSetup      func(*MockJobService)
+		expectedStatus int
+	}{
+		{
+			name: "cancel queued job",
+			path: "/jobs/j-123/cancel",
+			mockSetup: func(m *MockJobService) {
+				m.On("CancelJob", "j-123").Return(&models.Job{JobID: "j-123", Status: models.JobStatusCancelled}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "cancel finished job",
+			path: "/jobs/j-123/cancel",
+			mockSetup: func(m *MockJobService) {
+				m.On("CancelJob", "j-123").Return(nil, errors.New("job cannot be cancelled"))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "cancel unknown job",
+			path: "/jobs/j-999/cancel",
+			mockSetup: func(m *MockJobService) {
+				m.On("CancelJob", "j-999").Return(nil, errors.New("job not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "retry failed job",
+			path: "/jobs/j-123/retry",
+			mockSetup: func(m *MockJobService) {
+				m.On("RetryJob", "j-123").Return(&models.Job{JobID: "j-123", Status: models.JobStatusQueued}, nil)
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name: "retry succeeded job",
+			path: "/jobs/j-123/retry",
+			mockSetup: func(m *MockJobService) {
+				m.On("RetryJob", "j-123").Return(nil, errors.New("job cannot be retried"))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "retry job without audio",
+			path: "/jobs/j-123/retry",
+			mockSetup: func(m *MockJobService) {
+				m.On("RetryJob", "j-123").Return(nil, errors.New("job audio is no longer available"))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockJobService)
+			tt.mockSetup(mockService)
+
+			router := gin.New()
+			handler := NewTranscribeHandler(mockService)
+			router.POST("/jobs/:job_id/cancel", handler.CancelJob)
+			router.POST("/jobs/:job_id/retry", handler.RetryJob)
+
+			req, _ := http.NewRequest(http.MethodPost, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTranscribeHandler_RetryJob_OnlyFailedJobs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.NewDB(&database.Config{Driver: "sqlite", DBName: "file:retry_job?mode=memory&cache=shared"})
+	require.NoError(t, err)
+	require.NoError(t, database.AutoMigrate(db))
+	jobRepo := repository.NewJobRepository(db)
+	jobService := service.NewJobService(jobRepo, repository.NewTranscriptRepository(db), nil, nil, nil,
+		service.NewJobEventService(jobRepo, events.NewBroker(events.DefaultConfig())), &service.AudioConfig{})
+
+	audioKey := "audio/abc.ogg"
+	leaseOwner := "worker-1"
+	now := time.Now()
+	for _, job := range []*repository.JobEntity{
+		{JobID: "j-running", Status: repository.JobStatusRunning, AudioKey: &audioKey, Attempts: 1, StartedAt: &now, LeaseOwner: &leaseOwner},
+		{JobID: "j-failed", Status: repository.JobStatusFailed, AudioKey: &audioKey, Attempts: 3},
+		{JobID: "j-queued", Status: repository.JobStatusQueued, AudioKey: &audioKey},
+	} {
+		job.CreatedAt = now
+		require.NoError(t, jobRepo.Create(job))
+	}
+
+	router := gin.New()
+	router.POST("/jobs/:job_id/retry", NewTranscribeHandler(jobService).RetryJob)
+	retry := func(jobID string) int {
+		req, _ := http.NewRequest(http.MethodPost, "/jobs/"+jobID+"/retry", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusConflict, retry("j-running"))
+	running, err := jobRepo.FindByID("j-running")
+	require.NoError(t, err)
+	assert.Equal(t, repository.JobStatusRunning, running.Status)
+	assert.Equal(t, 1, running.Attempts)
+	assert.Equal(t, &leaseOwner, running.LeaseOwner)
+
+	assert.Equal(t, http.StatusConflict, retry("j-queued"))
+	assert.Equal(t, http.StatusAccepted, retry("j-failed"))
+	assert.Equal(t, http.StatusConflict, retry("j-failed"))
+	assert.Equal(t, http.StatusNotFound, retry("j-missing"))
+}
+
+func TestTranscribeHandler_GetTranscript(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	transcript := &models.Transcript{
+		JobID:       "j-1",
+		Language:    "ja",
+		DurationSec: 3725.5,
+		Speakers:    []string{"speaker_1", "speaker_2"},
+		Segments: []models.TranscriptSegment{
+			{Speaker: "speaker_1", Start: 0, End: 1.25, Text: "Hi <there>"},
+			{Speaker: "speaker_2", Start: 3723.0004, End: 3725.5, Text: "Hello\n\nagain"},
+			{Start: 3725.5, End: 3725.5, Text: "..."},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		mockSetup      func(*MockJobService)
+		expectedStatus int
+		expectedType   string
+		expectedBody   string
+	}{
+		{
+			name:  "json by default",
+			query: "",
+			mockSetup: func(m *MockJobService) {
+				m.On("GetTranscript", "j-1").Return(transcript, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedType:   "application/json; charset=utf-8",
+		},
+		{
+			name:  "srt",
+			query: "?format=srt",
+			mockSetup: func(m *MockJobService) {
+				m.On("GetTranscript", "j-1").Return(transcript, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedType:   "application/x-subrip; charset=utf-8",
+			expectedBody: "1\n00:00:00,000 --> 00:00:01,250\nspeaker_1: Hi <there>\n\n" +
+				"2\n01:02:03,000 --> 01:02:05,500\nspeaker_2: Hello again\n\n" +
+				"3\n01:02:05,500 --> 01:02:05,500\n...\n\n",
+		},
+		{
+			name:  "vtt",
+			query: "?format=vtt",
+			mockSetup: func(m *MockJobService) {
+				m.On("GetTranscript", "j-1").Return(transcript, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedType:   "text/vtt; charset=utf-8",
+			expectedBody: "WEBVTT\n\n00:00:00.000 --> 00:00:01.250\n<v speaker_1>Hi &lt;there&gt;\n" +
+				"\n01:02:03.000 --> 01:02:05.500\n<v speaker_2>Hello again\n" +
+				"\n01:02:05.500 --> 01:02:05.500\n...\n",
+		},
+		{
+			name:           "unknown format",
+			query:          "?format=docx",
+			mockSetup:      func(m *MockJobService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "job not found",
+			query: "?format=srt",
+			mockSetup: func(m *MockJobService) {
+				m.On("GetTranscript", "j-1").Return(nil, errors.New("job not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:  "job not finished",
+			query: "",
+			mockSetup: func(m *MockJobService) {
+				m.On("GetTranscript", "j-1").Return(nil, errors.New("transcript is not available"))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockJobService)
+			tt.mockSetup(mockService)
+
+			router := gin.New()
+			handler := NewTranscribeHandler(mockService)
+			router.GET("/jobs/:job_id/transcript", handler.GetTranscript)
+
+			req, _ := http.NewRequest(http.MethodGet, "/jobs/j-1/transcript"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedType != "" {
+				assert.Equal(t, tt.expectedType, w.Header().Get("Content-Type"))
+			}
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
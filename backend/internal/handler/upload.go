@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jphacks/os_2522/backend/internal/errors"
+	"github.com/jphacks/os_2522/backend/internal/models"
+)
+
+// uploadOffsetHeader carries the byte offset of a chunk, as in the tus protocol
+const uploadOffsetHeader = "Upload-Offset"
+
+// UploadHandler handles resumable audio uploads
+type UploadHandler struct {
+	uploadService UploadServiceInterface
+}
+
+// NewUploadHandler creates a new UploadHandler
+func NewUploadHandler(uploadService UploadServiceInterface) *UploadHandler {
+	return &UploadHandler{uploadService: uploadService}
+}
+
+// CreateUpload handles POST /uploads
+func (h *UploadHandler) CreateUpload(c *gin.Context) {
+	var req models.UploadCreate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.HandleValidationErrors(c, err)
+		return
+	}
+
+	upload, err := h.uploadService.CreateUpload(c.Request.Context(), &req)
+	if err != nil {
+		respondWithUploadError(c, err)
+		return
+	}
+
+	c.Header("Location", "/v1/uploads/"+upload.UploadID)
+	c.Header(uploadOffsetHeader, strconv.FormatInt(upload.Offset, 10))
+	c.JSON(http.StatusCreated, upload)
+}
+
+// GetUpload handles GET /uploads/{upload_id}
+func (h *UploadHandler) GetUpload(c *gin.Context) {
+	upload, err := h.uploadService.GetUpload(c.Param("upload_id"))
+	if err != nil {
+		respondWithUploadError(c, err)
+		return
+	}
+
+	c.Header(uploadOffsetHeader, strconv.FormatInt(upload.Offset, 10))
+	c.JSON(http.StatusOK, upload)
+}
+
+// AppendChunk handles PATCH /uploads/{upload_id}
+func (h *UploadHandler) AppendChunk(c *gin.Context) {
+	offset, err := strconv.ParseInt(c.GetHeader(uploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		errors.RespondWithError(c, errors.BadRequest("Upload-Offset header must be a non-negative integer"))
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType != "application/offset+octet-stream" && mediaType != "application/octet-stream" {
+		errors.RespondWithError(c, errors.UnsupportedMediaType("Chunks must be sent as application/offset+octet-stream"))
+		return
+	}
+
+	upload, err := h.uploadService.AppendChunk(c.Request.Context(), c.Param("upload_id"), offset, c.Request.Body)
+	if err != nil {
+		respondWithUploadError(c, err)
+		return
+	}
+
+	c.Header(uploadOffsetHeader, strconv.FormatInt(upload.Offset, 10))
+	c.JSON(http.StatusOK, upload)
+}
+
+// CompleteUpload handles POST /uploads/{upload_id}/complete
+func (h *UploadHandler) CompleteUpload(c *gin.Context) {
+	job, err := h.uploadService.CompleteUpload(c.Request.Context(), c.Param("upload_id"))
+	if err != nil {
+		respondWithUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// DeleteUpload handles DELETE /uploads/{upload_id}
+func (h *UploadHandler) DeleteUpload(c *gin.Context) {
+	if err := h.uploadService.DeleteUpload(c.Request.Context(), c.Param("upload_id")); err != nil {
+		respondWithUploadError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondWithUploadError maps UploadService errors to Problem responses
+func respondWithUploadError(c *gin.Context, err error) {
+	switch msg := err.Error(); {
+	case msg == "upload not found":
+		errors.RespondWithError(c, errors.NotFound("Upload not found"))
+	case msg == "person not found":
+		errors.RespondWithError(c, errors.NotFound("Person not found"))
+	case msg == "audio is too large", msg == "audio is too long", msg == "chunk is too large":
+		errors.RespondWithError(c, errors.PayloadTooLarge(msg))
+	case strings.HasPrefix(msg, "unsupported audio"):
+		errors.RespondWithError(c, errors.UnsupportedMediaType(msg))
+	case msg == "invalid checksum":
+		errors.RespondWithError(c, errors.BadRequest("checksum must be sha256:<64 lowercase hex digits>"))
+	case msg == "chunk exceeds upload length", strings.HasPrefix(msg, "invalid webhook_url"):
+		errors.RespondWithError(c, errors.BadRequest(msg))
+	case strings.HasPrefix(msg, "upload offset mismatch"),
+		msg == "upload is not in progress", msg == "upload is being completed", msg == "upload is incomplete":
+		errors.RespondWithError(c, errors.Conflict(msg))
+	case msg == "checksum mismatch":
+		errors.RespondWithError(c, errors.UnprocessableEntity("Assembled audio does not match checksum; the upload was discarded"))
+	default:
+		errors.RespondWithError(c, errors.InternalServerError(msg))
+	}
+}
@@ -0,0 +1,493 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jphacks/os_2522/backend/internal/database"
+	"github.com/jphacks/os_2522/backend/internal/events"
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/service"
+	"github.com/jphacks/os_2522/backend/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockUploadService is a mock implementation of UploadService
+type MockUploadService struct {
+	mock.Mock
+}
+
+func (m *MockUploadService) CreateUpload(ctx context.Context, req *models.UploadCreate) (*models.Upload, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Upload), args.Error(1)
+}
+
+func (m *MockUploadService) GetUpload(uploadID string) (*models.Upload, error) {
+	args := m.Called(uploadID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Upload), args.Error(1)
+}
+
+func (m *MockUploadService) AppendChunk(ctx context.Context, uploadID string, offset int64, r io.Reader) (*models.Upload, error) {
+	data, _ := io.ReadAll(r)
+	args := m.Called(uploadID, offset, string(data))
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Upload), args.Error(1)
+}
+
+func (m *MockUploadService) CompleteUpload(ctx context.Context, uploadID string) (*models.Job, error) {
+	args := m.Called(uploadID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Job), args.Error(1)
+}
+
+func (m *MockUploadService) DeleteUpload(ctx context.Context, uploadID string) error {
+	args := m.Called(uploadID)
+	return args.Error(0)
+}
+
+func newUploadRouter(service *MockUploadService) *gin.Engine {
+	router := gin.New()
+	handler := NewUploadHandler(service)
+	router.POST("/uploads", handler.CreateUpload)
+	router.GET("/uploads/:upload_id", handler.GetUpload)
+	router.PATCH("/uploads/:upload_id", handler.AppendChunk)
+	router.POST("/uploads/:upload_id/complete", handler.CompleteUpload)
+	router.DELETE("/uploads/:upload_id", handler.DeleteUpload)
+	return router
+}
+
+func TestUploadHandler_CreateUpload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		mockSetup      func(*MockUploadService)
+		expectedStatus int
+	}{
+		{
+			name: "created",
+			body: `{"length": 1048576, "person_id": "p-123"}`,
+			mockSetup: func(m *MockUploadService) {
+				m.On("CreateUpload", mock.MatchedBy(func(req *models.UploadCreate) bool {
+					return req.Length == 1048576 && *req.PersonID == "p-123"
+				})).Return(&models.Upload{UploadID: "u-1", Status: models.UploadStatusUploading, Length: 1048576}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "missing length",
+			body:           `{}`,
+			mockSetup:      func(m *MockUploadService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "too large",
+			body: `{"length": 99999999999}`,
+			mockSetup: func(m *MockUploadService) {
+				m.On("CreateUpload", mock.Anything).Return(nil, errors.New("audio is too large"))
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "invalid checksum",
+			body: `{"length": 10, "checksum": "md5:abc"}`,
+			mockSetup: func(m *MockUploadService) {
+				m.On("CreateUpload", mock.Anything).Return(nil, errors.New("invalid checksum"))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "unknown person",
+			body: `{"length": 10, "person_id": "p-999"}`,
+			mockSetup: func(m *MockUploadService) {
+				m.On("CreateUpload", mock.Anything).Return(nil, errors.New("person not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "invalid webhook",
+			body: `{"length": 10, "webhook_url": "http://127.0.0.1/"}`,
+			mockSetup: func(m *MockUploadService) {
+				m.On("CreateUpload", mock.Anything).Return(nil, errors.New("invalid webhook_url: webhook target address is not allowed"))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockUploadService)
+			tt.mockSetup(mockService)
+
+			req, _ := http.NewRequest(http.MethodPost, "/uploads", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			newUploadRouter(mockService).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusCreated {
+				assert.Equal(t, "/v1/uploads/u-1", w.Header().Get("Location"))
+				assert.Equal(t, "0", w.Header().Get("Upload-Offset"))
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUploadHandler_AppendChunk(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		offset         string
+		contentType    string
+		mockSetup      func(*MockUploadService)
+		expectedStatus int
+		expectedOffset string
+	}{
+		{
+			name:        "chunk stored",
+			offset:      "4",
+			contentType: "application/offset+octet-stream",
+			mockSetup: func(m *MockUploadService) {
+				m.On("AppendChunk", "u-1", int64(4), "chunk").Return(&models.Upload{UploadID: "u-1", Offset: 9, Length: 20}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedOffset: "9",
+		},
+		{
+			name:        "plain octet stream",
+			offset:      "0",
+			contentType: "application/octet-stream",
+			mockSetup: func(m *MockUploadService) {
+				m.On("AppendChunk", "u-1", int64(0), "chunk").Return(&models.Upload{UploadID: "u-1", Offset: 5, Length: 20}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedOffset: "5",
+		},
+		{
+			name:           "missing offset",
+			contentType:    "application/offset+octet-stream",
+			mockSetup:      func(m *MockUploadService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "negative offset",
+			offset:         "-1",
+			contentType:    "application/offset+octet-stream",
+			mockSetup:      func(m *MockUploadService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "wrong content type",
+			offset:         "0",
+			contentType:    "multipart/form-data",
+			mockSetup:      func(m *MockUploadService) {},
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "offset mismatch",
+			offset:      "0",
+			contentType: "application/offset+octet-stream",
+			mockSetup: func(m *MockUploadService) {
+				m.On("AppendChunk", "u-1", int64(0), "chunk").Return(nil, errors.New("upload offset mismatch: expected 4"))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:        "chunk too large",
+			offset:      "0",
+			contentType: "application/offset+octet-stream",
+			mockSetup: func(m *MockUploadService) {
+				m.On("AppendChunk", "u-1", int64(0), "chunk").Return(nil, errors.New("chunk is too large"))
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "chunk beyond length",
+			offset:      "18",
+			contentType: "application/offset+octet-stream",
+			mockSetup: func(m *MockUploadService) {
+				m.On("AppendChunk", "u-1", int64(18), "chunk").Return(nil, errors.New("chunk exceeds upload length"))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "upload completed",
+			offset:      "20",
+			contentType: "application/offset+octet-stream",
+			mockSetup: func(m *MockUploadService) {
+				m.On("AppendChunk", "u-1", int64(20), "chunk").Return(nil, errors.New("upload is not in progress"))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:        "upload expired",
+			offset:      "0",
+			contentType: "application/offset+octet-stream",
+			mockSetup: func(m *MockUploadService) {
+				m.On("AppendChunk", "u-1", int64(0), "chunk").Return(nil, errors.New("upload not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockUploadService)
+			tt.mockSetup(mockService)
+
+			req, _ := http.NewRequest(http.MethodPatch, "/uploads/u-1", bytes.NewReader([]byte("chunk")))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.offset != "" {
+				req.Header.Set("Upload-Offset", tt.offset)
+			}
+			w := httptest.NewRecorder()
+			newUploadRouter(mockService).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedOffset, w.Header().Get("Upload-Offset"))
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUploadHandler_GetCompleteAndDeleteUpload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		mockSetup      func(*MockUploadService)
+		expectedStatus int
+	}{
+		{
+			name:   "get upload",
+			method: http.MethodGet,
+			path:   "/uploads/u-1",
+			mockSetup: func(m *MockUploadService) {
+				m.On("GetUpload", "u-1").Return(&models.Upload{UploadID: "u-1", Offset: 3}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "get unknown upload",
+			method: http.MethodGet,
+			path:   "/uploads/u-9",
+			mockSetup: func(m *MockUploadService) {
+				m.On("GetUpload", "u-9").Return(nil, errors.New("upload not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "complete upload",
+			method: http.MethodPost,
+			path:   "/uploads/u-1/complete",
+			mockSetup: func(m *MockUploadService) {
+				m.On("CompleteUpload", "u-1").Return(&models.Job{JobID: "j-1", Status: models.JobStatusQueued}, nil)
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:   "complete incomplete upload",
+			method: http.MethodPost,
+			path:   "/uploads/u-1/complete",
+			mockSetup: func(m *MockUploadService) {
+				m.On("CompleteUpload", "u-1").Return(nil, errors.New("upload is incomplete"))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:   "complete with checksum mismatch",
+			method: http.MethodPost,
+			path:   "/uploads/u-1/complete",
+			mockSetup: func(m *MockUploadService) {
+				m.On("CompleteUpload", "u-1").Return(nil, errors.New("checksum mismatch"))
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "complete with unsupported audio",
+			method: http.MethodPost,
+			path:   "/uploads/u-1/complete",
+			mockSetup: func(m *MockUploadService) {
+				m.On("CompleteUpload", "u-1").Return(nil, errors.New("unsupported audio: corrupt audio: WAV data chunk is truncated"))
+			},
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:   "complete with too long audio",
+			method: http.MethodPost,
+			path:   "/uploads/u-1/complete",
+			mockSetup: func(m *MockUploadService) {
+				m.On("CompleteUpload", "u-1").Return(nil, errors.New("audio is too long"))
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "complete fails in storage",
+			method: http.MethodPost,
+			path:   "/uploads/u-1/complete",
+			mockSetup: func(m *MockUploadService) {
+				m.On("CompleteUpload", "u-1").Return(nil, errors.New("failed to assemble upload: disk full"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:   "delete upload",
+			method: http.MethodDelete,
+			path:   "/uploads/u-1",
+			mockSetup: func(m *MockUploadService) {
+				m.On("DeleteUpload", "u-1").Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:   "delete upload being completed",
+			method: http.MethodDelete,
+			path:   "/uploads/u-1",
+			mockSetup: func(m *MockUploadService) {
+				m.On("DeleteUpload", "u-1").Return(errors.New("upload is being completed"))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockUploadService)
+			tt.mockSetup(mockService)
+
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+			newUploadRouter(mockService).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// silentWAV returns one second of 8 kHz mono silence
+func silentWAV() []byte {
+	const sampleRate, dataSize = 8000, 16000
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+dataSize))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, []uint32{16})
+	binary.Write(&b, binary.LittleEndian, []uint16{1, 1})
+	binary.Write(&b, binary.LittleEndian, []uint32{sampleRate, sampleRate * 2})
+	binary.Write(&b, binary.LittleEndian, []uint16{2, 16})
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(dataSize))
+	b.Write(make([]byte, dataSize))
+	return b.Bytes()
+}
+
+func TestUploadHandler_RecoversStalledCompletion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.NewDB(&database.Config{Driver: "sqlite", DBName: "file:stalled_upload?mode=memory&cache=shared"})
+	require.NoError(t, err)
+	require.NoError(t, database.AutoMigrate(db))
+	store, err := storage.NewLocalStore(storage.LocalConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+	jobRepo := repository.NewJobRepository(db)
+	uploadRepo := repository.NewUploadRepository(db)
+	jobService := service.NewJobService(jobRepo, repository.NewTranscriptRepository(db), store, nil, nil,
+		service.NewJobEventService(jobRepo, events.NewBroker(events.DefaultConfig())),
+		&service.AudioConfig{MaxSize: 1 << 20, MaxDuration: time.Minute})
+	uploadService := service.NewUploadService(uploadRepo, repository.NewPersonRepository(db), store, jobService,
+		&service.UploadConfig{MaxChunkSize: 1 << 20, Expiry: time.Hour, AssembleLease: 10 * time.Minute})
+
+	audio := silentWAV()
+	chunk, err := store.Put(context.Background(), bytes.NewReader(audio))
+	require.NoError(t, err)
+	now := time.Now()
+	createUpload := func(uploadID string, updatedAt time.Time, jobID *string) {
+		require.NoError(t, uploadRepo.Create(&repository.UploadEntity{
+			UploadID: uploadID, Length: int64(len(audio)), Received: int64(len(audio)),
+			Status: repository.UploadStatusAssembling, JobID: jobID,
+			CreatedAt: updatedAt, UpdatedAt: updatedAt, ExpiresAt: now.Add(time.Hour),
+		}))
+		require.NoError(t, db.Create(&repository.UploadChunkEntity{
+			UploadID: uploadID, Size: int64(len(audio)), ChunkKey: chunk.Key,
+		}).Error)
+	}
+
+	// Queued, but marking the upload completed failed
+	jobID := "j-queued"
+	require.NoError(t, jobRepo.Create(&repository.JobEntity{JobID: jobID, Status: repository.JobStatusQueued, AudioKey: &chunk.Key, CreatedAt: now}))
+	createUpload("u-queued", now, &jobID)
+	// Completion crashed before the job was queued
+	createUpload("u-stale", now.Add(-time.Hour), nil)
+	createUpload("u-stale-deleted", now.Add(-time.Hour), nil)
+	// Completion still in progress
+	createUpload("u-busy", now, nil)
+
+	router := gin.New()
+	handler := NewUploadHandler(uploadService)
+	router.GET("/uploads/:upload_id", handler.GetUpload)
+	router.POST("/uploads/:upload_id/complete", handler.CompleteUpload)
+	router.DELETE("/uploads/:upload_id", handler.DeleteUpload)
+	do := func(method, path string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var body map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body
+	}
+
+	code, body := do(http.MethodGet, "/uploads/u-queued")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "completed", body["status"])
+	code, body = do(http.MethodPost, "/uploads/u-queued/complete")
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, jobID, body["job_id"])
+	queued, err := uploadRepo.FindByID("u-queued")
+	require.NoError(t, err)
+	assert.Equal(t, repository.UploadStatusCompleted, queued.Status)
+
+	code, body = do(http.MethodPost, "/uploads/u-stale/complete")
+	assert.Equal(t, http.StatusAccepted, code)
+	stale, err := uploadRepo.FindByID("u-stale")
+	require.NoError(t, err)
+	assert.Equal(t, repository.UploadStatusCompleted, stale.Status)
+	require.NotNil(t, stale.JobID)
+	assert.Equal(t, *stale.JobID, body["job_id"])
+
+	code, _ = do(http.MethodDelete, "/uploads/u-stale-deleted")
+	assert.Equal(t, http.StatusNoContent, code)
+
+	code, _ = do(http.MethodPost, "/uploads/u-busy/complete")
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = do(http.MethodDelete, "/uploads/u-busy")
+	assert.Equal(t, http.StatusConflict, code)
+}
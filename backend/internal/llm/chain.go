@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Chain tries its providers in order and returns the first response. A
+// provider that errors is skipped for this request only, so the primary
+// provider is tried again first on the next one.
+type Chain struct {
+	providers []Provider
+}
+
+// NewChain creates a Chain. The first provider is the primary one.
+func NewChain(providers ...Provider) *Chain {
+	return &Chain{providers: providers}
+}
+
+// Name returns the provider names in order, e.g. "gemini>openai"
+func (c *Chain) Name() string {
+	names := make([]string, 0, len(c.providers))
+	for _, p := range c.providers {
+		names = append(names, p.Name())
+	}
+	return strings.Join(names, ">")
+}
+
+// Generate returns the response of the first provider that succeeds
+func (c *Chain) Generate(ctx context.Context, req Request) (*Response, error) {
+	var failures []string
+	for i, p := range c.providers {
+		resp, err := p.Generate(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			// The caller gave up; falling back would not help
+			return nil, ctx.Err()
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", p.Name(), err))
+		if i+1 < len(c.providers) {
+			log.Printf("Warning: LLM provider %s failed, falling back to %s: %v", p.Name(), c.providers[i+1].Name(), err)
+		}
+	}
+	return nil, fmt.Errorf("%w: all providers failed (%s)", ErrUnavailable, strings.Join(failures, "; "))
+}
+
+// Close closes every provider
+func (c *Chain) Close() {
+	for _, p := range c.providers {
+		p.Close()
+	}
+}
@@ -0,0 +1,146 @@
+package llm
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// EstimateTokens approximates how many tokens text takes up. Tokenizers
+// differ between models, so it errs on the high side: a token for every
+// character outside ASCII, which covers Japanese, and one per four ASCII
+// characters.
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return other + (ascii+3)/4
+}
+
+// SplitText splits text into chunks of at most maxTokens estimated tokens.
+// Chunks end at line breaks where possible, then at sentence ends, so a
+// transcript keeps one speaker turn per line. The last lines of a chunk, up
+// to overlapTokens, are repeated at the start of the next one so that
+// nothing said across the boundary loses its context. Text that fits is
+// returned as a single chunk.
+func SplitText(text string, maxTokens, overlapTokens int) []string {
+	if maxTokens <= 0 || EstimateTokens(text) <= maxTokens {
+		return []string{text}
+	}
+	// Leave room for a new piece after the overlap so every chunk makes progress
+	if overlapTokens > maxTokens/2 {
+		overlapTokens = maxTokens / 2
+	}
+	if overlapTokens < 0 {
+		overlapTokens = 0
+	}
+
+	var pieces []string
+	for _, line := range strings.SplitAfter(text, "\n") {
+		pieces = append(pieces, splitPiece(line, maxTokens-overlapTokens)...)
+	}
+
+	var chunks []string
+	var current []string
+	tokens := 0
+	flush := func() {
+		if chunk := strings.TrimSpace(strings.Join(current, "")); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+	}
+	for _, piece := range pieces {
+		pieceTokens := EstimateTokens(piece)
+		if tokens+pieceTokens > maxTokens && len(current) > 0 {
+			flush()
+			keep, kept := 0, 0
+			for i := len(current) - 1; i >= 0; i-- {
+				t := EstimateTokens(current[i])
+				if kept+t > overlapTokens {
+					break
+				}
+				kept += t
+				keep++
+			}
+			current = append([]string(nil), current[len(current)-keep:]...)
+			tokens = kept
+		}
+		current = append(current, piece)
+		tokens += pieceTokens
+	}
+	flush()
+	return chunks
+}
+
+// splitPiece splits a line longer than limit at sentence ends, and
+// sentences that are still too long at the last space or character that fits
+func splitPiece(line string, limit int) []string {
+	if EstimateTokens(line) <= limit {
+		return []string{line}
+	}
+
+	var pieces []string
+	for _, sentence := range splitSentences(line) {
+		if EstimateTokens(sentence) <= limit {
+			pieces = append(pieces, sentence)
+			continue
+		}
+		start, ascii, other := 0, 0, 0
+		for i, r := range sentence {
+			if r < utf8.RuneSelf {
+				ascii++
+			} else {
+				other++
+			}
+			if other+(ascii+3)/4 <= limit {
+				continue
+			}
+			// Cut after the last space so words stay whole
+			cut := i
+			if space := strings.LastIndexByte(sentence[start:i], ' '); space > 0 {
+				cut = start + space + 1
+			}
+			pieces = append(pieces, sentence[start:cut])
+			start = cut
+			ascii, other = 0, 0
+			for _, r := range sentence[start : i+utf8.RuneLen(r)] {
+				if r < utf8.RuneSelf {
+					ascii++
+				} else {
+					other++
+				}
+			}
+		}
+		pieces = append(pieces, sentence[start:])
+	}
+	return pieces
+}
+
+// splitSentences splits text after sentence-ending punctuation, keeping
+// the punctuation, closing brackets and following spaces with the sentence.
+// ASCII punctuation only ends a sentence when a space follows, so "3.5" stays whole.
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	ended, terminal := false, false
+	for i, r := range text {
+		switch {
+		case strings.ContainsRune("。！？．", r):
+			ended = true
+		case strings.ContainsRune(".!?", r):
+			terminal = true
+		case (ended || terminal) && r == ' ', ended && strings.ContainsRune("」』）)", r):
+			ended = true
+		default:
+			if ended {
+				sentences = append(sentences, text[start:i])
+				start = i
+			}
+			ended, terminal = false, false
+		}
+	}
+	return append(sentences, text[start:])
+}
@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// FakeConfig holds the settings of the deterministic fake
+type FakeConfig struct {
+	// Response is returned for every request when set
+	Response string
+}
+
+// FakeProvider returns predictable text without calling a model. It is meant
+// for tests, demos and developing the rest of the pipeline offline.
+type FakeProvider struct {
+	config FakeConfig
+}
+
+// NewFakeProvider creates a FakeProvider
+func NewFakeProvider(config FakeConfig) *FakeProvider {
+	return &FakeProvider{config: config}
+}
+
+// Name returns the provider name
+func (p *FakeProvider) Name() string {
+	return ProviderFake
+}
+
+// Generate returns the configured response, or else the start of the prompt.
+// When the request has a schema, the start of the prompt is wrapped in the
+// smallest JSON value matching it.
+func (p *FakeProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	text := p.config.Response
+	if text == "" {
+		text = "[fake] " + truncateRunes(strings.Join(strings.Fields(req.Prompt), " "), 100)
+		if req.Schema != nil {
+			encoded, err := json.Marshal(fakeValue(req.Schema, text))
+			if err != nil {
+				return nil, err
+			}
+			text = string(encoded)
+		}
+	}
+	return &Response{Text: text, Provider: ProviderFake, Model: ProviderFake}, nil
+}
+
+// fakeValue builds a value matching the schema: text for free-form strings,
+// the first option for enums, and null or empty for everything optional
+func fakeValue(s *Schema, text string) interface{} {
+	if s.Nullable {
+		return nil
+	}
+	switch s.Type {
+	case TypeObject:
+		object := make(map[string]interface{}, len(s.Required))
+		for _, name := range s.Required {
+			if property, ok := s.Properties[name]; ok {
+				object[name] = fakeValue(property, text)
+			}
+		}
+		return object
+	case TypeArray:
+		return []interface{}{}
+	case TypeString:
+		switch {
+		case len(s.Enum) > 0:
+			return s.Enum[0]
+		case s.Format == "date":
+			return "1970-01-01"
+		}
+		if runes := []rune(text); s.MaxLength > 0 && len(runes) > s.MaxLength {
+			return string(runes[:s.MaxLength])
+		}
+		return text
+	case TypeBoolean:
+		return false
+	default:
+		return 0
+	}
+}
+
+// Close does nothing
+func (p *FakeProvider) Close() {}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+// GeminiConfig holds the settings of the Google Gemini API
+type GeminiConfig struct {
+	APIKey string
+	Model  string
+}
+
+// DefaultGeminiConfig returns the default Gemini settings
+func DefaultGeminiConfig() GeminiConfig {
+	return GeminiConfig{
+		Model: "gemini-2.5-flash",
+	}
+}
+
+// GeminiProvider generates text with the Google Gemini API
+type GeminiProvider struct {
+	config GeminiConfig
+	params Params
+	client *genai.Client
+}
+
+// NewGeminiProvider creates a GeminiProvider
+func NewGeminiProvider(config GeminiConfig, params Params) (*GeminiProvider, error) {
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("GEMINI_API_KEY is not set")
+	}
+	if config.Model == "" {
+		config.Model = DefaultGeminiConfig().Model
+	}
+
+	client, err := genai.NewClient(context.Background(), option.WithAPIKey(config.APIKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+	}
+	return &GeminiProvider{
+		config: config,
+		params: params,
+		client: client,
+	}, nil
+}
+
+// Name returns the provider name
+func (p *GeminiProvider) Name() string {
+	return ProviderGemini
+}
+
+// Generate sends the request to Gemini
+func (p *GeminiProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	if p.params.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.params.Timeout)
+		defer cancel()
+	}
+
+	// Models are cheap handles; a fresh one per request keeps settings from leaking between calls
+	model := p.client.GenerativeModel(p.config.Model)
+	if req.System != "" {
+		model.SystemInstruction = genai.NewUserContent(genai.Text(req.System))
+	}
+	if p.params.Temperature != nil {
+		model.SetTemperature(float32(*p.params.Temperature))
+	}
+	if p.params.MaxTokens > 0 {
+		model.SetMaxOutputTokens(int32(p.params.MaxTokens))
+	}
+	if req.Schema != nil {
+		model.ResponseMIMEType = "application/json"
+		model.ResponseSchema = geminiSchema(req.Schema)
+	}
+
+	resp, err := model.GenerateContent(ctx, genai.Text(req.Prompt))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return nil, fmt.Errorf("%w: no candidates returned", ErrUnavailable)
+	}
+
+	var text strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if t, ok := part.(genai.Text); ok {
+			text.WriteString(string(t))
+		}
+	}
+	if strings.TrimSpace(text.String()) == "" {
+		return nil, fmt.Errorf("%w: empty response", ErrUnavailable)
+	}
+	return &Response{Text: text.String(), Provider: ProviderGemini, Model: p.config.Model}, nil
+}
+
+// Close closes the Gemini client
+func (p *GeminiProvider) Close() {
+	p.client.Close()
+}
+
+// geminiSchema converts a schema to Gemini's OpenAPI subset, which has no
+// length limits; those are left to validation
+func geminiSchema(s *Schema) *genai.Schema {
+	if s == nil {
+		return nil
+	}
+	out := &genai.Schema{
+		Description: s.Description,
+		Nullable:    s.Nullable,
+		Enum:        s.Enum,
+		Items:       geminiSchema(s.Items),
+		Required:    s.Required,
+	}
+	switch s.Type {
+	case TypeObject:
+		out.Type = genai.TypeObject
+	case TypeArray:
+		out.Type = genai.TypeArray
+	case TypeString:
+		out.Type = genai.TypeString
+	case TypeNumber:
+		out.Type = genai.TypeNumber
+	case TypeInteger:
+		out.Type = genai.TypeInteger
+	case TypeBoolean:
+		out.Type = genai.TypeBoolean
+	}
+	if len(s.Enum) > 0 {
+		out.Format = "enum"
+	}
+	if s.Format == "date" {
+		out.Description = strings.TrimSpace(out.Description + " (YYYY-MM-DD)")
+	}
+	if len(s.Properties) > 0 {
+		out.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, property := range s.Properties {
+			out.Properties[name] = geminiSchema(property)
+		}
+	}
+	return out
+}
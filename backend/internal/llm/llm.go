@@ -0,0 +1,151 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/utils"
+)
+
+// Provider names accepted by LLM_PROVIDER and LLM_FALLBACKS
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+	ProviderFake   = "fake"
+)
+
+// ErrUnavailable is returned when a provider cannot be reached, is overloaded
+// or returns an unusable response
+var ErrUnavailable = errors.New("language model unavailable")
+
+// Request is a single prompt sent to a language model
+type Request struct {
+	// System holds the instructions, sent as a system prompt where supported
+	System string
+	// Prompt is the user message, e.g. the text to summarize
+	Prompt string
+	// Schema asks for a JSON response matching it where the provider supports
+	// that. Callers still validate the response with ParseJSON.
+	Schema *Schema
+}
+
+// Response is the text generated for a Request
+type Response struct {
+	Text string
+	// Provider and Model identify what produced the text, which may be a
+	// fallback rather than the primary provider
+	Provider string
+	Model    string
+}
+
+// Provider generates text with a language model
+type Provider interface {
+	Generate(ctx context.Context, req Request) (*Response, error)
+	// Name returns the provider name for logging
+	Name() string
+	// Close releases connections held by the provider
+	Close()
+}
+
+// Params are the generation settings shared by all providers
+type Params struct {
+	// Temperature is left to the model's default when nil
+	Temperature *float64
+	// MaxTokens caps the length of the response; 0 leaves it to the model
+	MaxTokens int
+	// Timeout bounds a single request
+	Timeout time.Duration
+}
+
+// Config selects the primary provider, its fallbacks and their settings
+type Config struct {
+	Provider string
+	// Fallbacks are tried in order when the provider before them fails
+	Fallbacks []string
+	Params    Params
+	Gemini    GeminiConfig
+	OpenAI    OpenAIConfig
+	Fake      FakeConfig
+}
+
+// GetConfigFromEnv returns the language model settings from environment variables
+func GetConfigFromEnv() Config {
+	gemini := DefaultGeminiConfig()
+	openai := DefaultOpenAIConfig()
+
+	params := Params{
+		MaxTokens: utils.GetEnvInt("LLM_MAX_TOKENS", 0),
+		Timeout:   utils.GetEnvDuration("LLM_TIMEOUT", time.Minute),
+	}
+	if temperature := utils.GetEnvFloat("LLM_TEMPERATURE", -1); temperature >= 0 {
+		params.Temperature = &temperature
+	}
+
+	var fallbacks []string
+	for _, name := range strings.Split(utils.GetEnv("LLM_FALLBACKS", ""), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			fallbacks = append(fallbacks, name)
+		}
+	}
+
+	return Config{
+		Provider:  utils.GetEnv("LLM_PROVIDER", ProviderGemini),
+		Fallbacks: fallbacks,
+		Params:    params,
+		Gemini: GeminiConfig{
+			APIKey: utils.GetEnv("GEMINI_API_KEY", ""),
+			Model:  utils.GetEnv("LLM_GEMINI_MODEL", gemini.Model),
+		},
+		OpenAI: OpenAIConfig{
+			BaseURL: utils.GetEnv("LLM_OPENAI_BASE_URL", openai.BaseURL),
+			APIKey:  utils.GetEnv("LLM_OPENAI_API_KEY", ""),
+			Model:   utils.GetEnv("LLM_OPENAI_MODEL", openai.Model),
+		},
+		Fake: FakeConfig{
+			Response: utils.GetEnv("LLM_FAKE_RESPONSE", ""),
+		},
+	}
+}
+
+// New creates the provider selected by config.Provider, wrapped in a Chain
+// when fallbacks are configured
+func New(config Config) (Provider, error) {
+	names := append([]string{config.Provider}, config.Fallbacks...)
+	providers := make([]Provider, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		provider, err := newProvider(name, config)
+		if err != nil {
+			for _, p := range providers {
+				p.Close()
+			}
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+	return NewChain(providers...), nil
+}
+
+func newProvider(name string, config Config) (Provider, error) {
+	switch name {
+	case ProviderGemini, "":
+		return NewGeminiProvider(config.Gemini, config.Params)
+	case ProviderOpenAI:
+		return NewOpenAIProvider(config.OpenAI, config.Params)
+	case ProviderFake:
+		return NewFakeProvider(config.Fake), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q (expected %s, %s or %s)", name, ProviderGemini, ProviderOpenAI, ProviderFake)
+	}
+}
@@ -0,0 +1,301 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_SelectsProvider(t *testing.T) {
+	fake, err := New(Config{Provider: ProviderFake})
+	require.NoError(t, err)
+	assert.Equal(t, ProviderFake, fake.Name())
+
+	openai, err := New(Config{Provider: ProviderOpenAI, OpenAI: DefaultOpenAIConfig()})
+	require.NoError(t, err)
+	assert.Equal(t, ProviderOpenAI, openai.Name())
+
+	chain, err := New(Config{Provider: ProviderOpenAI, Fallbacks: []string{ProviderFake, ProviderOpenAI}, OpenAI: DefaultOpenAIConfig()})
+	require.NoError(t, err)
+	assert.Equal(t, "openai>fake", chain.Name())
+
+	_, err = New(Config{Provider: ProviderGemini})
+	assert.ErrorContains(t, err, "GEMINI_API_KEY")
+
+	_, err = New(Config{Provider: ProviderOpenAI, OpenAI: OpenAIConfig{BaseURL: "localhost:11434", Model: "llama3.1"}})
+	assert.Error(t, err)
+
+	_, err = New(Config{Provider: ProviderFake, Fallbacks: []string{"claude"}})
+	assert.ErrorContains(t, err, "unknown LLM provider")
+}
+
+func TestOpenAIProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var req chatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "llama3.1", req.Model)
+		require.NotNil(t, req.Temperature)
+		assert.Equal(t, 0.2, *req.Temperature)
+		assert.Equal(t, 256, req.MaxTokens)
+		require.Len(t, req.Messages, 2)
+		assert.Equal(t, chatMessage{Role: "system", Content: "Summarize."}, req.Messages[0])
+
+		switch req.Messages[1].Content {
+		case "hello":
+			_, _ = w.Write([]byte(`{"model":"llama3.1:8b","choices":[{"message":{"role":"assistant","content":"A greeting."},"finish_reason":"stop"}]}`))
+		case "empty":
+			_, _ = w.Write([]byte(`{"choices":[]}`))
+		default:
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":{"message":"Rate limit reached"}}`))
+		}
+	}))
+	defer server.Close()
+
+	temperature := 0.2
+	provider, err := NewOpenAIProvider(
+		OpenAIConfig{BaseURL: server.URL + "/v1/", APIKey: "secret", Model: "llama3.1"},
+		Params{Temperature: &temperature, MaxTokens: 256},
+	)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	resp, err := provider.Generate(ctx, Request{System: "Summarize.", Prompt: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, &Response{Text: "A greeting.", Provider: ProviderOpenAI, Model: "llama3.1:8b"}, resp)
+
+	_, err = provider.Generate(ctx, Request{System: "Summarize.", Prompt: "empty"})
+	assert.ErrorIs(t, err, ErrUnavailable)
+
+	_, err = provider.Generate(ctx, Request{System: "Summarize.", Prompt: "busy"})
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.ErrorContains(t, err, "Rate limit reached")
+}
+
+func TestOpenAIProvider_Schema(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		format, err := json.Marshal(req["response_format"])
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type":"json_schema","json_schema":{"name":"response","strict":true,"schema":{"type":"string","maxLength":10}}}`, string(format))
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"\"ok\""}}]}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAIProvider(OpenAIConfig{BaseURL: server.URL, Model: "llama3.1"}, Params{})
+	require.NoError(t, err)
+	resp, err := provider.Generate(context.Background(), Request{Prompt: "hi", Schema: &Schema{Type: TypeString, MaxLength: 10}})
+	require.NoError(t, err)
+	assert.Equal(t, `"ok"`, resp.Text)
+}
+
+func TestFakeProvider(t *testing.T) {
+	ctx := context.Background()
+	resp, err := NewFakeProvider(FakeConfig{}).Generate(ctx, Request{Prompt: "  line one\nline two "})
+	require.NoError(t, err)
+	assert.Equal(t, "[fake] line one line two", resp.Text)
+
+	resp, err = NewFakeProvider(FakeConfig{Response: "fixed"}).Generate(ctx, Request{Prompt: "anything"})
+	require.NoError(t, err)
+	assert.Equal(t, "fixed", resp.Text)
+
+	resp, err = NewFakeProvider(FakeConfig{}).Generate(ctx, Request{Prompt: "a long prompt", Schema: testSchema})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"headline":"[fake] a l","tags":[],"items":[]}`, resp.Text)
+	_, err = ParseJSON(resp.Text, testSchema)
+	assert.NoError(t, err)
+}
+
+var testSchema = &Schema{
+	Type:     TypeObject,
+	Required: []string{"headline", "tags", "items"},
+	Properties: map[string]*Schema{
+		"headline": {Type: TypeString, MaxLength: 10},
+		"tags":     {Type: TypeArray, MaxItems: 2, Items: &Schema{Type: TypeString}},
+		"items": {
+			Type: TypeArray,
+			Items: &Schema{
+				Type:     TypeObject,
+				Required: []string{"kind", "due"},
+				Properties: map[string]*Schema{
+					"kind": {Type: TypeString, Enum: []string{"a", "b"}},
+					"due":  {Type: TypeString, Format: "date", Nullable: true},
+				},
+			},
+		},
+	},
+}
+
+func TestSchema_MarshalJSON(t *testing.T) {
+	encoded, err := json.Marshal(testSchema.Properties["items"].Items)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"kind": {"type": "string", "enum": ["a", "b"]},
+			"due": {"type": ["string", "null"], "format": "date"}
+		},
+		"required": ["kind", "due"],
+		"additionalProperties": false
+	}`, string(encoded))
+}
+
+func TestParseJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		expectedErr string
+	}{
+		{name: "valid", text: `{"headline":"hi","tags":["x"],"items":[{"kind":"a","due":"2025-10-20"},{"kind":"b","due":null}]}`},
+		{name: "code fence", text: "```json\n{\"headline\":\"hi\",\"tags\":[],\"items\":[]}\n```"},
+		{name: "not JSON", text: "Sure! Here is the summary.", expectedErr: "not valid JSON"},
+		{name: "missing property", text: `{"headline":"hi","tags":[]}`, expectedErr: `$: missing property "items"`},
+		{name: "unexpected property", text: `{"headline":"hi","tags":[],"items":[],"extra":1}`, expectedErr: `$: unexpected property "extra"`},
+		{name: "wrong type", text: `{"headline":1,"tags":[],"items":[]}`, expectedErr: "$.headline: must be a string"},
+		{name: "too long", text: `{"headline":"こんにちは、元気ですか？","tags":[],"items":[]}`, expectedErr: "$.headline: must be at most 10 characters"},
+		{name: "too many items", text: `{"headline":"hi","tags":["x","y","z"],"items":[]}`, expectedErr: "$.tags: must have at most 2 items"},
+		{name: "not in enum", text: `{"headline":"hi","tags":[],"items":[{"kind":"c","due":null}]}`, expectedErr: "$.items[0].kind: must be one of a, b"},
+		{name: "invalid date", text: `{"headline":"hi","tags":[],"items":[{"kind":"a","due":"next week"}]}`, expectedErr: "$.items[0].due: must be a date"},
+		{name: "null not allowed", text: `{"headline":null,"tags":[],"items":[]}`, expectedErr: "$.headline: must not be null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseJSON(tt.text, testSchema)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+// stubProvider returns err, or a response naming itself
+type stubProvider struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (p *stubProvider) Name() string { return p.name }
+func (p *stubProvider) Close()       {}
+func (p *stubProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &Response{Text: "from " + p.name, Provider: p.name}, nil
+}
+
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+	primary := &stubProvider{name: "primary", err: errors.New("quota exceeded")}
+	secondary := &stubProvider{name: "secondary"}
+	chain := NewChain(primary, secondary)
+
+	resp, err := chain.Generate(ctx, Request{Prompt: "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, "secondary", resp.Provider)
+
+	// The primary provider is tried again first on every request
+	_, err = chain.Generate(ctx, Request{Prompt: "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, primary.calls)
+
+	secondary.err = errors.New("connection refused")
+	_, err = chain.Generate(ctx, Request{Prompt: "hi"})
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.ErrorContains(t, err, "primary: quota exceeded; secondary: connection refused")
+
+	// A cancelled request does not fall back
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	primary.err = context.Canceled
+	_, err = chain.Generate(cancelled, Request{Prompt: "hi"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 3, secondary.calls)
+}
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, EstimateTokens(""))
+	assert.Equal(t, 3, EstimateTokens("hello world"))
+	assert.Equal(t, 5, EstimateTokens("こんにちは"))
+	assert.Equal(t, 5, EstimateTokens("speaker_1: はい"))
+}
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxTokens int
+		overlap   int
+		expected  []string
+	}{
+		{
+			name:      "fits in one chunk",
+			text:      "speaker_1: こんにちは\nspeaker_2: どうも",
+			maxTokens: 100,
+			expected:  []string{"speaker_1: こんにちは\nspeaker_2: どうも"},
+		},
+		{
+			name:      "no limit",
+			text:      "こんにちは",
+			maxTokens: 0,
+			expected:  []string{"こんにちは"},
+		},
+		{
+			name:      "splits at line breaks",
+			text:      "一行目です\n二行目です\n三行目です",
+			maxTokens: 12,
+			expected:  []string{"一行目です\n二行目です", "三行目です"},
+		},
+		{
+			name:      "repeats the last lines as overlap",
+			text:      "一行目です\n二行目です\n三行目です\n四行目です",
+			maxTokens: 12,
+			overlap:   6,
+			expected:  []string{"一行目です\n二行目です", "二行目です\n三行目です", "三行目です\n四行目です"},
+		},
+		{
+			name:      "splits long lines at sentence ends",
+			text:      "今日は晴れ。明日は雨。Then it clears up. Really.",
+			maxTokens: 8,
+			expected:  []string{"今日は晴れ。", "明日は雨。", "Then it clears up. Really."},
+		},
+		{
+			name:      "keeps decimals and words whole",
+			text:      "version 3.5 is out",
+			maxTokens: 4,
+			expected:  []string{"version 3.5 is", "out"},
+		},
+		{
+			name:      "cuts sentences without punctuation",
+			text:      "あいうえおかきくけこさしすせそ",
+			maxTokens: 6,
+			expected:  []string{"あいうえおか", "きくけこさし", "すせそ"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := SplitText(tt.text, tt.maxTokens, tt.overlap)
+			assert.Equal(t, tt.expected, chunks)
+			for _, chunk := range chunks {
+				if tt.maxTokens > 0 {
+					assert.LessOrEqual(t, EstimateTokens(chunk), tt.maxTokens)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OpenAIConfig holds the settings of an OpenAI-compatible chat completions API
+type OpenAIConfig struct {
+	// BaseURL is the API root that /chat/completions is appended to, e.g.
+	// https://api.openai.com/v1, http://localhost:11434/v1 for Ollama or
+	// http://localhost:8080/v1 for the llama.cpp server
+	BaseURL string
+	// APIKey is sent as a bearer token when set; local servers usually need none
+	APIKey string
+	Model  string
+}
+
+// DefaultOpenAIConfig returns the default OpenAI-compatible settings
+func DefaultOpenAIConfig() OpenAIConfig {
+	return OpenAIConfig{
+		BaseURL: "http://localhost:11434/v1",
+		Model:   "llama3.1",
+	}
+}
+
+// OpenAIProvider generates text with a service implementing the OpenAI chat completions API
+type OpenAIProvider struct {
+	config   OpenAIConfig
+	params   Params
+	endpoint string
+	client   *http.Client
+}
+
+// NewOpenAIProvider creates an OpenAIProvider
+func NewOpenAIProvider(config OpenAIConfig, params Params) (*OpenAIProvider, error) {
+	parsed, err := url.Parse(config.BaseURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid LLM base URL %q", config.BaseURL)
+	}
+	if config.Model == "" {
+		return nil, fmt.Errorf("LLM_OPENAI_MODEL is not set")
+	}
+
+	return &OpenAIProvider{
+		config:   config,
+		params:   params,
+		endpoint: strings.TrimSuffix(config.BaseURL, "/") + "/chat/completions",
+		client:   &http.Client{},
+	}, nil
+}
+
+// Name returns the provider name
+func (p *OpenAIProvider) Name() string {
+	return ProviderOpenAI
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type responseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *jsonSchema `json:"json_schema,omitempty"`
+}
+
+type jsonSchema struct {
+	Name   string  `json:"name"`
+	Schema *Schema `json:"schema"`
+	Strict bool    `json:"strict"`
+}
+
+type chatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      chatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// Generate sends the request as a chat completion with an optional system message
+func (p *OpenAIProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	if p.params.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.params.Timeout)
+		defer cancel()
+	}
+
+	body := chatRequest{
+		Model:       p.config.Model,
+		Temperature: p.params.Temperature,
+		MaxTokens:   p.params.MaxTokens,
+	}
+	if req.Schema != nil {
+		body.ResponseFormat = &responseFormat{
+			Type:       "json_schema",
+			JSONSchema: &jsonSchema{Name: "response", Schema: req.Schema, Strict: true},
+		}
+	}
+	if req.System != "" {
+		body.Messages = append(body.Messages, chatMessage{Role: "system", Content: req.System})
+	}
+	body.Messages = append(body.Messages, chatMessage{Role: "user", Content: req.Prompt})
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	if p.config.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", ErrUnavailable, errorMessage(data, resp.Status))
+	}
+
+	var output chatResponse
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("%w: invalid response: %v", ErrUnavailable, err)
+	}
+	if len(output.Choices) == 0 || strings.TrimSpace(output.Choices[0].Message.Content) == "" {
+		return nil, fmt.Errorf("%w: empty response", ErrUnavailable)
+	}
+	model := output.Model
+	if model == "" {
+		model = p.config.Model
+	}
+	return &Response{Text: output.Choices[0].Message.Content, Provider: ProviderOpenAI, Model: model}, nil
+}
+
+// Close releases idle connections
+func (p *OpenAIProvider) Close() {
+	p.client.CloseIdleConnections()
+}
+
+// errorMessage extracts a human readable message from an error body.
+// It understands the OpenAI {"error": {"message"}} shape as well as {"error"}.
+func errorMessage(body []byte, fallback string) string {
+	var nested struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &nested); err == nil && nested.Error.Message != "" {
+		return fallback + ": " + nested.Error.Message
+	}
+	var flat struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &flat); err == nil && flat.Error != "" {
+		return fallback + ": " + flat.Error
+	}
+	return fallback
+}
@@ -0,0 +1,192 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Schema types
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+)
+
+// Schema is the subset of JSON Schema that providers accept for structured
+// output. It is sent to the model to constrain its response, and Validate
+// checks the response again since not every provider enforces it.
+type Schema struct {
+	Type        string
+	Description string
+	// Nullable also allows null, written as "type": [type, "null"]
+	Nullable bool
+	// Object keywords. Objects never allow properties beyond Properties.
+	Properties map[string]*Schema
+	Required   []string
+	// Array keywords
+	Items    *Schema
+	MaxItems int
+	// String keywords. Format "date" requires YYYY-MM-DD.
+	Enum      []string
+	MaxLength int
+	Format    string
+}
+
+// MarshalJSON encodes the schema as standard JSON Schema
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	out := map[string]interface{}{"type": s.Type}
+	if s.Nullable {
+		out["type"] = []string{s.Type, "null"}
+	}
+	if s.Description != "" {
+		out["description"] = s.Description
+	}
+	if s.Type == TypeObject {
+		out["properties"] = s.Properties
+		out["required"] = s.Required
+		out["additionalProperties"] = false
+	}
+	if s.Items != nil {
+		out["items"] = s.Items
+	}
+	if s.MaxItems > 0 {
+		out["maxItems"] = s.MaxItems
+	}
+	if len(s.Enum) > 0 {
+		out["enum"] = s.Enum
+	}
+	if s.MaxLength > 0 {
+		out["maxLength"] = s.MaxLength
+	}
+	if s.Format != "" {
+		out["format"] = s.Format
+	}
+	return json.Marshal(out)
+}
+
+var datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// Validate checks a decoded JSON value against the schema. The error names
+// the offending location, e.g. "$.facts[1].category: must be one of ...",
+// so it can be shown to the model when asking it to correct its output.
+func (s *Schema) Validate(value interface{}) error {
+	return s.validate("$", value)
+}
+
+func (s *Schema) validate(path string, value interface{}) error {
+	if value == nil {
+		if s.Nullable {
+			return nil
+		}
+		return fmt.Errorf("%s: must not be null", path)
+	}
+
+	switch s.Type {
+	case TypeObject:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: must be an object", path)
+		}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("%s: missing property %q", path, name)
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := s.Properties[name]
+			if !ok {
+				return fmt.Errorf("%s: unexpected property %q", path, name)
+			}
+			if err := property.validate(path+"."+name, object[name]); err != nil {
+				return err
+			}
+		}
+	case TypeArray:
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: must be an array", path)
+		}
+		if s.MaxItems > 0 && len(items) > s.MaxItems {
+			return fmt.Errorf("%s: must have at most %d items", path, s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case TypeString:
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: must be a string", path)
+		}
+		if s.MaxLength > 0 && utf8.RuneCountInString(text) > s.MaxLength {
+			return fmt.Errorf("%s: must be at most %d characters", path, s.MaxLength)
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, text) {
+			return fmt.Errorf("%s: must be one of %s", path, strings.Join(s.Enum, ", "))
+		}
+		if s.Format == "date" {
+			if _, err := time.Parse("2006-01-02", text); err != nil || !datePattern.MatchString(text) {
+				return fmt.Errorf("%s: must be a date in YYYY-MM-DD format", path)
+			}
+		}
+	case TypeNumber, TypeInteger:
+		number, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s: must be a number", path)
+		}
+		if s.Type == TypeInteger && number != float64(int64(number)) {
+			return fmt.Errorf("%s: must be an integer", path)
+		}
+	case TypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: must be a boolean", path)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported schema type %q", path, s.Type)
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseJSON extracts the JSON value from a model response and validates it
+// against schema. Models asked for JSON sometimes wrap it in a Markdown code
+// fence or add a sentence around it, so the outermost object is used.
+func ParseJSON(text string, schema *Schema) (interface{}, error) {
+	text = strings.TrimSpace(text)
+	if start, end := strings.Index(text, "{"), strings.LastIndex(text, "}"); start >= 0 && end > start {
+		text = text[start : end+1]
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %v", err)
+	}
+	if err := schema.Validate(value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,16 @@
+package models
+
+// BoundingBox represents a face region in image pixel coordinates
+type BoundingBox struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// DetectedFace represents one face found by the extraction service
+type DetectedFace struct {
+	BoundingBox BoundingBox `json:"bbox"`
+	Confidence  float64     `json:"confidence"`
+	Embedding   []float32   `json:"embedding"`
+}
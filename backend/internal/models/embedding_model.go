@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// DistanceMetric represents how embeddings of a model are compared
+type DistanceMetric string
+
+const (
+	DistanceMetricCosine    DistanceMetric = "cosine"
+	DistanceMetricEuclidean DistanceMetric = "euclidean" // Reported as 1 / (1 + L2 distance) of normalized vectors
+)
+
+// EmbeddingModel represents a registered face embedding model
+type EmbeddingModel struct {
+	ModelVersion     string         `json:"model_version"`
+	Family           string         `json:"family"`
+	Dimension        int            `json:"dimension"`
+	Metric           DistanceMetric `json:"metric"`
+	DefaultThreshold float64        `json:"default_threshold"`
+	Description      *string        `json:"description,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
+
+// EmbeddingModelCreate represents the request body for registering a model
+type EmbeddingModelCreate struct {
+	ModelVersion     string         `json:"model_version" binding:"required,max=100"`
+	Family           string         `json:"family" binding:"required,max=100"`
+	Dimension        int            `json:"dimension" binding:"required,min=1,max=4096"`
+	Metric           DistanceMetric `json:"metric,omitempty" binding:"omitempty,oneof=cosine euclidean"`
+	DefaultThreshold float64        `json:"default_threshold" binding:"required,gt=0,max=1"`
+	Description      *string        `json:"description,omitempty" binding:"omitempty,max=2000"`
+}
+
+// EmbeddingModelList represents a list of registered models
+type EmbeddingModelList struct {
+	Items []EmbeddingModel `json:"items"`
+}
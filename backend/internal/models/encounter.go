@@ -9,6 +9,8 @@ type Encounter struct {
 	RecognizedAt time.Time `json:"recognized_at"`
 	Score        float64   `json:"score"`
 	Summary      *string   `json:"summary,omitempty"`
+	// SummaryDetails is the structured summary whose headline is Summary
+	SummaryDetails *ConversationSummary `json:"summary_details,omitempty"`
 }
 
 // EncounterList represents a paginated list of encounters
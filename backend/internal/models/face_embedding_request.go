@@ -1,9 +1,10 @@
 package models
 
-// FaceEmbeddingRequest represents a request to add a face embedding
+// FaceEmbeddingRequest represents a request to add a face embedding.
+// The embedding dimension is validated against the model registry.
 type FaceEmbeddingRequest struct {
-	Embedding       []float32 `json:"embedding" binding:"required,len=512"`
-	EmbeddingDim    int       `json:"embedding_dim" binding:"required,eq=512"`
+	Embedding       []float32 `json:"embedding" binding:"required,min=1,max=4096"`
+	EmbeddingDim    int       `json:"embedding_dim" binding:"required,min=1,max=4096"`
 	ModelVersion    string    `json:"model_version" binding:"required"`
 	Note            *string   `json:"note,omitempty" binding:"omitempty,max=2000"`
 	SourceImageHash *string   `json:"source_image_hash,omitempty"`
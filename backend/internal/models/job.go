@@ -10,14 +10,64 @@ const (
 	JobStatusRunning   JobStatus = "running"
 	JobStatusSucceeded JobStatus = "succeeded"
 	JobStatusFailed    JobStatus = "failed"
+	JobStatusCancelled JobStatus = "cancelled"
 )
 
+// IsValid reports whether s is a known job status
+func (s JobStatus) IsValid() bool {
+	switch s {
+	case JobStatusQueued, JobStatusRunning, JobStatusSucceeded, JobStatusFailed, JobStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// TranscriptWord is a recognized word with its position in the recording
+type TranscriptWord struct {
+	Word  string  `json:"word"`
+	Start float64 `json:"start"` // Seconds from the start of the recording
+	End   float64 `json:"end"`
+	// Confidence is between 0 and 1 when the engine reports it
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+// TranscriptSegment is a stretch of speech by one speaker
+type TranscriptSegment struct {
+	// Speaker is a label such as "speaker_1", consistent within one job. It is
+	// empty when the transcription backend does not tell speakers apart.
+	Speaker string  `json:"speaker,omitempty"`
+	Start   float64 `json:"start"` // Seconds from the start of the recording
+	End     float64 `json:"end"`
+	Text    string  `json:"text"`
+}
+
+// Transcript is the speaker-segmented transcript of a job
+type Transcript struct {
+	JobID       string              `json:"job_id"`
+	Language    string              `json:"language"`
+	DurationSec float64             `json:"duration_sec"`
+	Speakers    []string            `json:"speakers"` // Speaker labels in order of first appearance
+	Segments    []TranscriptSegment `json:"segments"`
+}
+
 // TranscriptionResult represents the result of transcription
 type TranscriptionResult struct {
-	PersonID    *string `json:"person_id,omitempty"`
-	Transcript  string  `json:"transcript"`
-	Summary     string  `json:"summary"`
-	Language    string  `json:"language"`
+	PersonID       *string              `json:"person_id,omitempty"`
+	EncounterID    *string              `json:"encounter_id,omitempty"` // Encounter the summary was recorded on
+	Transcript     string               `json:"transcript"`
+	Summary        string               `json:"summary"`
+	SummaryDetails *ConversationSummary `json:"summary_details,omitempty"` // Structured summary whose headline is Summary
+	Language       string               `json:"language"`
+	DurationSec    float64              `json:"duration_sec"`
+	Words          []TranscriptWord     `json:"words,omitempty"`
+}
+
+// AudioInfo describes the recording of a job, as read from its headers on upload
+type AudioInfo struct {
+	Format      string  `json:"format"` // wav, flac, ogg or mp3
+	Codec       string  `json:"codec"`
+	SampleRate  int     `json:"sample_rate"`
+	Channels    int     `json:"channels"`
 	DurationSec float64 `json:"duration_sec"`
 }
 
@@ -25,8 +75,85 @@ type TranscriptionResult struct {
 type Job struct {
 	JobID      string               `json:"job_id"`
 	Status     JobStatus            `json:"status"`
+	Attempts   int                  `json:"attempts"`
+	Audio      *AudioInfo           `json:"audio,omitempty"`
 	CreatedAt  time.Time            `json:"created_at"`
 	FinishedAt *time.Time           `json:"finished_at,omitempty"`
 	Result     *TranscriptionResult `json:"result,omitempty"`
 	Error      *Problem             `json:"error,omitempty"`
 }
+
+// JobList represents a paginated list of jobs
+type JobList struct {
+	Items      []Job   `json:"items"`
+	NextCursor *string `json:"next_cursor,omitempty"`
+}
+
+// JobFilter narrows a job listing. Nil fields are not filtered on.
+type JobFilter struct {
+	Status        *JobStatus
+	PersonID      *string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+// WebhookDeliveryStatus represents the state of a webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is a webhook notification about a job and the state of its delivery
+type WebhookDelivery struct {
+	DeliveryID string                `json:"delivery_id"`
+	Event      string                `json:"event"`
+	URL        string                `json:"url"`
+	Status     WebhookDeliveryStatus `json:"status"`
+	Attempts   int                   `json:"attempts"`
+	// LastStatusCode is the HTTP status of the latest attempt, if a response was received
+	LastStatusCode *int       `json:"last_status_code,omitempty"`
+	LastError      *string    `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// WebhookDeliveryList represents the deliveries of a job
+type WebhookDeliveryList struct {
+	Items []WebhookDelivery `json:"items"`
+}
+
+// WebhookPayload is the JSON body sent to webhook targets
+type WebhookPayload struct {
+	DeliveryID string    `json:"delivery_id"`
+	Event      string    `json:"event"`
+	CreatedAt  time.Time `json:"created_at"`
+	Job        Job       `json:"job"`
+}
+
+// JobStage is the progress of a job within its status
+type JobStage string
+
+const (
+	// JobStageUploaded means the audio is stored and the job waits for a worker
+	JobStageUploaded     JobStage = "uploaded"
+	JobStageTranscribing JobStage = "transcribing"
+	JobStageSummarizing  JobStage = "summarizing"
+	// JobStageDone means the job reached succeeded, failed or cancelled
+	JobStageDone JobStage = "done"
+)
+
+// JobEvent is a state change or progress update of a job, streamed to clients
+type JobEvent struct {
+	ID       int64     `json:"-"` // Sent as the SSE event ID
+	JobID    string    `json:"job_id"`
+	Status   JobStatus `json:"status"`
+	Stage    JobStage  `json:"stage"`
+	Attempts int       `json:"attempts"`
+	Time     time.Time `json:"time"`
+	// Job is the full job, included when the stage is done and in the first event of a stream
+	Job *Job `json:"job,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// ProfileDocument is the long-term memory about a person, merged from the
+// summaries of all conversations with them
+type ProfileDocument struct {
+	Overview  string       `json:"overview"` // A few sentences on who the person is
+	Facts     []PersonFact `json:"facts"`
+	Interests []string     `json:"interests"`
+	FollowUps []FollowUp   `json:"follow_ups"` // Commitments that are still open
+}
+
+// PersonProfile is a version of a person's profile
+type PersonProfile struct {
+	PersonID string `json:"person_id"`
+	Version  int    `json:"version"`
+	ProfileDocument
+	EncounterID  *string   `json:"encounter_id,omitempty"`  // Encounter merged into this version
+	RevertedFrom *int      `json:"reverted_from,omitempty"` // Version restored by a revert
+	CreatedAt    time.Time `json:"created_at"`
+	// PendingEncounters is how many summarized encounters are waiting to be
+	// merged; only set on the current profile
+	PendingEncounters *int `json:"pending_encounters,omitempty"`
+}
+
+// ProfileVersionList represents a paginated list of profile versions, newest first
+type ProfileVersionList struct {
+	Items      []PersonProfile `json:"items"`
+	NextCursor *string         `json:"next_cursor,omitempty"`
+}
+
+// ProfileRevertRequest restores an earlier profile version
+type ProfileRevertRequest struct {
+	Version int `json:"version" binding:"required,min=1"`
+}
@@ -4,16 +4,33 @@ package models
 type RecognitionStatus string
 
 const (
-	RecognitionStatusKnown   RecognitionStatus = "known"
-	RecognitionStatusUnknown RecognitionStatus = "unknown"
+	RecognitionStatusKnown     RecognitionStatus = "known"
+	RecognitionStatusUnknown   RecognitionStatus = "unknown"
+	RecognitionStatusAmbiguous RecognitionStatus = "ambiguous" // Top two persons are too close to call
 )
 
+// ScoreAggregation represents how face-level scores are combined into a person score
+type ScoreAggregation string
+
+const (
+	ScoreAggregationMax      ScoreAggregation = "max"
+	ScoreAggregationMeanTopN ScoreAggregation = "mean_top_n"
+	ScoreAggregationCentroid ScoreAggregation = "centroid"
+)
+
+// MatchedFace represents an enrolled face that contributed to a candidate's score
+type MatchedFace struct {
+	FaceID string  `json:"face_id"`
+	Score  float64 `json:"score"`
+}
+
 // RecognitionCandidate represents a potential match candidate
 type RecognitionCandidate struct {
-	PersonID    string  `json:"person_id"`
-	Name        string  `json:"name"`
-	Score       float64 `json:"score"`
-	LastSummary *string `json:"last_summary,omitempty"`
+	PersonID     string        `json:"person_id"`
+	Name         string        `json:"name"`
+	Score        float64       `json:"score"`
+	LastSummary  *string       `json:"last_summary,omitempty"`
+	MatchedFaces []MatchedFace `json:"matched_faces,omitempty"`
 }
 
 // RecognitionResponse represents the response for face recognition
@@ -23,3 +40,27 @@ type RecognitionResponse struct {
 	Candidates       []RecognitionCandidate `json:"candidates"`
 	CreatedEncounter *Encounter             `json:"created_encounter,omitempty"`
 }
+
+// BatchRecognitionItemResult represents the recognition result of one face in a batch
+type BatchRecognitionItemResult struct {
+	TrackID string `json:"track_id"`
+	RecognitionResponse
+}
+
+// BatchRecognitionResponse represents the response for batch recognition, in request order
+type BatchRecognitionResponse struct {
+	Items []BatchRecognitionItemResult `json:"items"`
+}
+
+// RecognizedFace represents the recognition result of one face detected in an image
+type RecognizedFace struct {
+	BoundingBox         BoundingBox `json:"bbox"`
+	DetectionConfidence float64     `json:"detection_confidence"`
+	RecognitionResponse
+}
+
+// ImageRecognitionResponse represents the response for image recognition.
+// Faces are ordered by detection confidence, highest first.
+type ImageRecognitionResponse struct {
+	Faces []RecognizedFace `json:"faces"`
+}
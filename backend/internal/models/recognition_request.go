@@ -1,10 +1,54 @@
 package models
 
-// RecognitionRequest represents a face recognition request with pre-computed embedding
+// RecognitionRequest represents a face recognition request with pre-computed embedding.
+// The embedding dimension is validated against the model registry.
 type RecognitionRequest struct {
-	Embedding    []float32 `json:"embedding" binding:"required,len=512"`
-	EmbeddingDim int       `json:"embedding_dim" binding:"required,eq=512"`
+	Embedding    []float32 `json:"embedding" binding:"required,min=1,max=4096"`
+	EmbeddingDim int       `json:"embedding_dim" binding:"required,min=1,max=4096"`
 	ModelVersion string    `json:"model_version" binding:"required"`
 	TopK         int       `json:"top_k" binding:"omitempty,min=1,max=10"`
 	MinScore     float64   `json:"min_score" binding:"omitempty,min=0,max=1"`
+	// Aggregation selects how face scores are combined per person (defaults to server configuration)
+	Aggregation ScoreAggregation `json:"aggregation,omitempty" binding:"omitempty,oneof=max mean_top_n centroid"`
+	// AggregationTopN is the number of best faces averaged by mean_top_n
+	AggregationTopN int `json:"aggregation_top_n,omitempty" binding:"omitempty,min=1,max=20"`
+	// AmbiguityMargin is the minimum score gap between the top two persons (defaults to server configuration, 0 disables)
+	AmbiguityMargin *float64 `json:"ambiguity_margin,omitempty" binding:"omitempty,min=0,max=1"`
+	// LogEncounter records an encounter for a known match (defaults to true)
+	LogEncounter *bool `json:"log_encounter,omitempty"`
+}
+
+// BatchRecognitionItem represents one face of a frame in a batch recognition request
+type BatchRecognitionItem struct {
+	TrackID      string    `json:"track_id" binding:"required,max=100"` // Client-side tracking ID of the face
+	Embedding    []float32 `json:"embedding" binding:"required,min=1,max=4096"`
+	EmbeddingDim int       `json:"embedding_dim" binding:"required,min=1,max=4096"`
+}
+
+// BatchRecognitionRequest represents a recognition request for several faces in one frame.
+// Options apply to every item.
+type BatchRecognitionRequest struct {
+	Items           []BatchRecognitionItem `json:"items" binding:"required,min=1,max=20,dive"`
+	ModelVersion    string                 `json:"model_version" binding:"required"`
+	TopK            int                    `json:"top_k" binding:"omitempty,min=1,max=10"`
+	MinScore        float64                `json:"min_score" binding:"omitempty,min=0,max=1"`
+	Aggregation     ScoreAggregation       `json:"aggregation,omitempty" binding:"omitempty,oneof=max mean_top_n centroid"`
+	AggregationTopN int                    `json:"aggregation_top_n,omitempty" binding:"omitempty,min=1,max=20"`
+	AmbiguityMargin *float64               `json:"ambiguity_margin,omitempty" binding:"omitempty,min=0,max=1"`
+	LogEncounter    *bool                  `json:"log_encounter,omitempty"`
+}
+
+// ItemRequest returns the single-face request equivalent to item i
+func (r *BatchRecognitionRequest) ItemRequest(i int) *RecognitionRequest {
+	return &RecognitionRequest{
+		Embedding:       r.Items[i].Embedding,
+		EmbeddingDim:    r.Items[i].EmbeddingDim,
+		ModelVersion:    r.ModelVersion,
+		TopK:            r.TopK,
+		MinScore:        r.MinScore,
+		Aggregation:     r.Aggregation,
+		AggregationTopN: r.AggregationTopN,
+		AmbiguityMargin: r.AmbiguityMargin,
+		LogEncounter:    r.LogEncounter,
+	}
 }
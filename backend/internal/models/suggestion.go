@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Suggestion is a conversation opener suggested for the next meeting with a person
+type Suggestion struct {
+	Rank   int    `json:"rank"` // 1 is the most promising
+	Opener string `json:"opener"`
+	Reason string `json:"reason"` // What the opener builds on, e.g. a follow-up that is due
+}
+
+// SuggestionList is the ranked list of openers for a person
+type SuggestionList struct {
+	PersonID         string       `json:"person_id"`
+	LastMetAt        *time.Time   `json:"last_met_at,omitempty"`
+	DaysSinceLastMet *int         `json:"days_since_last_met,omitempty"`
+	Items            []Suggestion `json:"items"`
+	// GeneratedAt is when the suggestions were generated, which is earlier
+	// than the request when they came from the cache
+	GeneratedAt time.Time `json:"generated_at"`
+	Cached      bool      `json:"cached"`
+}
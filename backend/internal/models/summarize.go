@@ -3,9 +3,65 @@ package models
 // SummarizeRequest represents a request to summarize text
 type SummarizeRequest struct {
 	Text string `json:"text" binding:"required"`
+	// Date is when the conversation took place (YYYY-MM-DD), used to resolve
+	// relative due dates; today when omitted
+	Date string `json:"date,omitempty"`
 }
 
 // SummarizeResponse represents a response from the summarize endpoint
 type SummarizeResponse struct {
-	Summary string `json:"summary"`
+	Summary  string              `json:"summary"` // Same as details.headline
+	Details  ConversationSummary `json:"details"`
+	Metadata SummaryMetadata     `json:"metadata"`
+}
+
+// SummaryMetadata describes how a summary was produced. Text longer than a
+// chunk is summarized chunk by chunk, and the chunk summaries are combined
+// in one or more reduce rounds.
+type SummaryMetadata struct {
+	EstimatedTokens int `json:"estimated_tokens"`
+	Chunks          int `json:"chunks"`
+	ReduceRounds    int `json:"reduce_rounds"`
+}
+
+// Categories of facts learned about a person
+const (
+	FactCategoryFamily     = "family"
+	FactCategoryWork       = "work"
+	FactCategoryEducation  = "education"
+	FactCategoryHobby      = "hobby"
+	FactCategoryHealth     = "health"
+	FactCategoryPreference = "preference"
+	FactCategoryOther      = "other"
+)
+
+// FactCategories lists the fact categories in the order shown to the model
+var FactCategories = []string{
+	FactCategoryFamily,
+	FactCategoryWork,
+	FactCategoryEducation,
+	FactCategoryHobby,
+	FactCategoryHealth,
+	FactCategoryPreference,
+	FactCategoryOther,
+}
+
+// ConversationSummary is the structured summary of a conversation
+type ConversationSummary struct {
+	Headline  string       `json:"headline"`
+	Topics    []string     `json:"topics"`
+	Facts     []PersonFact `json:"facts"`      // What was learned about the person
+	FollowUps []FollowUp   `json:"follow_ups"` // What was promised to be done later
+}
+
+// PersonFact is something learned about the person, e.g. that they have two children
+type PersonFact struct {
+	Category string `json:"category"`
+	Fact     string `json:"fact"`
+}
+
+// FollowUp is a commitment made during a conversation
+type FollowUp struct {
+	Description string  `json:"description"`
+	DueDate     *string `json:"due_date"` // YYYY-MM-DD, or null when no date was mentioned
 }
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// UploadStatus represents the state of a resumable upload
+type UploadStatus string
+
+const (
+	UploadStatusUploading  UploadStatus = "uploading"
+	UploadStatusAssembling UploadStatus = "assembling"
+	UploadStatusCompleted  UploadStatus = "completed"
+)
+
+// UploadCreate represents the request body for starting a resumable upload
+type UploadCreate struct {
+	Length     int64   `json:"length" binding:"required,min=1"`
+	PersonID   *string `json:"person_id,omitempty" binding:"omitempty,max=50"`
+	WebhookURL *string `json:"webhook_url,omitempty"`
+	// Checksum is the expected "sha256:<hex>" of the complete audio
+	Checksum *string `json:"checksum,omitempty"`
+}
+
+// Upload represents a resumable audio upload
+type Upload struct {
+	UploadID  string       `json:"upload_id"`
+	Status    UploadStatus `json:"status"`
+	Offset    int64        `json:"offset"`
+	Length    int64        `json:"length"`
+	PersonID  *string      `json:"person_id,omitempty"`
+	Checksum  *string      `json:"checksum,omitempty"`
+	JobID     *string      `json:"job_id,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	ExpiresAt time.Time    `json:"expires_at"`
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -63,6 +64,61 @@ func (r *EncounterRepository) FindByID(encounterID string) (*EncounterEntity, er
 	return &encounter, nil
 }
 
+// FindLatestByPersonID retrieves the most recent encounter of a person
+func (r *EncounterRepository) FindLatestByPersonID(personID string) (*EncounterEntity, error) {
+	var encounter EncounterEntity
+	if err := r.db.Where("person_id = ?", personID).Order("recognized_at DESC").First(&encounter).Error; err != nil {
+		return nil, err
+	}
+	return &encounter, nil
+}
+
+// FindLatestUnsummarized retrieves the most recent encounter of a person
+// recognized between from and to that has no summary yet
+func (r *EncounterRepository) FindLatestUnsummarized(personID string, from, to time.Time) (*EncounterEntity, error) {
+	var encounter EncounterEntity
+	err := r.db.Where("person_id = ? AND summary IS NULL AND recognized_at BETWEEN ? AND ?", personID, from, to).
+		Order("recognized_at DESC").
+		First(&encounter).Error
+	if err != nil {
+		return nil, err
+	}
+	return &encounter, nil
+}
+
+// FindLatestSummarized retrieves the most recent encounter of a person that has a summary
+func (r *EncounterRepository) FindLatestSummarized(personID string) (*EncounterEntity, error) {
+	var encounter EncounterEntity
+	err := r.db.Where("person_id = ? AND summary IS NOT NULL", personID).
+		Order("recognized_at DESC").
+		First(&encounter).Error
+	if err != nil {
+		return nil, err
+	}
+	return &encounter, nil
+}
+
+// FindRecentSummarized retrieves up to limit of the most recent encounters of
+// a person that have a summary, newest first
+func (r *EncounterRepository) FindRecentSummarized(personID string, limit int) ([]EncounterEntity, error) {
+	var encounters []EncounterEntity
+	err := r.db.Where("person_id = ? AND summary IS NOT NULL", personID).
+		Order("recognized_at DESC").
+		Limit(limit).
+		Find(&encounters).Error
+	if err != nil {
+		return nil, err
+	}
+	return encounters, nil
+}
+
+// UpdateSummary sets the summary of an encounter and its structured details
+func (r *EncounterRepository) UpdateSummary(encounterID string, summary string, details *string) error {
+	return r.db.Model(&EncounterEntity{}).
+		Where("encounter_id = ?", encounterID).
+		Updates(map[string]interface{}{"summary": summary, "summary_details": details}).Error
+}
+
 // UpdateLastSummaryForPerson updates the last_summary field of a person based on the latest encounter
 func (r *EncounterRepository) UpdateLastSummaryForPerson(personID string, summary *string) error {
 	return r.db.Model(&PersonEntity{}).
@@ -40,9 +40,11 @@ func (r *FaceRepository) Delete(faceID string) error {
 	return r.db.Delete(&FaceEntity{}, "face_id = ?", faceID).Error
 }
 
-// FindAllEmbeddings retrieves all face embeddings for similarity search
+// FindAllEmbeddings retrieves all face embeddings of non-deleted persons for similarity search
 func (r *FaceRepository) FindAllEmbeddings() ([]FaceEntity, error) {
 	var faces []FaceEntity
-	err := r.db.Select("face_id, person_id, embedding, embedding_dim").Find(&faces).Error
+	err := r.db.Select("face_id, person_id, embedding, embedding_dim, model_version").
+		Where("person_id IN (?)", r.db.Model(&PersonEntity{}).Select("person_id")).
+		Find(&faces).Error
 	return faces, err
 }
@@ -1,11 +1,20 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrLeaseLost is returned when a worker updates a job whose lease it no longer holds
+	ErrLeaseLost = errors.New("job lease lost")
+	// ErrInvalidTransition is returned when the job state machine does not allow a status change
+	ErrInvalidTransition = errors.New("invalid job status transition")
+)
+
 // JobRepository handles job data access
 type JobRepository struct {
 	db *gorm.DB
@@ -21,6 +30,17 @@ func (r *JobRepository) Create(job *JobEntity) error {
 	return r.db.Create(job).Error
 }
 
+// CreateWith creates a job and runs onCreate in the same transaction. An
+// error from onCreate rolls the job back.
+func (r *JobRepository) CreateWith(job *JobEntity, onCreate func(tx *gorm.DB) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		return onCreate(tx)
+	})
+}
+
 // FindByID retrieves a job by ID
 func (r *JobRepository) FindByID(jobID string) (*JobEntity, error) {
 	var job JobEntity
@@ -35,23 +55,213 @@ func (r *JobRepository) Update(job *JobEntity) error {
 	return r.db.Save(job).Error
 }
 
-// UpdateStatus updates the status of a job
-func (r *JobRepository) UpdateStatus(jobID string, status JobStatus, finishedAt *time.Time) error {
-	updates := map[string]interface{}{
-		"status": status,
+// JobFilter narrows a job listing. Nil fields are not filtered on.
+type JobFilter struct {
+	Status        *JobStatus
+	PersonID      *string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+// List retrieves jobs matching filter, newest first, with pagination
+func (r *JobRepository) List(filter JobFilter, limit int, cursor *string) ([]JobEntity, *string, error) {
+	var jobs []JobEntity
+	query := r.db.Model(&JobEntity{})
+
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+	if filter.PersonID != nil {
+		query = query.Where("person_id = ?", *filter.PersonID)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
 	}
-	if finishedAt != nil {
-		updates["finished_at"] = finishedAt
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
 	}
-	return r.db.Model(&JobEntity{}).Where("job_id = ?", jobID).Updates(updates).Error
+
+	// Apply cursor pagination
+	if cursor != nil && *cursor != "" {
+		decodedCursor, err := decodeCursor(*cursor)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid cursor: %w", err)
+		}
+		query = query.Where("created_at < ?", decodedCursor)
+	}
+
+	// Fetch limit + 1 to check if there's a next page
+	query = query.Order("created_at DESC").Limit(limit + 1)
+
+	if err := query.Find(&jobs).Error; err != nil {
+		return nil, nil, err
+	}
+
+	// Check if there's a next page
+	var nextCursor *string
+	if len(jobs) > limit {
+		encoded := encodeCursor(jobs[limit-1].CreatedAt)
+		nextCursor = &encoded
+		jobs = jobs[:limit]
+	}
+
+	return jobs, nextCursor, nil
 }
 
-// FindPendingJobs retrieves jobs that are queued or running
-func (r *JobRepository) FindPendingJobs(limit int) ([]JobEntity, error) {
-	var jobs []JobEntity
-	err := r.db.Where("status IN ?", []JobStatus{JobStatusQueued, JobStatusRunning}).
+// Transition moves a job to status next and applies updates, provided the
+// state machine allows the move from the job's current status. It returns
+// gorm.ErrRecordNotFound for unknown jobs and ErrInvalidTransition otherwise.
+func (r *JobRepository) Transition(jobID string, next JobStatus, updates map[string]interface{}) (*JobEntity, error) {
+	return r.TransitionFrom(jobID, jobStatusesLeadingTo(next), next, updates)
+}
+
+// TransitionFrom is Transition restricted to jobs currently in one of the
+// statuses in from, for moves that the state machine allows from other
+// statuses too but that only make sense from these
+func (r *JobRepository) TransitionFrom(jobID string, from []JobStatus, next JobStatus, updates map[string]interface{}) (*JobEntity, error) {
+	updates["status"] = next
+	result := r.db.Model(&JobEntity{}).
+		Where("job_id = ? AND status IN ?", jobID, from).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Distinguish a missing job from one in the wrong status
+		if _, err := r.FindByID(jobID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidTransition
+	}
+	return r.FindByID(jobID)
+}
+
+// Delete deletes a job together with its webhook deliveries and transcript segments
+func (r *JobRepository) Delete(jobID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&WebhookDeliveryEntity{}, "job_id = ?", jobID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&TranscriptSegmentEntity{}, "job_id = ?", jobID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&JobEntity{}, "job_id = ?", jobID).Error
+	})
+}
+
+// CountAudioReferences counts the jobs and upload chunks that reference an
+// audio object. Storage is content-addressed, so a chunk of a resumable upload
+// may share its object with a job.
+func (r *JobRepository) CountAudioReferences(audioKey string) (int64, error) {
+	var jobs, chunks int64
+	if err := r.db.Model(&JobEntity{}).Where("audio_key = ?", audioKey).Count(&jobs).Error; err != nil {
+		return 0, err
+	}
+	if err := r.db.Model(&UploadChunkEntity{}).Where("chunk_key = ?", audioKey).Count(&chunks).Error; err != nil {
+		return 0, err
+	}
+	return jobs + chunks, nil
+}
+
+// DetachPersonAudio removes the audio reference from every job of a person
+// and returns the keys that were referenced
+func (r *JobRepository) DetachPersonAudio(personID string) ([]string, error) {
+	var keys []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&JobEntity{}).
+			Where("person_id = ? AND audio_key IS NOT NULL", personID).
+			Distinct().
+			Pluck("audio_key", &keys).Error; err != nil {
+			return err
+		}
+		return tx.Model(&JobEntity{}).
+			Where("person_id = ? AND audio_key IS NOT NULL", personID).
+			Update("audio_key", nil).Error
+	})
+	return keys, err
+}
+
+// claimableCondition matches queued jobs whose retry delay has passed and
+// running jobs whose lease has expired (their worker crashed or stalled)
+const claimableCondition = "(status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)) OR " +
+	"(status = ? AND (lease_expires_at IS NULL OR lease_expires_at < ?))"
+
+// ClaimNext leases the oldest claimable job to owner until leaseUntil.
+// It returns nil when there is nothing to do. The conditional update makes
+// the claim safe against other workers racing for the same job.
+func (r *JobRepository) ClaimNext(owner string, now, leaseUntil time.Time) (*JobEntity, error) {
+	var candidates []JobEntity
+	err := r.db.Select("job_id").
+		Where(claimableCondition, JobStatusQueued, now, JobStatusRunning, now).
 		Order("created_at ASC").
-		Limit(limit).
-		Find(&jobs).Error
-	return jobs, err
+		Limit(5).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range candidates {
+		result := r.db.Model(&JobEntity{}).
+			Where("job_id = ?", candidate.JobID).
+			Where(claimableCondition, JobStatusQueued, now, JobStatusRunning, now).
+			Updates(map[string]interface{}{
+				"status":           JobStatusRunning,
+				"lease_owner":      owner,
+				"lease_expires_at": leaseUntil,
+				"started_at":       now,
+				"attempts":         gorm.Expr("attempts + 1"),
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return r.FindByID(candidate.JobID)
+		}
+	}
+	return nil, nil
+}
+
+// RenewLease extends the lease held by owner
+func (r *JobRepository) RenewLease(jobID, owner string, leaseUntil time.Time) error {
+	return updateLeased(r.db, jobID, owner, map[string]interface{}{
+		"lease_expires_at": leaseUntil,
+	})
+}
+
+// FinishLeased applies updates to a job and releases its lease, provided owner still holds it.
+// When onFinish is set it runs in the same transaction with the updated job, and
+// an error from it rolls the update back.
+func (r *JobRepository) FinishLeased(jobID, owner string, updates map[string]interface{}, onFinish func(tx *gorm.DB, job *JobEntity) error) error {
+	updates["lease_owner"] = nil
+	updates["lease_expires_at"] = nil
+	if onFinish == nil {
+		return updateLeased(r.db, jobID, owner, updates)
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := updateLeased(tx, jobID, owner, updates); err != nil {
+			return err
+		}
+		var job JobEntity
+		if err := tx.First(&job, "job_id = ?", jobID).Error; err != nil {
+			return err
+		}
+		return onFinish(tx, &job)
+	})
+}
+
+func updateLeased(db *gorm.DB, jobID, owner string, updates map[string]interface{}) error {
+	if next, ok := updates["status"].(JobStatus); ok && !JobStatusRunning.CanTransitionTo(next) {
+		return ErrInvalidTransition
+	}
+	result := db.Model(&JobEntity{}).
+		Where("job_id = ? AND status = ? AND lease_owner = ?", jobID, JobStatusRunning, owner).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
 }
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+)
+
+// ModelRepository handles embedding model registry data access
+type ModelRepository struct {
+	db *gorm.DB
+}
+
+// NewModelRepository creates a new ModelRepository
+func NewModelRepository(db *gorm.DB) *ModelRepository {
+	return &ModelRepository{db: db}
+}
+
+// FindAll retrieves every registered model
+func (r *ModelRepository) FindAll() ([]EmbeddingModelEntity, error) {
+	var models []EmbeddingModelEntity
+	err := r.db.Order("model_version ASC").Find(&models).Error
+	return models, err
+}
+
+// FindByVersion retrieves a model by its version
+func (r *ModelRepository) FindByVersion(modelVersion string) (*EmbeddingModelEntity, error) {
+	var model EmbeddingModelEntity
+	if err := r.db.First(&model, "model_version = ?", modelVersion).Error; err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// FindByFamily retrieves the first registered model of a family
+func (r *ModelRepository) FindByFamily(family string) (*EmbeddingModelEntity, error) {
+	var model EmbeddingModelEntity
+	if err := r.db.Order("created_at ASC").First(&model, "family = ?", family).Error; err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// Create registers a new model
+func (r *ModelRepository) Create(model *EmbeddingModelEntity) error {
+	return r.db.Create(model).Error
+}
@@ -49,6 +49,23 @@ func (FaceEntity) TableName() string {
 	return "faces"
 }
 
+// EmbeddingModelEntity represents a registered face embedding model in the database
+type EmbeddingModelEntity struct {
+	ModelVersion     string    `gorm:"primaryKey;type:varchar(100)"` // e.g., "facenet-tflite-v1"
+	Family           string    `gorm:"type:varchar(100);not null;index"`
+	Dimension        int       `gorm:"not null"`
+	Metric           string    `gorm:"type:varchar(20);not null;default:'cosine'"`
+	DefaultThreshold float64   `gorm:"type:double precision;not null"`
+	Description      *string   `gorm:"type:text"`
+	CreatedAt        time.Time `gorm:"not null"`
+	UpdatedAt        time.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for EmbeddingModelEntity
+func (EmbeddingModelEntity) TableName() string {
+	return "embedding_models"
+}
+
 // EncounterEntity represents an encounter log in the database
 type EncounterEntity struct {
 	EncounterID  string    `gorm:"primaryKey;type:varchar(50)"`
@@ -56,7 +73,10 @@ type EncounterEntity struct {
 	RecognizedAt time.Time `gorm:"not null;index"`
 	Score        float64   `gorm:"type:double precision;not null"`
 	Summary      *string   `gorm:"type:text"`
-	CreatedAt    time.Time `gorm:"not null;index"`
+	// SummaryDetails is the structured summary as a JSON ConversationSummary,
+	// whose headline is Summary; null for summaries recorded before it existed
+	SummaryDetails *string   `gorm:"type:text"`
+	CreatedAt      time.Time `gorm:"not null;index"`
 
 	// Relations
 	Person PersonEntity `gorm:"foreignKey:PersonID"`
@@ -75,25 +95,222 @@ const (
 	JobStatusRunning   JobStatus = "running"
 	JobStatusSucceeded JobStatus = "succeeded"
 	JobStatusFailed    JobStatus = "failed"
+	JobStatusCancelled JobStatus = "cancelled"
 )
 
+// jobTransitions lists the statuses a job may move to from each status.
+// running -> running is a worker taking over a job whose lease expired, and
+// running -> queued is a retry after a failed attempt or a shutdown.
+var jobTransitions = map[JobStatus][]JobStatus{
+	JobStatusQueued:    {JobStatusRunning, JobStatusCancelled},
+	JobStatusRunning:   {JobStatusRunning, JobStatusQueued, JobStatusSucceeded, JobStatusFailed, JobStatusCancelled},
+	JobStatusFailed:    {JobStatusQueued},
+	JobStatusSucceeded: {},
+	JobStatusCancelled: {},
+}
+
+// CanTransitionTo reports whether a job in status s may move to next
+func (s JobStatus) CanTransitionTo(next JobStatus) bool {
+	for _, allowed := range jobTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal reports whether no further processing happens in status s
+func (s JobStatus) IsTerminal() bool {
+	return s == JobStatusSucceeded || s == JobStatusFailed || s == JobStatusCancelled
+}
+
+// jobStatusesLeadingTo returns the statuses from which a job may move to next
+func jobStatusesLeadingTo(next JobStatus) []JobStatus {
+	var from []JobStatus
+	for _, status := range []JobStatus{JobStatusQueued, JobStatusRunning, JobStatusSucceeded, JobStatusFailed, JobStatusCancelled} {
+		if status.CanTransitionTo(next) {
+			from = append(from, status)
+		}
+	}
+	return from
+}
+
 // JobEntity represents an async job in the database
 type JobEntity struct {
-	JobID        string     `gorm:"primaryKey;type:varchar(50)"`
-	PersonID     *string    `gorm:"type:varchar(50);index"`
-	Status       JobStatus  `gorm:"type:varchar(20);not null;index;default:'queued'"`
-	AudioPath    *string    `gorm:"type:varchar(500)"`
-	WebhookURL   *string    `gorm:"type:varchar(500)"`
-	Transcript   *string    `gorm:"type:text"`
-	Summary      *string    `gorm:"type:text"`
-	Language     *string    `gorm:"type:varchar(10)"`
-	DurationSec  *float64   `gorm:"type:double precision"`
-	ErrorMessage *string    `gorm:"type:text"`
-	CreatedAt    time.Time  `gorm:"not null;index"`
-	FinishedAt   *time.Time `gorm:"index"`
+	JobID          string    `gorm:"primaryKey;type:varchar(50)"`
+	PersonID       *string   `gorm:"type:varchar(50);index"`
+	Status         JobStatus `gorm:"type:varchar(20);not null;index;default:'queued'"`
+	AudioKey       *string   `gorm:"type:varchar(200);index"` // Content-addressed key in blob storage
+	AudioFormat    *string   `gorm:"type:varchar(10)"`        // Container detected on upload, e.g. "ogg"
+	AudioCodec     *string   `gorm:"type:varchar(10)"`
+	SampleRate     *int
+	Channels       *int
+	WebhookURL     *string    `gorm:"type:varchar(500)"`
+	Transcript     *string    `gorm:"type:text"`
+	Words          *string    `gorm:"type:text"` // JSON array of word timestamps
+	Summary        *string    `gorm:"type:text"`
+	SummaryDetails *string    `gorm:"type:text"` // JSON ConversationSummary whose headline is Summary
+	Language       *string    `gorm:"type:varchar(10)"`
+	DurationSec    *float64   `gorm:"type:double precision"` // Read from the audio on upload, or reported by the transcriber
+	ErrorMessage   *string    `gorm:"type:text"`
+	EncounterID    *string    `gorm:"type:varchar(50);index"` // Encounter the summary was attached to
+	CreatedAt      time.Time  `gorm:"not null;index"`
+	FinishedAt     *time.Time `gorm:"index"`
+
+	// Worker bookkeeping
+	Attempts       int        `gorm:"not null;default:0"`
+	LeaseOwner     *string    `gorm:"type:varchar(100)"`
+	LeaseExpiresAt *time.Time `gorm:"index"`
+	NextAttemptAt  *time.Time `gorm:"index"` // Earliest time a queued retry may run
+	StartedAt      *time.Time
 }
 
 // TableName specifies the table name for JobEntity
 func (JobEntity) TableName() string {
 	return "jobs"
 }
+
+// TranscriptSegmentEntity represents a stretch of speech by one speaker in a job's transcript
+type TranscriptSegmentEntity struct {
+	JobID    string  `gorm:"primaryKey;type:varchar(50)"`
+	Seq      int     `gorm:"primaryKey;autoIncrement:false"`       // Position of the segment within the transcript
+	Speaker  string  `gorm:"type:varchar(50);not null;default:''"` // Empty when speakers were not told apart
+	StartSec float64 `gorm:"type:double precision;not null"`
+	EndSec   float64 `gorm:"type:double precision;not null"`
+	Text     string  `gorm:"type:text;not null"`
+}
+
+// TableName specifies the table name for TranscriptSegmentEntity
+func (TranscriptSegmentEntity) TableName() string {
+	return "transcript_segments"
+}
+
+// WebhookDeliveryStatus represents the state of a webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDeliveryEntity represents one webhook notification and its delivery attempts
+type WebhookDeliveryEntity struct {
+	DeliveryID     string                `gorm:"primaryKey;type:varchar(50)"`
+	JobID          string                `gorm:"type:varchar(50);not null;index"`
+	Event          string                `gorm:"type:varchar(50);not null"` // e.g., "job.succeeded"
+	URL            string                `gorm:"type:varchar(500);not null"`
+	Payload        string                `gorm:"type:text;not null"` // Exact JSON body that is signed and sent
+	Status         WebhookDeliveryStatus `gorm:"type:varchar(20);not null;index;default:'pending'"`
+	Attempts       int                   `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time             `gorm:"not null;index"`
+	LastStatusCode *int
+	LastError      *string   `gorm:"type:text"`
+	CreatedAt      time.Time `gorm:"not null;index"`
+	DeliveredAt    *time.Time
+}
+
+// TableName specifies the table name for WebhookDeliveryEntity
+func (WebhookDeliveryEntity) TableName() string {
+	return "webhook_deliveries"
+}
+
+// UploadStatus represents the state of a resumable upload
+type UploadStatus string
+
+const (
+	UploadStatusUploading UploadStatus = "uploading"
+	// UploadStatusAssembling means the chunks are being joined into one object
+	UploadStatusAssembling UploadStatus = "assembling"
+	UploadStatusCompleted  UploadStatus = "completed"
+)
+
+// UploadEntity represents a resumable audio upload
+type UploadEntity struct {
+	UploadID   string       `gorm:"primaryKey;type:varchar(50)"`
+	PersonID   *string      `gorm:"type:varchar(50);index"`
+	WebhookURL *string      `gorm:"type:varchar(500)"`
+	Length     int64        `gorm:"not null"`           // Total size declared by the client
+	Received   int64        `gorm:"not null;default:0"` // Bytes stored so far; the offset of the next chunk
+	Checksum   *string      `gorm:"type:varchar(80)"`   // Expected "sha256:<hex>" of the whole upload
+	Status     UploadStatus `gorm:"type:varchar(20);not null;default:'uploading'"`
+	JobID      *string      `gorm:"type:varchar(50)"` // Job created when the upload completed
+	CreatedAt  time.Time    `gorm:"not null"`
+	UpdatedAt  time.Time    `gorm:"not null"`
+	ExpiresAt  time.Time    `gorm:"not null;index"`
+}
+
+// TableName specifies the table name for UploadEntity
+func (UploadEntity) TableName() string {
+	return "uploads"
+}
+
+// UploadChunkEntity represents one stored chunk of a resumable upload
+type UploadChunkEntity struct {
+	UploadID    string `gorm:"primaryKey;type:varchar(50)"`
+	ChunkOffset int64  `gorm:"primaryKey;autoIncrement:false"` // Position of the chunk within the upload
+	Size        int64  `gorm:"not null"`
+	ChunkKey    string `gorm:"type:varchar(200);not null;index"` // Content-addressed key in blob storage
+}
+
+// TableName specifies the table name for UploadChunkEntity
+func (UploadChunkEntity) TableName() string {
+	return "upload_chunks"
+}
+
+// SuggestionCacheEntity holds the conversation suggestions last generated for a person
+type SuggestionCacheEntity struct {
+	PersonID string `gorm:"primaryKey;type:varchar(50)"`
+	// SourceKey identifies the encounters the suggestions were generated from;
+	// they are stale once it no longer matches
+	SourceKey   string    `gorm:"type:varchar(110);not null"`
+	Suggestions string    `gorm:"type:text;not null"` // JSON array of suggestions
+	GeneratedAt time.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for SuggestionCacheEntity
+func (SuggestionCacheEntity) TableName() string {
+	return "suggestion_cache"
+}
+
+// ProfileVersionEntity is one version of a person's long-term profile.
+// The version with the highest number is the current profile.
+type ProfileVersionEntity struct {
+	PersonID     string    `gorm:"primaryKey;type:varchar(50)"`
+	Version      int       `gorm:"primaryKey;autoIncrement:false"`
+	Document     string    `gorm:"type:text;not null"`     // JSON ProfileDocument
+	EncounterID  *string   `gorm:"type:varchar(50);index"` // Encounter merged into this version
+	RevertedFrom *int      // Version restored by a revert
+	CreatedAt    time.Time `gorm:"not null"`
+}
+
+// TableName specifies the table name for ProfileVersionEntity
+func (ProfileVersionEntity) TableName() string {
+	return "profile_versions"
+}
+
+// ProfileUpdateStatus represents the state of merging an encounter into a profile
+type ProfileUpdateStatus string
+
+const (
+	ProfileUpdatePending   ProfileUpdateStatus = "pending"
+	ProfileUpdateSucceeded ProfileUpdateStatus = "succeeded"
+	ProfileUpdateFailed    ProfileUpdateStatus = "failed"
+)
+
+// ProfileUpdateEntity queues the summary of an encounter for merging into the person's profile
+type ProfileUpdateEntity struct {
+	EncounterID   string              `gorm:"primaryKey;type:varchar(50)"`
+	PersonID      string              `gorm:"type:varchar(50);not null;index"`
+	Status        ProfileUpdateStatus `gorm:"type:varchar(20);not null;index;default:'pending'"`
+	Attempts      int                 `gorm:"not null;default:0"`
+	NextAttemptAt time.Time           `gorm:"not null;index"`
+	LastError     *string             `gorm:"type:text"`
+	CreatedAt     time.Time           `gorm:"not null"`
+	FinishedAt    *time.Time
+}
+
+// TableName specifies the table name for ProfileUpdateEntity
+func (ProfileUpdateEntity) TableName() string {
+	return "profile_updates"
+}
@@ -70,6 +70,16 @@ func (r *PersonRepository) Create(person *PersonEntity) error {
 	return r.db.Create(person).Error
 }
 
+// CreateWithFace creates a person and its first face in one transaction
+func (r *PersonRepository) CreateWithFace(person *PersonEntity, face *FaceEntity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(person).Error; err != nil {
+			return err
+		}
+		return tx.Create(face).Error
+	})
+}
+
 // Update updates a person
 func (r *PersonRepository) Update(person *PersonEntity) error {
 	return r.db.Save(person).Error
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrProfileConflict is returned when a profile version could not be stored
+// because another version was stored concurrently
+var ErrProfileConflict = errors.New("profile was updated concurrently")
+
+// ProfileRepository handles person profile versions and the queue of
+// encounters waiting to be merged into them
+type ProfileRepository struct {
+	db *gorm.DB
+}
+
+// NewProfileRepository creates a new ProfileRepository.
+// Pass a transaction to write profiles atomically with other changes.
+func NewProfileRepository(db *gorm.DB) *ProfileRepository {
+	return &ProfileRepository{db: db}
+}
+
+// FindLatest retrieves the current profile version of a person
+func (r *ProfileRepository) FindLatest(personID string) (*ProfileVersionEntity, error) {
+	var version ProfileVersionEntity
+	if err := r.db.Where("person_id = ?", personID).Order("version DESC").First(&version).Error; err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// FindVersion retrieves a profile version of a person
+func (r *ProfileRepository) FindVersion(personID string, version int) (*ProfileVersionEntity, error) {
+	var entity ProfileVersionEntity
+	if err := r.db.First(&entity, "person_id = ? AND version = ?", personID, version).Error; err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// FindVersions retrieves the profile versions of a person, newest first, with pagination
+func (r *ProfileRepository) FindVersions(personID string, limit int, cursor *string) ([]ProfileVersionEntity, *string, error) {
+	query := r.db.Where("person_id = ?", personID)
+	if cursor != nil && *cursor != "" {
+		before, err := strconv.Atoi(*cursor)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid cursor: %w", err)
+		}
+		query = query.Where("version < ?", before)
+	}
+
+	var versions []ProfileVersionEntity
+	if err := query.Order("version DESC").Limit(limit + 1).Find(&versions).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var nextCursor *string
+	if len(versions) > limit {
+		next := strconv.Itoa(versions[limit-1].Version)
+		nextCursor = &next
+		versions = versions[:limit]
+	}
+	return versions, nextCursor, nil
+}
+
+// AppendVersion stores version as the next version of its person's profile.
+// The number is allocated in a transaction from the latest version number,
+// which check may reject first; it is 0 when there is no profile yet. If a
+// concurrent append takes the same number, ErrProfileConflict is returned.
+func (r *ProfileRepository) AppendVersion(version *ProfileVersionEntity, check func(latest int) error) error {
+	return r.appendVersion(version, check, nil)
+}
+
+// CommitUpdate stores the profile version produced by merging a queued
+// encounter into version base and marks the update succeeded, atomically.
+// It fails with ErrProfileConflict when base is no longer the latest version,
+// as the merge would then discard what was stored meanwhile.
+func (r *ProfileRepository) CommitUpdate(version *ProfileVersionEntity, base int, finishedAt time.Time) error {
+	check := func(latest int) error {
+		if latest != base {
+			return ErrProfileConflict
+		}
+		return nil
+	}
+	return r.appendVersion(version, check, func(tx *gorm.DB) error {
+		return tx.Model(&ProfileUpdateEntity{}).
+			Where("encounter_id = ?", *version.EncounterID).
+			Updates(map[string]interface{}{
+				"status":      ProfileUpdateSucceeded,
+				"last_error":  nil,
+				"finished_at": finishedAt,
+			}).Error
+	})
+}
+
+func (r *ProfileRepository) appendVersion(version *ProfileVersionEntity, check func(latest int) error, onCreate func(tx *gorm.DB) error) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		err := tx.Model(&ProfileVersionEntity{}).
+			Where("person_id = ?", version.PersonID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error
+		if err != nil {
+			return err
+		}
+		if err := check(latest); err != nil {
+			return err
+		}
+		version.Version = latest + 1
+		if err := tx.Create(version).Error; err != nil {
+			return err
+		}
+		if onCreate != nil {
+			return onCreate(tx)
+		}
+		return nil
+	})
+	if err != nil && version.Version > 0 {
+		// A failed insert aborts the transaction, so the number is checked afterwards
+		var taken int64
+		if r.db.Model(&ProfileVersionEntity{}).
+			Where("person_id = ? AND version = ?", version.PersonID, version.Version).
+			Count(&taken).Error == nil && taken > 0 {
+			return ErrProfileConflict
+		}
+	}
+	return err
+}
+
+// EnqueueUpdate queues an encounter for merging into its person's profile,
+// replacing an earlier update of the same encounter
+func (r *ProfileRepository) EnqueueUpdate(update *ProfileUpdateEntity) error {
+	return r.db.Save(update).Error
+}
+
+// EnqueueMissing queues every summarized encounter that has never been
+// queued, such as those summarized before profiles existed. It returns how
+// many were queued.
+func (r *ProfileRepository) EnqueueMissing(now time.Time) (int, error) {
+	var encounters []EncounterEntity
+	err := r.db.Select("encounter_id", "person_id").
+		Where("summary IS NOT NULL AND NOT EXISTS (?)",
+			r.db.Model(&ProfileUpdateEntity{}).Select("1").Where("profile_updates.encounter_id = encounters.encounter_id")).
+		Order("recognized_at ASC").
+		Find(&encounters).Error
+	if err != nil || len(encounters) == 0 {
+		return 0, err
+	}
+
+	updates := make([]ProfileUpdateEntity, len(encounters))
+	for i, encounter := range encounters {
+		updates[i] = ProfileUpdateEntity{
+			EncounterID: encounter.EncounterID,
+			PersonID:    encounter.PersonID,
+			Status:      ProfileUpdatePending,
+			// Spread the due times so older encounters are merged first
+			NextAttemptAt: now.Add(time.Duration(i) * time.Microsecond),
+			CreatedAt:     now,
+		}
+	}
+	if err := r.db.CreateInBatches(updates, 200).Error; err != nil {
+		return 0, err
+	}
+	return len(updates), nil
+}
+
+// ClaimNextUpdate reserves the pending update that has been due the longest
+// by moving its next attempt to claimUntil, so other updaters skip it while
+// it is being merged. It returns nil when nothing is due.
+func (r *ProfileRepository) ClaimNextUpdate(now, claimUntil time.Time) (*ProfileUpdateEntity, error) {
+	var candidates []ProfileUpdateEntity
+	err := r.db.Select("encounter_id").
+		Where("status = ? AND next_attempt_at <= ?", ProfileUpdatePending, now).
+		Order("next_attempt_at ASC").
+		Limit(5).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range candidates {
+		result := r.db.Model(&ProfileUpdateEntity{}).
+			Where("encounter_id = ? AND status = ? AND next_attempt_at <= ?", candidate.EncounterID, ProfileUpdatePending, now).
+			Updates(map[string]interface{}{
+				"next_attempt_at": claimUntil,
+				"attempts":        gorm.Expr("attempts + 1"),
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			var update ProfileUpdateEntity
+			if err := r.db.First(&update, "encounter_id = ?", candidate.EncounterID).Error; err != nil {
+				return nil, err
+			}
+			return &update, nil
+		}
+	}
+	return nil, nil
+}
+
+// UpdateUpdate applies updates to a queued update
+func (r *ProfileRepository) UpdateUpdate(encounterID string, updates map[string]interface{}) error {
+	return r.db.Model(&ProfileUpdateEntity{}).Where("encounter_id = ?", encounterID).Updates(updates).Error
+}
+
+// CountPendingUpdates counts the encounters of a person waiting to be merged
+func (r *ProfileRepository) CountPendingUpdates(personID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&ProfileUpdateEntity{}).
+		Where("person_id = ? AND status = ?", personID, ProfileUpdatePending).
+		Count(&count).Error
+	return count, err
+}
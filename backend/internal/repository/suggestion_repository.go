@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+)
+
+// SuggestionRepository handles cached conversation suggestions
+type SuggestionRepository struct {
+	db *gorm.DB
+}
+
+// NewSuggestionRepository creates a new SuggestionRepository
+func NewSuggestionRepository(db *gorm.DB) *SuggestionRepository {
+	return &SuggestionRepository{db: db}
+}
+
+// FindByPersonID retrieves the cached suggestions of a person
+func (r *SuggestionRepository) FindByPersonID(personID string) (*SuggestionCacheEntity, error) {
+	var cache SuggestionCacheEntity
+	if err := r.db.First(&cache, "person_id = ?", personID).Error; err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// Save stores the suggestions of a person, replacing any cached before
+func (r *SuggestionRepository) Save(cache *SuggestionCacheEntity) error {
+	return r.db.Save(cache).Error
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+)
+
+// TranscriptRepository handles transcript segment data access
+type TranscriptRepository struct {
+	db *gorm.DB
+}
+
+// NewTranscriptRepository creates a new TranscriptRepository
+func NewTranscriptRepository(db *gorm.DB) *TranscriptRepository {
+	return &TranscriptRepository{db: db}
+}
+
+// ReplaceSegments stores the segments of a job in order, replacing those of
+// an earlier attempt
+func (r *TranscriptRepository) ReplaceSegments(jobID string, segments []TranscriptSegmentEntity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&TranscriptSegmentEntity{}, "job_id = ?", jobID).Error; err != nil {
+			return err
+		}
+		if len(segments) == 0 {
+			return nil
+		}
+		for i := range segments {
+			segments[i].JobID = jobID
+			segments[i].Seq = i
+		}
+		return tx.CreateInBatches(segments, 200).Error
+	})
+}
+
+// FindSegments retrieves the segments of a job in order
+func (r *TranscriptRepository) FindSegments(jobID string) ([]TranscriptSegmentEntity, error) {
+	var segments []TranscriptSegmentEntity
+	if err := r.db.Where("job_id = ?", jobID).Order("seq ASC").Find(&segments).Error; err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
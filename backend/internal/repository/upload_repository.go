@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrUploadConflict is returned when an upload changed since it was read, such
+// as a chunk written at an offset another request already filled
+var ErrUploadConflict = errors.New("upload was modified concurrently")
+
+// UploadRepository handles resumable upload data access
+type UploadRepository struct {
+	db *gorm.DB
+}
+
+// NewUploadRepository creates a new UploadRepository
+func NewUploadRepository(db *gorm.DB) *UploadRepository {
+	return &UploadRepository{db: db}
+}
+
+// Create creates a new upload
+func (r *UploadRepository) Create(upload *UploadEntity) error {
+	return r.db.Create(upload).Error
+}
+
+// FindByID retrieves an upload by ID
+func (r *UploadRepository) FindByID(uploadID string) (*UploadEntity, error) {
+	var upload UploadEntity
+	if err := r.db.First(&upload, "upload_id = ?", uploadID).Error; err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// AppendChunk records a stored chunk at the end of an upload. It fails with
+// ErrUploadConflict unless the upload is still uploading, has received exactly
+// chunk.ChunkOffset bytes and has room for the chunk.
+func (r *UploadRepository) AppendChunk(chunk *UploadChunkEntity, expiresAt time.Time) (*UploadEntity, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&UploadEntity{}).
+			Where("upload_id = ? AND status = ? AND received = ? AND received + ? <= length",
+				chunk.UploadID, UploadStatusUploading, chunk.ChunkOffset, chunk.Size).
+			Updates(map[string]interface{}{
+				"received":   gorm.Expr("received + ?", chunk.Size),
+				"updated_at": time.Now(),
+				"expires_at": expiresAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrUploadConflict
+		}
+		return tx.Create(chunk).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.FindByID(chunk.UploadID)
+}
+
+// FindChunks retrieves the chunks of an upload in order
+func (r *UploadRepository) FindChunks(uploadID string) ([]UploadChunkEntity, error) {
+	var chunks []UploadChunkEntity
+	err := r.db.Where("upload_id = ?", uploadID).
+		Order("chunk_offset ASC").
+		Find(&chunks).Error
+	return chunks, err
+}
+
+// Transition moves an upload from one status to another and applies updates.
+// It fails with ErrUploadConflict when the upload is not in status from.
+func (r *UploadRepository) Transition(uploadID string, from, to UploadStatus, updates map[string]interface{}) error {
+	values := map[string]interface{}{
+		"status":     to,
+		"updated_at": time.Now(),
+	}
+	for column, value := range updates {
+		values[column] = value
+	}
+	result := r.db.Model(&UploadEntity{}).
+		Where("upload_id = ? AND status = ?", uploadID, from).
+		Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUploadConflict
+	}
+	return nil
+}
+
+// AttachJob records the job created for an assembling upload, using tx so
+// that it is written together with the job. It fails with ErrUploadConflict
+// when the upload is no longer assembling or already has a job.
+func (r *UploadRepository) AttachJob(tx *gorm.DB, uploadID, jobID string) error {
+	result := tx.Model(&UploadEntity{}).
+		Where("upload_id = ? AND status = ? AND job_id IS NULL", uploadID, UploadStatusAssembling).
+		Updates(map[string]interface{}{
+			"job_id":     jobID,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUploadConflict
+	}
+	return nil
+}
+
+// ReclaimAssembling takes over an upload whose completion stalled: one still
+// assembling without a job since before staleBefore, as left by a crash. The
+// completion starts anew from now. It fails with ErrUploadConflict when the
+// upload is not stalled.
+func (r *UploadRepository) ReclaimAssembling(uploadID string, staleBefore time.Time, updates map[string]interface{}) error {
+	values := map[string]interface{}{
+		"updated_at": time.Now(),
+	}
+	for column, value := range updates {
+		values[column] = value
+	}
+	result := r.db.Model(&UploadEntity{}).
+		Where("upload_id = ? AND status = ? AND job_id IS NULL AND updated_at < ?", uploadID, UploadStatusAssembling, staleBefore).
+		Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUploadConflict
+	}
+	return nil
+}
+
+// DeleteChunks removes the chunk records of an upload and returns their keys
+func (r *UploadRepository) DeleteChunks(uploadID string) ([]string, error) {
+	var keys []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return deleteChunks(tx, uploadID, &keys)
+	})
+	return keys, err
+}
+
+// Delete removes an upload with its chunk records and returns the chunk keys
+func (r *UploadRepository) Delete(uploadID string) ([]string, error) {
+	var keys []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteChunks(tx, uploadID, &keys); err != nil {
+			return err
+		}
+		return tx.Delete(&UploadEntity{}, "upload_id = ?", uploadID).Error
+	})
+	return keys, err
+}
+
+func deleteChunks(tx *gorm.DB, uploadID string, keys *[]string) error {
+	if err := tx.Model(&UploadChunkEntity{}).Where("upload_id = ?", uploadID).Pluck("chunk_key", keys).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&UploadChunkEntity{}, "upload_id = ?", uploadID).Error
+}
+
+// FindExpired retrieves uploads that expired before now, oldest first
+func (r *UploadRepository) FindExpired(now time.Time, limit int) ([]UploadEntity, error) {
+	var uploads []UploadEntity
+	err := r.db.Where("expires_at < ?", now).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&uploads).Error
+	return uploads, err
+}
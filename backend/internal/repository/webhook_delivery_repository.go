@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookDeliveryRepository handles webhook delivery data access
+type WebhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookDeliveryRepository creates a new WebhookDeliveryRepository.
+// Pass a transaction to write deliveries atomically with other changes.
+func NewWebhookDeliveryRepository(db *gorm.DB) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{db: db}
+}
+
+// Create creates a new delivery
+func (r *WebhookDeliveryRepository) Create(delivery *WebhookDeliveryEntity) error {
+	return r.db.Create(delivery).Error
+}
+
+// FindByID retrieves a delivery by ID
+func (r *WebhookDeliveryRepository) FindByID(deliveryID string) (*WebhookDeliveryEntity, error) {
+	var delivery WebhookDeliveryEntity
+	if err := r.db.First(&delivery, "delivery_id = ?", deliveryID).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// FindByJobID retrieves the deliveries of a job, oldest first
+func (r *WebhookDeliveryRepository) FindByJobID(jobID string) ([]WebhookDeliveryEntity, error) {
+	var deliveries []WebhookDeliveryEntity
+	err := r.db.Where("job_id = ?", jobID).
+		Order("created_at ASC").
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimNext reserves the pending delivery that has been due the longest by
+// moving its next attempt to claimUntil, so other dispatchers skip it while it
+// is being sent. It returns nil when nothing is due.
+func (r *WebhookDeliveryRepository) ClaimNext(now, claimUntil time.Time) (*WebhookDeliveryEntity, error) {
+	var candidates []WebhookDeliveryEntity
+	err := r.db.Select("delivery_id").
+		Where("status = ? AND next_attempt_at <= ?", WebhookDeliveryPending, now).
+		Order("next_attempt_at ASC").
+		Limit(5).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range candidates {
+		result := r.db.Model(&WebhookDeliveryEntity{}).
+			Where("delivery_id = ? AND status = ? AND next_attempt_at <= ?", candidate.DeliveryID, WebhookDeliveryPending, now).
+			Updates(map[string]interface{}{
+				"next_attempt_at": claimUntil,
+				"attempts":        gorm.Expr("attempts + 1"),
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return r.FindByID(candidate.DeliveryID)
+		}
+	}
+	return nil, nil
+}
+
+// Update applies updates to a delivery
+func (r *WebhookDeliveryRepository) Update(deliveryID string, updates map[string]interface{}) error {
+	return r.db.Model(&WebhookDeliveryEntity{}).Where("delivery_id = ?", deliveryID).Updates(updates).Error
+}
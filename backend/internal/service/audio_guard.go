@@ -0,0 +1,156 @@
+package service
+
+import (
+	"errors"
+	"sync"
+)
+
+// errAudioRemoved is returned when stored audio was deleted before the row
+// referencing it could be written. Storing it again succeeds.
+var errAudioRemoved = errors.New("audio was deleted while it was being stored")
+
+// audioGuard keeps unreferenced audio from being deleted while it is about to
+// be referenced again. Storage deduplicates identical content, so a Put can
+// return an existing object just as its last job is deleted; the new job must
+// not end up pointing at a deleted object.
+//
+// Only the window between Put returning a key and the row that references it
+// being written is protected, and only within this process. Objects orphaned
+// by a crash or by a race with another instance are left to the audio sweep.
+type audioGuard struct {
+	// remove deletes key if nothing references it and reports whether it did.
+	// It runs without mu held.
+	remove func(key string) bool
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	seq  uint64
+	// stores are the stores in progress
+	stores map[*audioStoring]struct{}
+	// holds counts the stores that wait for a row referencing each key
+	holds map[string]int
+	// pending are held keys that were released meanwhile
+	pending  map[string]bool
+	deleting map[string]bool
+	// removed records when keys were deleted while stores were in progress
+	removed map[string]uint64
+}
+
+// audioStoring is one store, from before its Put until its object is referenced
+type audioStoring struct {
+	guard *audioGuard
+	seq   uint64
+	key   string
+	ended bool
+}
+
+func newAudioGuard(remove func(key string) bool) *audioGuard {
+	g := &audioGuard{
+		remove:   remove,
+		stores:   make(map[*audioStoring]struct{}),
+		holds:    make(map[string]int),
+		pending:  make(map[string]bool),
+		deleting: make(map[string]bool),
+		removed:  make(map[string]uint64),
+	}
+	g.cond = sync.NewCond(&g.mu)
+	return g
+}
+
+// begin is called before Put. It does not delay deletions.
+func (g *audioGuard) begin() *audioStoring {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.seq++
+	st := &audioStoring{guard: g, seq: g.seq}
+	g.stores[st] = struct{}{}
+	return st
+}
+
+// hold is called once Put has returned key and keeps key from being deleted
+// until end. It fails with errAudioRemoved if key was deleted since begin, as
+// Put may have found the object just before.
+func (st *audioStoring) hold(key string) error {
+	g := st.guard
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for g.deleting[key] {
+		g.cond.Wait()
+	}
+	if seq, ok := g.removed[key]; ok && seq > st.seq {
+		return errAudioRemoved
+	}
+	g.holds[key]++
+	st.key = key
+	return nil
+}
+
+// end is called once the held object is referenced from the database, or
+// given up on. A release that came in meanwhile is carried out now.
+func (st *audioStoring) end() {
+	g := st.guard
+	g.mu.Lock()
+	if st.ended {
+		g.mu.Unlock()
+		return
+	}
+	st.ended = true
+	delete(g.stores, st)
+
+	release := false
+	if st.key != "" {
+		g.holds[st.key]--
+		if g.holds[st.key] == 0 {
+			delete(g.holds, st.key)
+			release = g.pending[st.key]
+			delete(g.pending, st.key)
+		}
+	}
+
+	// Removals before the oldest store began cannot affect any store
+	oldest := g.seq + 1
+	for other := range g.stores {
+		if other.seq < oldest {
+			oldest = other.seq
+		}
+	}
+	for key, seq := range g.removed {
+		if seq < oldest {
+			delete(g.removed, key)
+		}
+	}
+	g.mu.Unlock()
+
+	if release {
+		g.release(st.key)
+	}
+}
+
+// release deletes key if nothing references it. A held key is deleted once
+// its last hold ends instead. It reports whether key was deleted.
+func (g *audioGuard) release(key string) bool {
+	g.mu.Lock()
+	if g.holds[key] > 0 {
+		g.pending[key] = true
+		g.mu.Unlock()
+		return false
+	}
+	if g.deleting[key] {
+		g.mu.Unlock()
+		return false
+	}
+	g.deleting[key] = true
+	g.mu.Unlock()
+
+	removed := g.remove(key)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.deleting, key)
+	if removed && len(g.stores) > 0 {
+		g.seq++
+		g.removed[key] = g.seq
+	}
+	g.cond.Broadcast()
+	return removed
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudioGuard_DefersReleaseOfHeldKey(t *testing.T) {
+	var removed []string
+	g := newAudioGuard(func(key string) bool {
+		removed = append(removed, key)
+		return true
+	})
+
+	// A slow store of other audio does not hold up deletions
+	slow := g.begin()
+	assert.True(t, g.release("a"))
+	assert.Equal(t, []string{"a"}, removed)
+
+	st := g.begin()
+	require.NoError(t, st.hold("b"))
+	assert.False(t, g.release("b"))
+	assert.Equal(t, []string{"a"}, removed)
+
+	st.end()
+	assert.Equal(t, []string{"a", "b"}, removed)
+	st.end()
+	assert.Equal(t, []string{"a", "b"}, removed)
+	slow.end()
+}
+
+func TestAudioGuard_DetectsRemovalDuringPut(t *testing.T) {
+	g := newAudioGuard(func(key string) bool { return true })
+
+	// Put found "a" just before it was deleted
+	st := g.begin()
+	assert.True(t, g.release("a"))
+	assert.ErrorIs(t, st.hold("a"), errAudioRemoved)
+	st.end()
+
+	// A store that begins afterwards writes the object anew
+	again := g.begin()
+	require.NoError(t, again.hold("a"))
+	again.end()
+	assert.Empty(t, g.removed)
+	assert.Empty(t, g.holds)
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// AudioSweeper periodically deletes stored audio that nothing references.
+// Audio is normally deleted together with its job or upload; the sweep
+// catches objects left behind by a crash or by concurrent deletions on
+// several server instances.
+type AudioSweeper struct {
+	jobService *JobService
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewAudioSweeper creates an AudioSweeper. Call Start to begin sweeping.
+func NewAudioSweeper(jobService *JobService) *AudioSweeper {
+	return &AudioSweeper{jobService: jobService}
+}
+
+// Start launches the sweeper goroutine. A non-positive sweep interval disables it.
+func (a *AudioSweeper) Start(ctx context.Context) {
+	if a.jobService.config.SweepInterval <= 0 {
+		log.Println("Audio sweep is disabled")
+		return
+	}
+	ctx, a.cancel = context.WithCancel(ctx)
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.loop(ctx)
+	}()
+	log.Printf("Audio sweeper started, keeping unreferenced audio for %v", a.jobService.config.SweepGrace)
+}
+
+// Stop cancels the sweep in progress and waits for the goroutine to exit
+func (a *AudioSweeper) Stop() {
+	if a.cancel == nil {
+		return
+	}
+	a.cancel()
+	a.wg.Wait()
+}
+
+func (a *AudioSweeper) loop(ctx context.Context) {
+	ticker := time.NewTicker(a.jobService.config.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		deleted, err := a.jobService.SweepAudio(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Warning: audio sweep: %v", err)
+		}
+		if deleted > 0 {
+			log.Printf("Audio sweep deleted %d unreferenced object(s)", deleted)
+		}
+	}
+}
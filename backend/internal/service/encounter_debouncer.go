@@ -0,0 +1,65 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// encounterDebouncer decides whether a recognition should be logged as a new encounter.
+// It remembers the last encounter time per person so that continuous recognition
+// (e.g. 30 fps from the camera) creates at most one encounter per window.
+type encounterDebouncer struct {
+	encounterRepo *repository.EncounterRepository
+	window        time.Duration
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newEncounterDebouncer(encounterRepo *repository.EncounterRepository, window time.Duration) *encounterDebouncer {
+	return &encounterDebouncer{
+		encounterRepo: encounterRepo,
+		window:        window,
+		last:          make(map[string]time.Time),
+	}
+}
+
+// reserve reports whether an encounter may be created for the person at the given time.
+// When it returns true the slot is taken; call release if the encounter could not be stored.
+func (d *encounterDebouncer) reserve(personID string, at time.Time) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	last, ok := d.last[personID]
+	if !ok {
+		// Seed from the database so that restarts do not reset the window
+		latest, err := d.encounterRepo.FindLatestByPersonID(personID)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return false, err
+		}
+		if latest != nil {
+			last = latest.RecognizedAt
+		}
+	}
+
+	if !last.IsZero() && at.Sub(last) < d.window {
+		d.last[personID] = last
+		return false, nil
+	}
+
+	d.last[personID] = at
+	return true, nil
+}
+
+// release restores the previous state after a failed insert
+func (d *encounterDebouncer) release(personID string, at time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.last[personID].Equal(at) {
+		delete(d.last, personID)
+	}
+}
@@ -1,24 +1,49 @@
 package service
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jphacks/os_2522/backend/internal/models"
 	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/utils"
 	"gorm.io/gorm"
 )
 
+// EncounterConfig holds encounter parameters
+type EncounterConfig struct {
+	// SummaryMatchWindow is how long before a recording was uploaded an
+	// encounter may have been recognized for the recording's summary to be attached to it
+	SummaryMatchWindow time.Duration
+}
+
+// GetEncounterConfigFromEnv reads encounter configuration from environment variables
+func GetEncounterConfigFromEnv() *EncounterConfig {
+	config := &EncounterConfig{
+		SummaryMatchWindow: utils.GetEnvDuration("ENCOUNTER_SUMMARY_MATCH_WINDOW", 3*time.Hour),
+	}
+	if config.SummaryMatchWindow < 0 {
+		config.SummaryMatchWindow = 0
+	}
+	return config
+}
+
 // EncounterService handles encounter business logic
 type EncounterService struct {
 	encounterRepo *repository.EncounterRepository
 	personRepo    *repository.PersonRepository
+	config        *EncounterConfig
 }
 
 // NewEncounterService creates a new EncounterService
-func NewEncounterService(encounterRepo *repository.EncounterRepository, personRepo *repository.PersonRepository) *EncounterService {
+func NewEncounterService(encounterRepo *repository.EncounterRepository, personRepo *repository.PersonRepository, config *EncounterConfig) *EncounterService {
 	return &EncounterService{
 		encounterRepo: encounterRepo,
 		personRepo:    personRepo,
+		config:        config,
 	}
 }
 
@@ -47,6 +72,13 @@ func (s *EncounterService) ListEncounters(personID string, limit int, cursor *st
 			Score:        entity.Score,
 			Summary:      entity.Summary,
 		}
+		if entity.SummaryDetails != nil {
+			var details models.ConversationSummary
+			if err := json.Unmarshal([]byte(*entity.SummaryDetails), &details); err != nil {
+				return nil, fmt.Errorf("failed to decode summary details: %w", err)
+			}
+			encounters[i].SummaryDetails = &details
+		}
 	}
 
 	return &models.EncounterList{
@@ -54,3 +86,68 @@ func (s *EncounterService) ListEncounters(personID string, limit int, cursor *st
 		NextCursor: nextCursor,
 	}, nil
 }
+
+// JobFinishing records the summary of a succeeded transcription job with a
+// person on that person's encounter log, inside the job's completion transaction.
+// The summary goes to the latest encounter recognized within the match window
+// before the upload that has no summary yet; without one a new encounter is
+// created at the upload time. The person's last_summary then follows the
+// latest summarized encounter, so a late retry of an older recording does not
+// replace a newer summary.
+func (s *EncounterService) JobFinishing(tx *gorm.DB, job *repository.JobEntity) error {
+	if job.Status != repository.JobStatusSucceeded || job.PersonID == nil || job.Summary == nil {
+		return nil
+	}
+	summary := strings.TrimSpace(*job.Summary)
+	if summary == "" {
+		return nil
+	}
+	personID := *job.PersonID
+
+	// The person may have been deleted while the job was queued
+	if _, err := repository.NewPersonRepository(tx).FindByID(personID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+
+	encounterRepo := repository.NewEncounterRepository(tx)
+	encounter, err := encounterRepo.FindLatestUnsummarized(personID, job.CreatedAt.Add(-s.config.SummaryMatchWindow), job.CreatedAt)
+	switch {
+	case err == nil:
+		if err := encounterRepo.UpdateSummary(encounter.EncounterID, summary, job.SummaryDetails); err != nil {
+			return err
+		}
+	case err == gorm.ErrRecordNotFound:
+		encounter = &repository.EncounterEntity{
+			EncounterID:    fmt.Sprintf("e-%s", uuid.New().String()[:8]),
+			PersonID:       personID,
+			RecognizedAt:   job.CreatedAt,
+			Summary:        &summary,
+			SummaryDetails: job.SummaryDetails,
+			CreatedAt:      time.Now(),
+		}
+		if err := encounterRepo.Create(encounter); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	if err := tx.Model(&repository.JobEntity{}).
+		Where("job_id = ?", job.JobID).
+		Update("encounter_id", encounter.EncounterID).Error; err != nil {
+		return err
+	}
+	job.EncounterID = &encounter.EncounterID
+
+	latest, err := encounterRepo.FindLatestSummarized(personID)
+	if err != nil {
+		return err
+	}
+	return encounterRepo.UpdateLastSummaryForPerson(personID, latest.Summary)
+}
+
+// JobFinished implements worker.CompletionHook
+func (s *EncounterService) JobFinished(job *repository.JobEntity) {}
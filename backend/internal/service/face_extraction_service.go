@@ -1,80 +1,114 @@
 package service
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
-	"os"
-	"os/exec"
+	"sort"
+
+	"github.com/jphacks/os_2522/backend/internal/extraction"
+	"github.com/jphacks/os_2522/backend/internal/models"
 )
 
 // FaceExtractionServiceInterface defines the interface for a service that extracts face embeddings from images.
 type FaceExtractionServiceInterface interface {
-	ExtractEmbedding(file *multipart.FileHeader) ([]float32, error)
+	ExtractEmbedding(ctx context.Context, file *multipart.FileHeader) ([]float32, error)
+	ExtractFaces(ctx context.Context, file *multipart.FileHeader) ([]models.DetectedFace, error)
+	ModelVersion() string
 }
 
-// FaceExtractionService runs a Python script to extract embeddings.
+// FaceExtractionService extracts embeddings through the configured extraction backend.
 type FaceExtractionService struct {
-	PythonPath string
-	ScriptPath string
+	extractor extraction.Extractor
 }
 
 // NewFaceExtractionService creates a new FaceExtractionService.
-func NewFaceExtractionService() *FaceExtractionService {
+func NewFaceExtractionService(extractor extraction.Extractor) *FaceExtractionService {
 	return &FaceExtractionService{
-		PythonPath: "backend/ml/.venv/bin/python",
-		ScriptPath: "backend/ml/extract_embedding.py",
+		extractor: extractor,
 	}
 }
 
-// ExtractEmbedding saves the uploaded image to a temporary file and runs the Python script to get the embedding.
-func (s *FaceExtractionService) ExtractEmbedding(fileHeader *multipart.FileHeader) ([]float32, error) {
-	if fileHeader == nil {
-		return nil, fmt.Errorf("image file is nil")
+// ModelVersion returns the registered embedding model of the extracted embeddings.
+func (s *FaceExtractionService) ModelVersion() string {
+	return s.extractor.ModelVersion()
+}
+
+// ExtractEmbedding returns the embedding of the most confident face in the uploaded image.
+func (s *FaceExtractionService) ExtractEmbedding(ctx context.Context, fileHeader *multipart.FileHeader) ([]float32, error) {
+	image, err := readUpload(fileHeader)
+	if err != nil {
+		return nil, err
 	}
+	return s.ExtractEmbeddingFromImage(ctx, image)
+}
 
-	// 1. Save the uploaded file to a temporary file
-	src, err := fileHeader.Open()
+// ExtractFaces detects every face in the uploaded image with its bounding box and embedding.
+func (s *FaceExtractionService) ExtractFaces(ctx context.Context, fileHeader *multipart.FileHeader) ([]models.DetectedFace, error) {
+	image, err := readUpload(fileHeader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+		return nil, err
 	}
-	defer src.Close()
+	return s.ExtractFacesFromImage(ctx, image)
+}
 
-	tempFile, err := os.CreateTemp("", "upload-*.jpg") // Assume jpg for simplicity, though script handles others
+// ExtractEmbeddingFromImage returns the embedding of the most confident face in an encoded image.
+func (s *FaceExtractionService) ExtractEmbeddingFromImage(ctx context.Context, image []byte) ([]float32, error) {
+	faces, err := s.ExtractFacesFromImage(ctx, image)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %w", err)
+		return nil, err
+	}
+	if len(faces) == 0 {
+		return nil, fmt.Errorf("no face detected")
 	}
-	defer os.Remove(tempFile.Name()) // Clean up the temp file
+	return faces[0].Embedding, nil
+}
 
-	_, err = io.Copy(tempFile, src)
+// ExtractFacesFromImage detects every face in an encoded image.
+// Faces are returned highest confidence first; an image without faces yields an empty slice.
+func (s *FaceExtractionService) ExtractFacesFromImage(ctx context.Context, image []byte) ([]models.DetectedFace, error) {
+	faces, err := s.extractor.Extract(ctx, image)
 	if err != nil {
-		return nil, fmt.Errorf("failed to save to temp file: %w", err)
+		return nil, err
 	}
-	tempFile.Close() // Close the file so the python script can open it
 
-	// 2. Execute the Python script
-	cmd := exec.Command(s.PythonPath, s.ScriptPath, tempFile.Name())
+	if faces == nil {
+		faces = []models.DetectedFace{}
+	}
+	sort.SliceStable(faces, func(i, j int) bool {
+		return faces[i].Confidence > faces[j].Confidence
+	})
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	return faces, nil
+}
 
-	err = cmd.Run()
-	if err != nil {
-		return nil, fmt.Errorf("python script execution failed: %w - Stderr: %s", err, stderr.String())
-	}
+// isExtractionUnavailable reports whether an extraction error is about the backend
+// rather than the image, so callers can tell "retry later" from "bad image".
+func isExtractionUnavailable(err error) bool {
+	return errors.Is(err, extraction.ErrQueueFull) ||
+		errors.Is(err, extraction.ErrTimeout) ||
+		errors.Is(err, extraction.ErrUnavailable) ||
+		errors.Is(err, extraction.ErrClosed) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
 
-	if stderr.Len() > 0 {
-		return nil, fmt.Errorf("python script error: %s", stderr.String())
+func readUpload(fileHeader *multipart.FileHeader) ([]byte, error) {
+	if fileHeader == nil {
+		return nil, fmt.Errorf("image file is nil")
 	}
 
-	// 3. Parse the JSON output from the script
-	var embedding []float32
-	if err := json.Unmarshal(stdout.Bytes(), &embedding); err != nil {
-		return nil, fmt.Errorf("failed to parse embedding from python script: %w", err)
+	src, err := fileHeader.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
 	}
+	defer src.Close()
 
-	return embedding, nil
+	image, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	return image, nil
 }
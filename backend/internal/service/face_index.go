@@ -0,0 +1,37 @@
+package service
+
+import (
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/utils"
+	"github.com/jphacks/os_2522/backend/internal/vectorindex"
+)
+
+// LoadFaceIndex builds the process-wide embedding index from every stored face,
+// partitioned by model family. FaceService and PersonService keep it in sync afterwards.
+func LoadFaceIndex(faceRepo *repository.FaceRepository, modelService *ModelService, config vectorindex.Config) (*vectorindex.Collection, error) {
+	faceEntities, err := faceRepo.FindAllEmbeddings()
+	if err != nil {
+		return nil, err
+	}
+
+	index := vectorindex.NewCollection(config)
+	for _, faceEntity := range faceEntities {
+		embedding := utils.BytesToFloat32Slice(faceEntity.Embedding)
+		if embedding == nil {
+			continue // Skip invalid embeddings
+		}
+
+		var modelVersion string
+		if faceEntity.ModelVersion != nil {
+			modelVersion = *faceEntity.ModelVersion
+		}
+
+		index.Add(modelService.FamilyOf(modelVersion), vectorindex.Entry{
+			FaceID:    faceEntity.FaceID,
+			PersonID:  faceEntity.PersonID,
+			Embedding: embedding,
+		})
+	}
+
+	return index, nil
+}
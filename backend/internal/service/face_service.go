@@ -8,20 +8,30 @@ import (
 	"github.com/jphacks/os_2522/backend/internal/models"
 	"github.com/jphacks/os_2522/backend/internal/repository"
 	"github.com/jphacks/os_2522/backend/internal/utils"
+	"github.com/jphacks/os_2522/backend/internal/vectorindex"
 	"gorm.io/gorm"
 )
 
 // FaceService handles face business logic
 type FaceService struct {
-	faceRepo   *repository.FaceRepository
-	personRepo *repository.PersonRepository
+	faceRepo     *repository.FaceRepository
+	personRepo   *repository.PersonRepository
+	faceIndex    *vectorindex.Collection
+	modelService *ModelService
 }
 
 // NewFaceService creates a new FaceService
-func NewFaceService(faceRepo *repository.FaceRepository, personRepo *repository.PersonRepository) *FaceService {
+func NewFaceService(
+	faceRepo *repository.FaceRepository,
+	personRepo *repository.PersonRepository,
+	faceIndex *vectorindex.Collection,
+	modelService *ModelService,
+) *FaceService {
 	return &FaceService{
-		faceRepo:   faceRepo,
-		personRepo: personRepo,
+		faceRepo:     faceRepo,
+		personRepo:   personRepo,
+		faceIndex:    faceIndex,
+		modelService: modelService,
 	}
 }
 
@@ -36,6 +46,12 @@ func (s *FaceService) AddFace(personID string, req *models.FaceEmbeddingRequest)
 		return nil, err
 	}
 
+	// Verify the embedding matches a registered model
+	model, err := s.modelService.ValidateEmbedding(req.ModelVersion, req.Embedding)
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate face ID
 	faceID := fmt.Sprintf("f-%s", uuid.New().String()[:8])
 
@@ -49,7 +65,7 @@ func (s *FaceService) AddFace(personID string, req *models.FaceEmbeddingRequest)
 		FaceID:            faceID,
 		PersonID:          personID,
 		Embedding:         embeddingBytes,
-		EmbeddingDim:      req.EmbeddingDim,
+		EmbeddingDim:      model.Dimension,
 		ModelVersion:      &req.ModelVersion,
 		EmbeddingChecksum: &checksum,
 		SourceImageHash:   req.SourceImageHash,
@@ -61,6 +77,12 @@ func (s *FaceService) AddFace(personID string, req *models.FaceEmbeddingRequest)
 		return nil, err
 	}
 
+	s.faceIndex.Add(model.Family, vectorindex.Entry{
+		FaceID:    entity.FaceID,
+		PersonID:  entity.PersonID,
+		Embedding: req.Embedding,
+	})
+
 	return &models.Face{
 		FaceID:            entity.FaceID,
 		PersonID:          entity.PersonID,
@@ -130,5 +152,10 @@ func (s *FaceService) DeleteFace(personID, faceID string) error {
 		return fmt.Errorf("face does not belong to this person")
 	}
 
-	return s.faceRepo.Delete(faceID)
+	if err := s.faceRepo.Delete(faceID); err != nil {
+		return err
+	}
+
+	s.faceIndex.Remove(faceID)
+	return nil
 }
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jphacks/os_2522/backend/internal/events"
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// JobEventService publishes job progress to the in-process event broker and
+// opens event streams for clients. It is a worker.CompletionHook and
+// worker.ProgressObserver, so it sees every stage a job goes through.
+type JobEventService struct {
+	jobRepo *repository.JobRepository
+	broker  *events.Broker
+}
+
+// NewJobEventService creates a new JobEventService
+func NewJobEventService(jobRepo *repository.JobRepository, broker *events.Broker) *JobEventService {
+	return &JobEventService{
+		jobRepo: jobRepo,
+		broker:  broker,
+	}
+}
+
+// Publish announces that a job reached a stage. Events for the done stage carry the full job.
+func (s *JobEventService) Publish(entity *repository.JobEntity, stage models.JobStage) {
+	if s == nil {
+		return
+	}
+	event := models.JobEvent{
+		JobID:    entity.JobID,
+		Status:   models.JobStatus(entity.Status),
+		Stage:    stage,
+		Attempts: entity.Attempts,
+	}
+	if stage == models.JobStageDone {
+		job, err := toJob(entity)
+		if err != nil {
+			log.Printf("Warning: failed to build event for job %s: %v", entity.JobID, err)
+		}
+		event.Job = job
+	}
+	s.broker.Publish(event)
+}
+
+// JobFinishing implements worker.CompletionHook
+func (s *JobEventService) JobFinishing(tx *gorm.DB, job *repository.JobEntity) error {
+	return nil
+}
+
+// JobFinished publishes the outcome once it has been committed
+func (s *JobEventService) JobFinished(job *repository.JobEntity) {
+	s.Publish(job, models.JobStageDone)
+}
+
+// JobProgress implements worker.ProgressObserver
+func (s *JobEventService) JobProgress(job *repository.JobEntity, stage models.JobStage) {
+	s.Publish(job, stage)
+}
+
+// Subscribe opens an event stream for a job. lastEventID is the Last-Event-ID
+// of a reconnecting client, or 0. Unless the stream can resume from it, the
+// first event describes the job's current state. The caller must Close the subscription.
+func (s *JobEventService) Subscribe(jobID string, lastEventID int64) (*events.Subscription, error) {
+	// Subscribe before reading the job so no change falls between the two
+	sub := s.broker.Subscribe(jobID, lastEventID)
+
+	entity, err := s.jobRepo.FindByID(jobID)
+	if err != nil {
+		sub.Close()
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("job not found")
+		}
+		return nil, err
+	}
+	if lastEventID > 0 && !sub.Missed {
+		return sub, nil
+	}
+
+	job, err := toJob(entity)
+	if err != nil {
+		sub.Close()
+		return nil, err
+	}
+	snapshot := models.JobEvent{
+		JobID:    entity.JobID,
+		Status:   job.Status,
+		Stage:    currentStage(entity, sub.Latest),
+		Attempts: entity.Attempts,
+		Time:     entity.CreatedAt,
+		Job:      job,
+	}
+	if sub.Latest != nil {
+		snapshot.ID = sub.Latest.ID
+		snapshot.Time = sub.Latest.Time
+	}
+	sub.Replay = []models.JobEvent{snapshot}
+	return sub, nil
+}
+
+// currentStage derives the stage of a job from its status and, for running
+// jobs, the latest event seen in this process
+func currentStage(entity *repository.JobEntity, latest *models.JobEvent) models.JobStage {
+	switch {
+	case entity.Status.IsTerminal():
+		return models.JobStageDone
+	case entity.Status == repository.JobStatusQueued:
+		return models.JobStageUploaded
+	case latest != nil && latest.Status == models.JobStatusRunning:
+		return latest.Stage
+	default:
+		return models.JobStageTranscribing
+	}
+}
@@ -1,56 +1,201 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"mime/multipart"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jphacks/os_2522/backend/internal/audio"
 	"github.com/jphacks/os_2522/backend/internal/models"
 	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/storage"
+	"github.com/jphacks/os_2522/backend/internal/utils"
 	"gorm.io/gorm"
 )
 
+// AudioConfig holds the limits for uploaded recordings
+type AudioConfig struct {
+	// MaxSize is the largest recording accepted, in bytes
+	MaxSize int64
+	// MaxDuration is the longest recording accepted
+	MaxDuration time.Duration
+	// SweepInterval is how often stored audio is checked for objects nothing references
+	SweepInterval time.Duration
+	// SweepGrace is how long an unreferenced object is kept after it was last
+	// stored. It must exceed the time between storing audio and queueing its job.
+	SweepGrace time.Duration
+}
+
+// GetAudioConfigFromEnv reads audio limits from environment variables
+func GetAudioConfigFromEnv() *AudioConfig {
+	return &AudioConfig{
+		MaxSize:       int64(utils.GetEnvInt("AUDIO_MAX_SIZE_MB", 500)) << 20,
+		MaxDuration:   utils.GetEnvDuration("AUDIO_MAX_DURATION", 4*time.Hour),
+		SweepInterval: utils.GetEnvDuration("AUDIO_SWEEP_INTERVAL", time.Hour),
+		SweepGrace:    utils.GetEnvDuration("AUDIO_SWEEP_GRACE", 24*time.Hour),
+	}
+}
+
+// JobNotifier is told when a job has been queued, so it can start without waiting for the next poll
+type JobNotifier interface {
+	Notify()
+}
+
 // JobService handles job business logic
 type JobService struct {
-	jobRepo *repository.JobRepository
+	jobRepo        *repository.JobRepository
+	transcriptRepo *repository.TranscriptRepository
+	audioStore     storage.Store
+	notifier       JobNotifier
+	webhooks       *WebhookService
+	events         *JobEventService
+	config         *AudioConfig
+	audio          *audioGuard
 }
 
-// NewJobService creates a new JobService
-func NewJobService(jobRepo *repository.JobRepository) *JobService {
-	return &JobService{
-		jobRepo: jobRepo,
+// NewJobService creates a new JobService. notifier may be nil when no worker runs in this process.
+func NewJobService(jobRepo *repository.JobRepository, transcriptRepo *repository.TranscriptRepository, audioStore storage.Store, notifier JobNotifier, webhooks *WebhookService, events *JobEventService, config *AudioConfig) *JobService {
+	s := &JobService{
+		jobRepo:        jobRepo,
+		transcriptRepo: transcriptRepo,
+		audioStore:     audioStore,
+		notifier:       notifier,
+		webhooks:       webhooks,
+		events:         events,
+		config:         config,
 	}
+	s.audio = newAudioGuard(s.deleteUnreferencedAudio)
+	return s
 }
 
-// CreateTranscriptionJob creates a new transcription job
-func (s *JobService) CreateTranscriptionJob(personID *string, file *multipart.FileHeader, webhookURL *string) (*models.Job, error) {
-	// Generate job ID
-	jobID := fmt.Sprintf("j-%s", uuid.New().String()[:8])
+// CreateTranscriptionJob stores the uploaded audio and queues a transcription job
+func (s *JobService) CreateTranscriptionJob(ctx context.Context, personID *string, file *multipart.FileHeader, webhookURL *string) (*models.Job, error) {
+	if err := s.validateWebhookURL(ctx, webhookURL); err != nil {
+		return nil, err
+	}
+	if file.Size > s.config.MaxSize {
+		return nil, fmt.Errorf("audio is too large")
+	}
 
-	// TODO: Save audio file to storage and get path
-	audioPath := fmt.Sprintf("/uploads/audio/%s", file.Filename)
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer src.Close()
 
-	entity := &repository.JobEntity{
-		JobID:      jobID,
-		PersonID:   personID,
-		Status:     repository.JobStatusQueued,
-		AudioPath:  &audioPath,
-		WebhookURL: webhookURL,
-		CreatedAt:  time.Now(),
+	info, err := s.probeAudio(src)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
 	}
 
-	if err := s.jobRepo.Create(entity); err != nil {
+	object, end, err := s.putAudio(ctx, src)
+	if errors.Is(err, errAudioRemoved) {
+		// The upload is still at hand, so it is simply stored again
+		if _, err = src.Seek(0, io.SeekStart); err == nil {
+			object, end, err = s.putAudio(ctx, src)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store audio: %w", err)
+	}
+	defer end()
+
+	job, err := s.queueJob(personID, webhookURL, object, info, nil)
+	if err != nil {
+		s.releaseAudio(object.Key)
 		return nil, err
 	}
+	return job, nil
+}
+
+// putAudio stores audio and keeps it from being deleted until end is called,
+// which must happen once a row references the object or it is given up on
+func (s *JobService) putAudio(ctx context.Context, r io.Reader) (object *storage.Object, end func(), err error) {
+	st := s.audio.begin()
+	object, err = s.audioStore.Put(ctx, r)
+	if err == nil {
+		err = st.hold(object.Key)
+	}
+	if err != nil {
+		st.end()
+		return nil, nil, err
+	}
+	return object, st.end, nil
+}
 
-	// TODO: Enqueue job for async processing
+// validateWebhookURL checks an optional webhook URL
+func (s *JobService) validateWebhookURL(ctx context.Context, webhookURL *string) error {
+	if webhookURL == nil {
+		return nil
+	}
+	if err := s.webhooks.ValidateTarget(ctx, *webhookURL); err != nil {
+		return fmt.Errorf("invalid webhook_url: %w", err)
+	}
+	return nil
+}
 
-	return &models.Job{
-		JobID:     entity.JobID,
-		Status:    models.JobStatus(entity.Status),
-		CreatedAt: entity.CreatedAt,
-	}, nil
+// probeAudio identifies a recording and checks it against the duration limit
+func (s *JobService) probeAudio(r io.Reader) (*audio.Info, error) {
+	info, err := audio.Probe(r)
+	switch {
+	case errors.Is(err, audio.ErrUnsupported):
+		return nil, fmt.Errorf("unsupported audio: expected WAV, FLAC, Ogg (Opus or Vorbis) or MP3")
+	case errors.Is(err, audio.ErrCorrupt):
+		return nil, fmt.Errorf("unsupported audio: %w", err)
+	case err != nil:
+		return nil, fmt.Errorf("failed to read audio: %w", err)
+	}
+	if info.Duration > s.config.MaxDuration {
+		return nil, fmt.Errorf("audio is too long")
+	}
+	return info, nil
+}
+
+// queueJob creates a transcription job for audio that is already stored.
+// When onQueue is set it runs in the transaction that creates the job, and an
+// error from it is returned without queueing the job.
+func (s *JobService) queueJob(personID, webhookURL *string, object *storage.Object, info *audio.Info, onQueue func(tx *gorm.DB, jobID string) error) (*models.Job, error) {
+	durationSec := info.Duration.Seconds()
+	entity := &repository.JobEntity{
+		JobID:       fmt.Sprintf("j-%s", uuid.New().String()[:8]),
+		PersonID:    personID,
+		Status:      repository.JobStatusQueued,
+		AudioKey:    &object.Key,
+		AudioFormat: &info.Format,
+		AudioCodec:  &info.Codec,
+		SampleRate:  &info.SampleRate,
+		Channels:    &info.Channels,
+		DurationSec: &durationSec,
+		WebhookURL:  webhookURL,
+		CreatedAt:   time.Now(),
+	}
+	var err error
+	if onQueue == nil {
+		err = s.jobRepo.Create(entity)
+	} else {
+		err = s.jobRepo.CreateWith(entity, func(tx *gorm.DB) error {
+			return onQueue(tx, entity.JobID)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(entity, models.JobStageUploaded)
+	if s.notifier != nil {
+		s.notifier.Notify()
+	}
+
+	return toJob(entity)
 }
 
 // GetJob retrieves a job by ID
@@ -62,22 +207,112 @@ func (s *JobService) GetJob(jobID string) (*models.Job, error) {
 		}
 		return nil, err
 	}
+	return toJob(entity)
+}
+
+// GetTranscript returns the speaker-segmented transcript of a succeeded job
+func (s *JobService) GetTranscript(jobID string) (*models.Transcript, error) {
+	entity, err := s.jobRepo.FindByID(jobID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("job not found")
+		}
+		return nil, err
+	}
+	if entity.Status != repository.JobStatusSucceeded {
+		return nil, fmt.Errorf("transcript is not available")
+	}
 
+	segments, err := s.transcriptRepo.FindSegments(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	transcript := &models.Transcript{
+		JobID:    entity.JobID,
+		Speakers: []string{},
+		Segments: make([]models.TranscriptSegment, 0, len(segments)),
+	}
+	if entity.Language != nil {
+		transcript.Language = *entity.Language
+	}
+	if entity.DurationSec != nil {
+		transcript.DurationSec = *entity.DurationSec
+	}
+	seen := make(map[string]bool)
+	for _, segment := range segments {
+		transcript.Segments = append(transcript.Segments, models.TranscriptSegment{
+			Speaker: segment.Speaker,
+			Start:   segment.StartSec,
+			End:     segment.EndSec,
+			Text:    segment.Text,
+		})
+		if segment.Speaker != "" && !seen[segment.Speaker] {
+			seen[segment.Speaker] = true
+			transcript.Speakers = append(transcript.Speakers, segment.Speaker)
+		}
+	}
+
+	// Jobs finished before transcripts were segmented only have the full text
+	if len(segments) == 0 && entity.Transcript != nil && *entity.Transcript != "" {
+		transcript.Segments = append(transcript.Segments, models.TranscriptSegment{
+			End:  transcript.DurationSec,
+			Text: *entity.Transcript,
+		})
+	}
+	return transcript, nil
+}
+
+// toJob converts a job entity to its API representation
+func toJob(entity *repository.JobEntity) (*models.Job, error) {
 	job := &models.Job{
 		JobID:      entity.JobID,
 		Status:     models.JobStatus(entity.Status),
+		Attempts:   entity.Attempts,
 		CreatedAt:  entity.CreatedAt,
 		FinishedAt: entity.FinishedAt,
 	}
+	if entity.AudioFormat != nil {
+		job.Audio = &models.AudioInfo{Format: *entity.AudioFormat}
+		if entity.AudioCodec != nil {
+			job.Audio.Codec = *entity.AudioCodec
+		}
+		if entity.SampleRate != nil {
+			job.Audio.SampleRate = *entity.SampleRate
+		}
+		if entity.Channels != nil {
+			job.Audio.Channels = *entity.Channels
+		}
+		if entity.DurationSec != nil {
+			job.Audio.DurationSec = *entity.DurationSec
+		}
+	}
 
 	// Add result if succeeded
 	if entity.Status == repository.JobStatusSucceeded && entity.Transcript != nil {
 		job.Result = &models.TranscriptionResult{
 			PersonID:    entity.PersonID,
+			EncounterID: entity.EncounterID,
 			Transcript:  *entity.Transcript,
-			Summary:     *entity.Summary,
-			Language:    *entity.Language,
-			DurationSec: *entity.DurationSec,
+		}
+		if entity.Summary != nil {
+			job.Result.Summary = *entity.Summary
+		}
+		if entity.SummaryDetails != nil {
+			if err := json.Unmarshal([]byte(*entity.SummaryDetails), &job.Result.SummaryDetails); err != nil {
+				return nil, fmt.Errorf("failed to decode summary details: %w", err)
+			}
+		}
+		if entity.Language != nil {
+			job.Result.Language = *entity.Language
+		}
+		if entity.DurationSec != nil {
+			job.Result.DurationSec = *entity.DurationSec
+		}
+		if entity.Words != nil {
+			if err := json.Unmarshal([]byte(*entity.Words), &job.Result.Words); err != nil {
+				return nil, fmt.Errorf("failed to decode words: %w", err)
+			}
 		}
 	}
 
@@ -94,15 +329,202 @@ func (s *JobService) GetJob(jobID string) (*models.Job, error) {
 	return job, nil
 }
 
-// UpdateJobStatus updates the status of a job
-func (s *JobService) UpdateJobStatus(jobID string, status models.JobStatus) error {
-	now := time.Now()
-	repoStatus := repository.JobStatus(status)
+// ListDeliveries returns the webhook deliveries of a job
+func (s *JobService) ListDeliveries(jobID string) (*models.WebhookDeliveryList, error) {
+	if _, err := s.jobRepo.FindByID(jobID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("job not found")
+		}
+		return nil, err
+	}
+	if s.webhooks == nil {
+		return &models.WebhookDeliveryList{Items: []models.WebhookDelivery{}}, nil
+	}
+	return s.webhooks.ListDeliveries(jobID)
+}
+
+// DeleteJob deletes a job and its audio. Running jobs cannot be deleted.
+func (s *JobService) DeleteJob(ctx context.Context, jobID string) error {
+	entity, err := s.jobRepo.FindByID(jobID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("job not found")
+		}
+		return err
+	}
+	if entity.Status == repository.JobStatusRunning {
+		return fmt.Errorf("job is running")
+	}
+
+	if err := s.jobRepo.Delete(jobID); err != nil {
+		return err
+	}
+	if entity.AudioKey != nil {
+		s.releaseAudio(*entity.AudioKey)
+	}
+	return nil
+}
+
+// DeletePersonAudio removes the recordings of a person's jobs from storage.
+// The jobs and their transcripts are kept.
+func (s *JobService) DeletePersonAudio(ctx context.Context, personID string) error {
+	keys, err := s.jobRepo.DetachPersonAudio(personID)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		s.releaseAudio(key)
+	}
+	return nil
+}
+
+// releaseAudio deletes an audio object once no job or upload chunk references
+// it. Identical uploads share one object, so it may still belong to another job.
+// An object that was just stored again is deleted once its new job is queued.
+func (s *JobService) releaseAudio(key string) {
+	s.audio.release(key)
+}
+
+// deleteUnreferencedAudio deletes an audio object that nothing references.
+// Failures are logged rather than returned: the job is already gone and a
+// leftover object is removed by the next sweep.
+func (s *JobService) deleteUnreferencedAudio(key string) bool {
+	count, err := s.jobRepo.CountAudioReferences(key)
+	if err != nil {
+		log.Printf("Warning: failed to check references to audio %s: %v", key, err)
+		return false
+	}
+	if count > 0 {
+		return false
+	}
+	if err := s.audioStore.Delete(context.Background(), key); err != nil {
+		log.Printf("Warning: failed to delete audio %s: %v", key, err)
+		return false
+	}
+	return true
+}
+
+// SweepAudio deletes stored audio that nothing references and that was last
+// stored before the grace period. This removes objects that a crash or a
+// concurrent deletion on another instance left behind. It returns how many
+// objects were deleted.
+func (s *JobService) SweepAudio(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-s.config.SweepGrace)
+	var stale []string
+	err := s.audioStore.List(ctx, func(info storage.ObjectInfo) error {
+		if info.ModTime.Before(cutoff) {
+			stale = append(stale, info.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list audio: %w", err)
+	}
+
+	deleted := 0
+	for _, key := range stale {
+		if ctx.Err() != nil {
+			break
+		}
+		if s.audio.release(key) {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
 
-	var finishedAt *time.Time
-	if status == models.JobStatusSucceeded || status == models.JobStatusFailed {
-		finishedAt = &now
+// ListJobs retrieves jobs matching filter, newest first, with pagination.
+// Word timestamps are left out of listings; fetch a single job to get them.
+func (s *JobService) ListJobs(filter *models.JobFilter, limit int, cursor *string) (*models.JobList, error) {
+	repoFilter := repository.JobFilter{
+		PersonID:      filter.PersonID,
+		CreatedAfter:  filter.CreatedAfter,
+		CreatedBefore: filter.CreatedBefore,
+	}
+	if filter.Status != nil {
+		status := repository.JobStatus(*filter.Status)
+		repoFilter.Status = &status
 	}
 
-	return s.jobRepo.UpdateStatus(jobID, repoStatus, finishedAt)
+	entities, nextCursor, err := s.jobRepo.List(repoFilter, limit, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]models.Job, 0, len(entities))
+	for i := range entities {
+		job, err := toJob(&entities[i])
+		if err != nil {
+			return nil, err
+		}
+		if job.Result != nil {
+			job.Result.Words = nil
+		}
+		jobs = append(jobs, *job)
+	}
+
+	return &models.JobList{
+		Items:      jobs,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// CancelJob stops a queued or running job. A running job's worker notices at
+// its next lease renewal and discards its result.
+func (s *JobService) CancelJob(jobID string) (*models.Job, error) {
+	entity, err := s.jobRepo.Transition(jobID, repository.JobStatusCancelled, map[string]interface{}{
+		"finished_at":      time.Now(),
+		"lease_owner":      nil,
+		"lease_expires_at": nil,
+		"next_attempt_at":  nil,
+	})
+	if err != nil {
+		return nil, transitionError(err, "job cannot be cancelled")
+	}
+	s.events.Publish(entity, models.JobStageDone)
+	return toJob(entity)
+}
+
+// RetryJob queues a failed job again with a fresh attempt budget
+func (s *JobService) RetryJob(jobID string) (*models.Job, error) {
+	entity, err := s.jobRepo.FindByID(jobID)
+	if err != nil {
+		return nil, transitionError(err, "")
+	}
+	// running -> queued is also allowed, but only a worker may requeue its own attempt
+	if entity.Status != repository.JobStatusFailed {
+		return nil, fmt.Errorf("job cannot be retried")
+	}
+	if entity.AudioKey == nil {
+		return nil, fmt.Errorf("job audio is no longer available")
+	}
+
+	entity, err = s.jobRepo.TransitionFrom(jobID, []repository.JobStatus{repository.JobStatusFailed}, repository.JobStatusQueued, map[string]interface{}{
+		"attempts":        0,
+		"error_message":   nil,
+		"finished_at":     nil,
+		"next_attempt_at": nil,
+		"started_at":      nil,
+	})
+	if err != nil {
+		return nil, transitionError(err, "job cannot be retried")
+	}
+
+	s.events.Publish(entity, models.JobStageUploaded)
+	if s.notifier != nil {
+		s.notifier.Notify()
+	}
+	return toJob(entity)
+}
+
+// transitionError maps repository errors of a status change to service errors
+func transitionError(err error, invalid string) error {
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		return fmt.Errorf("job not found")
+	case errors.Is(err, repository.ErrInvalidTransition):
+		return fmt.Errorf("%s", invalid)
+	default:
+		return err
+	}
 }
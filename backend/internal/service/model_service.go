@@ -0,0 +1,192 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ModelService handles the embedding model registry.
+// Registered models are cached in memory because every recognition looks one up.
+type ModelService struct {
+	modelRepo *repository.ModelRepository
+
+	mu    sync.RWMutex
+	cache map[string]repository.EmbeddingModelEntity
+}
+
+// NewModelService creates a new ModelService
+func NewModelService(modelRepo *repository.ModelRepository) *ModelService {
+	return &ModelService{
+		modelRepo: modelRepo,
+		cache:     make(map[string]repository.EmbeddingModelEntity),
+	}
+}
+
+// ListModels retrieves every registered model
+func (s *ModelService) ListModels() (*models.EmbeddingModelList, error) {
+	entities, err := s.modelRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.EmbeddingModel, len(entities))
+	for i, entity := range entities {
+		items[i] = toEmbeddingModel(&entity)
+	}
+
+	return &models.EmbeddingModelList{
+		Items: items,
+	}, nil
+}
+
+// GetModel retrieves a model by version
+func (s *ModelService) GetModel(modelVersion string) (*models.EmbeddingModel, error) {
+	entity, err := s.Resolve(modelVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	model := toEmbeddingModel(entity)
+	return &model, nil
+}
+
+// CreateModel registers a new model
+func (s *ModelService) CreateModel(req *models.EmbeddingModelCreate) (*models.EmbeddingModel, error) {
+	if _, err := s.modelRepo.FindByVersion(req.ModelVersion); err == nil {
+		return nil, fmt.Errorf("model already exists")
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	metric := req.Metric
+	if metric == "" {
+		metric = models.DistanceMetricCosine
+	}
+
+	// Models of a family share one index partition, so their embeddings must be comparable
+	if member, err := s.modelRepo.FindByFamily(req.Family); err == nil {
+		if member.Dimension != req.Dimension || member.Metric != string(metric) {
+			return nil, fmt.Errorf("model family mismatch")
+		}
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	entity := &repository.EmbeddingModelEntity{
+		ModelVersion:     req.ModelVersion,
+		Family:           req.Family,
+		Dimension:        req.Dimension,
+		Metric:           string(metric),
+		DefaultThreshold: req.DefaultThreshold,
+		Description:      req.Description,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+
+	if err := s.modelRepo.Create(entity); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[entity.ModelVersion] = *entity
+	s.mu.Unlock()
+
+	model := toEmbeddingModel(entity)
+	return &model, nil
+}
+
+// Resolve returns the registered model for a version
+func (s *ModelService) Resolve(modelVersion string) (*repository.EmbeddingModelEntity, error) {
+	s.mu.RLock()
+	cached, ok := s.cache[modelVersion]
+	s.mu.RUnlock()
+	if ok {
+		return &cached, nil
+	}
+
+	entity, err := s.modelRepo.FindByVersion(modelVersion)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("model not found")
+		}
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[modelVersion] = *entity
+	s.mu.Unlock()
+	return entity, nil
+}
+
+// ValidateEmbedding checks that an embedding was produced by a registered model with a matching dimension
+func (s *ModelService) ValidateEmbedding(modelVersion string, embedding []float32) (*repository.EmbeddingModelEntity, error) {
+	model, err := s.Resolve(modelVersion)
+	if err != nil {
+		if err.Error() == "model not found" {
+			return nil, fmt.Errorf("unknown model version")
+		}
+		return nil, err
+	}
+
+	if len(embedding) != model.Dimension {
+		return nil, fmt.Errorf("embedding dimension does not match model")
+	}
+	return model, nil
+}
+
+// CheckExtractionModel checks at startup that the model served by face
+// extraction is registered with the dimension it produces. dimension 0 is
+// not checked. An unregistered model is only logged, so that it can still be
+// registered through /v1/models; extraction is rejected until then.
+func (s *ModelService) CheckExtractionModel(modelVersion string, dimension int) error {
+	model, err := s.Resolve(modelVersion)
+	if err != nil {
+		if err.Error() == "model not found" {
+			log.Printf("Warning: face extraction model %q is not registered; register it via /v1/models", modelVersion)
+			return nil
+		}
+		return err
+	}
+	if dimension != 0 && dimension != model.Dimension {
+		return fmt.Errorf("face extraction produces %d-dimensional embeddings but model %q has dimension %d", dimension, modelVersion, model.Dimension)
+	}
+	return nil
+}
+
+// FamilyOf returns the family of a model version.
+// Unregistered versions form a family of their own so they are never mixed with others.
+func (s *ModelService) FamilyOf(modelVersion string) string {
+	model, err := s.Resolve(modelVersion)
+	if err != nil {
+		return modelVersion
+	}
+	return model.Family
+}
+
+// scoreForMetric converts a cosine similarity of normalized vectors into the model's score space
+func scoreForMetric(metric string, cosine float64) float64 {
+	if models.DistanceMetric(metric) == models.DistanceMetricEuclidean {
+		distance := math.Sqrt(math.Max(0, 2-2*cosine))
+		return 1 / (1 + distance)
+	}
+	return cosine
+}
+
+func toEmbeddingModel(entity *repository.EmbeddingModelEntity) models.EmbeddingModel {
+	return models.EmbeddingModel{
+		ModelVersion:     entity.ModelVersion,
+		Family:           entity.Family,
+		Dimension:        entity.Dimension,
+		Metric:           models.DistanceMetric(entity.Metric),
+		DefaultThreshold: entity.DefaultThreshold,
+		Description:      entity.Description,
+		CreatedAt:        entity.CreatedAt,
+	}
+}
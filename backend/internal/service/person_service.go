@@ -1,26 +1,48 @@
 package service
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jphacks/os_2522/backend/internal/models"
 	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/utils"
+	"github.com/jphacks/os_2522/backend/internal/vectorindex"
 	"gorm.io/gorm"
 )
 
 // PersonService handles person business logic
 type PersonService struct {
-	personRepo *repository.PersonRepository
-	faceRepo   *repository.FaceRepository
+	personRepo            *repository.PersonRepository
+	faceRepo              *repository.FaceRepository
+	faceIndex             *vectorindex.Collection
+	modelService          *ModelService
+	faceExtractionService *FaceExtractionService
+	jobService            *JobService
 }
 
 // NewPersonService creates a new PersonService
-func NewPersonService(personRepo *repository.PersonRepository, faceRepo *repository.FaceRepository) *PersonService {
+func NewPersonService(
+	personRepo *repository.PersonRepository,
+	faceRepo *repository.FaceRepository,
+	faceIndex *vectorindex.Collection,
+	modelService *ModelService,
+	faceExtractionService *FaceExtractionService,
+	jobService *JobService,
+) *PersonService {
 	return &PersonService{
-		personRepo: personRepo,
-		faceRepo:   faceRepo,
+		personRepo:            personRepo,
+		faceRepo:              faceRepo,
+		faceIndex:             faceIndex,
+		modelService:          modelService,
+		faceExtractionService: faceExtractionService,
+		jobService:            jobService,
 	}
 }
 
@@ -76,8 +98,10 @@ func (s *PersonService) GetPerson(personID string) (*models.Person, error) {
 	}, nil
 }
 
-// CreatePerson creates a new person
-func (s *PersonService) CreatePerson(req *models.PersonCreate) (*models.Person, error) {
+// CreatePerson creates a new person.
+// When a face image is given, the person and its first face are stored in one
+// transaction, and nothing is created if no face can be enrolled.
+func (s *PersonService) CreatePerson(ctx context.Context, req *models.PersonCreate) (*models.Person, error) {
 	// Generate person ID
 	personID := fmt.Sprintf("p-%s", uuid.New().String()[:8])
 
@@ -89,17 +113,79 @@ func (s *PersonService) CreatePerson(req *models.PersonCreate) (*models.Person,
 		UpdatedAt: time.Now(),
 	}
 
-	if err := s.personRepo.Create(entity); err != nil {
+	if req.FaceImageBase64 == nil {
+		if err := s.personRepo.Create(entity); err != nil {
+			return nil, err
+		}
+		return toPerson(entity, 0), nil
+	}
+
+	face, embedding, model, err := s.enrollmentFace(ctx, personID, *req.FaceImageBase64)
+	if err != nil {
 		return nil, err
 	}
 
+	if err := s.personRepo.CreateWithFace(entity, face); err != nil {
+		return nil, err
+	}
+
+	s.faceIndex.Add(model.Family, vectorindex.Entry{
+		FaceID:    face.FaceID,
+		PersonID:  personID,
+		Embedding: embedding,
+	})
+
+	return toPerson(entity, 1), nil
+}
+
+// enrollmentFace extracts the face to enroll from a base64 image (optionally a data URL)
+func (s *PersonService) enrollmentFace(ctx context.Context, personID, imageBase64 string) (*repository.FaceEntity, []float32, *repository.EmbeddingModelEntity, error) {
+	if i := strings.Index(imageBase64, ";base64,"); strings.HasPrefix(imageBase64, "data:") && i >= 0 {
+		imageBase64 = imageBase64[i+len(";base64,"):]
+	}
+	image, err := base64.StdEncoding.DecodeString(imageBase64)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid face image: %v", err)
+	}
+
+	embedding, err := s.faceExtractionService.ExtractEmbeddingFromImage(ctx, image)
+	if err != nil {
+		if err.Error() == "no face detected" || isExtractionUnavailable(err) {
+			return nil, nil, nil, err
+		}
+		return nil, nil, nil, fmt.Errorf("invalid face image: %v", err)
+	}
+
+	modelVersion := s.faceExtractionService.ModelVersion()
+	model, err := s.modelService.ValidateEmbedding(modelVersion, embedding)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	checksum := utils.CalculateEmbeddingChecksum(embedding)
+	imageHash := fmt.Sprintf("sha256:%x", sha256.Sum256(image))
+
+	return &repository.FaceEntity{
+		FaceID:            fmt.Sprintf("f-%s", uuid.New().String()[:8]),
+		PersonID:          personID,
+		Embedding:         utils.Float32SliceToBytes(embedding),
+		EmbeddingDim:      model.Dimension,
+		ModelVersion:      &modelVersion,
+		EmbeddingChecksum: &checksum,
+		SourceImageHash:   &imageHash,
+		CreatedAt:         time.Now(),
+	}, embedding, model, nil
+}
+
+func toPerson(entity *repository.PersonEntity, facesCount int) *models.Person {
 	return &models.Person{
-		PersonID:   entity.PersonID,
-		Name:       entity.Name,
-		CreatedAt:  entity.CreatedAt,
-		UpdatedAt:  entity.UpdatedAt,
-		FacesCount: 0,
-	}, nil
+		PersonID:    entity.PersonID,
+		Name:        entity.Name,
+		LastSummary: entity.LastSummary,
+		CreatedAt:   entity.CreatedAt,
+		UpdatedAt:   entity.UpdatedAt,
+		FacesCount:  facesCount,
+	}
 }
 
 // UpdatePerson updates a person
@@ -138,8 +224,8 @@ func (s *PersonService) UpdatePerson(personID string, req *models.PersonUpdate)
 	}, nil
 }
 
-// DeletePerson soft deletes a person
-func (s *PersonService) DeletePerson(personID string) error {
+// DeletePerson soft deletes a person and removes their recordings from storage
+func (s *PersonService) DeletePerson(ctx context.Context, personID string) error {
 	// Check if person exists
 	_, err := s.personRepo.FindByID(personID)
 	if err != nil {
@@ -149,5 +235,15 @@ func (s *PersonService) DeletePerson(personID string) error {
 		return err
 	}
 
-	return s.personRepo.Delete(personID)
+	if err := s.personRepo.Delete(personID); err != nil {
+		return err
+	}
+
+	// Faces of a deleted person must no longer be matched
+	s.faceIndex.RemovePerson(personID)
+
+	if err := s.jobService.DeletePersonAudio(ctx, personID); err != nil {
+		log.Printf("Warning: failed to delete audio of person %s: %v", personID, err)
+	}
+	return nil
 }
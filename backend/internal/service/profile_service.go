@@ -0,0 +1,176 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ProfileService serves the long-term profiles of persons and queues new
+// encounter summaries for a ProfileUpdater to merge into them. It is a
+// worker.CompletionHook: a job's encounter is queued in the same transaction
+// as the job outcome, so it must run after the EncounterService hook.
+type ProfileService struct {
+	personRepo  *repository.PersonRepository
+	profileRepo *repository.ProfileRepository
+	notifier    JobNotifier
+}
+
+// NewProfileService creates a new ProfileService. notifier wakes the updater and may be nil.
+func NewProfileService(personRepo *repository.PersonRepository, profileRepo *repository.ProfileRepository, notifier JobNotifier) *ProfileService {
+	return &ProfileService{
+		personRepo:  personRepo,
+		profileRepo: profileRepo,
+		notifier:    notifier,
+	}
+}
+
+// GetProfile returns the current profile of a person
+func (s *ProfileService) GetProfile(personID string) (*models.PersonProfile, error) {
+	if err := s.checkPerson(personID); err != nil {
+		return nil, err
+	}
+	entity, err := s.profileRepo.FindLatest(personID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("profile not found")
+		}
+		return nil, err
+	}
+	profile, err := toProfile(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.profileRepo.CountPendingUpdates(personID)
+	if err != nil {
+		return nil, err
+	}
+	count := int(pending)
+	profile.PendingEncounters = &count
+	return profile, nil
+}
+
+// ListVersions returns the profile versions of a person, newest first, with pagination
+func (s *ProfileService) ListVersions(personID string, limit int, cursor *string) (*models.ProfileVersionList, error) {
+	if err := s.checkPerson(personID); err != nil {
+		return nil, err
+	}
+	entities, nextCursor, err := s.profileRepo.FindVersions(personID, limit, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]models.PersonProfile, len(entities))
+	for i := range entities {
+		profile, err := toProfile(&entities[i])
+		if err != nil {
+			return nil, err
+		}
+		versions[i] = *profile
+	}
+	return &models.ProfileVersionList{
+		Items:      versions,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// GetVersion returns a profile version of a person
+func (s *ProfileService) GetVersion(personID string, version int) (*models.PersonProfile, error) {
+	if err := s.checkPerson(personID); err != nil {
+		return nil, err
+	}
+	entity, err := s.profileRepo.FindVersion(personID, version)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("profile version not found")
+		}
+		return nil, err
+	}
+	return toProfile(entity)
+}
+
+// RevertProfile restores an earlier profile version by copying it into a new
+// version, so the history is kept and the revert itself can be undone.
+// Encounters merged later build on the restored profile.
+func (s *ProfileService) RevertProfile(personID string, version int) (*models.PersonProfile, error) {
+	if err := s.checkPerson(personID); err != nil {
+		return nil, err
+	}
+	target, err := s.profileRepo.FindVersion(personID, version)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("profile version not found")
+		}
+		return nil, err
+	}
+
+	// The version is allocated with the latest version read again, as an
+	// encounter may be merged meanwhile
+	entity := &repository.ProfileVersionEntity{
+		PersonID:     personID,
+		Document:     target.Document,
+		RevertedFrom: &target.Version,
+		CreatedAt:    time.Now(),
+	}
+	err = s.profileRepo.AppendVersion(entity, func(latest int) error {
+		if latest == target.Version {
+			return fmt.Errorf("profile version is already current")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toProfile(entity)
+}
+
+func (s *ProfileService) checkPerson(personID string) error {
+	if _, err := s.personRepo.FindByID(personID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("person not found")
+		}
+		return err
+	}
+	return nil
+}
+
+// JobFinishing queues the encounter a succeeded job's summary was recorded on
+func (s *ProfileService) JobFinishing(tx *gorm.DB, job *repository.JobEntity) error {
+	if job.Status != repository.JobStatusSucceeded || job.PersonID == nil || job.EncounterID == nil {
+		return nil
+	}
+	now := time.Now()
+	return repository.NewProfileRepository(tx).EnqueueUpdate(&repository.ProfileUpdateEntity{
+		EncounterID:   *job.EncounterID,
+		PersonID:      *job.PersonID,
+		Status:        repository.ProfileUpdatePending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	})
+}
+
+// JobFinished wakes the updater once the update has been committed
+func (s *ProfileService) JobFinished(job *repository.JobEntity) {
+	if s.notifier != nil && job.EncounterID != nil {
+		s.notifier.Notify()
+	}
+}
+
+func toProfile(entity *repository.ProfileVersionEntity) (*models.PersonProfile, error) {
+	profile := &models.PersonProfile{
+		PersonID:     entity.PersonID,
+		Version:      entity.Version,
+		EncounterID:  entity.EncounterID,
+		RevertedFrom: entity.RevertedFrom,
+		CreatedAt:    entity.CreatedAt,
+	}
+	if err := json.Unmarshal([]byte(entity.Document), &profile.ProfileDocument); err != nil {
+		return nil, fmt.Errorf("failed to decode profile: %w", err)
+	}
+	return profile, nil
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/llm"
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+// profileInstructions is the system prompt for merging a conversation into a profile
+const profileInstructions = "あなたは、ユーザーが会った人物についての長期的な記憶を管理するアシスタントです。" +
+	"現在のプロフィール（JSON）と、その人物との新しい会話の要約が与えられます。" +
+	"新しい会話で分かったことを統合した、更新後のプロフィール全体を出力してください。\n" +
+	"- overview: どんな人物かを数文でまとめる\n" +
+	"- facts: 分かっている事実。重複は1つにまとめ、新しい情報と矛盾する古い事実は新しい内容に置き換える。多すぎる場合は重要なものを残す\n" +
+	"- interests: 関心事や繰り返し話題になる事柄\n" +
+	"- follow_ups: まだ果たされていない約束事。新しい会話で完了したと分かったものは削除する\n" +
+	"新しい会話に含まれない既存の情報は、矛盾しない限り残してください。推測はしないでください。" +
+	"出力は指定されたJSONスキーマに従うJSONのみとしてください。"
+
+// profileSchema is the JSON schema that merged profiles must match
+var profileSchema = &llm.Schema{
+	Type:     llm.TypeObject,
+	Required: []string{"overview", "facts", "interests", "follow_ups"},
+	Properties: map[string]*llm.Schema{
+		"overview": {Type: llm.TypeString, MaxLength: 500},
+		"facts": {
+			Type:     llm.TypeArray,
+			MaxItems: 50,
+			Items:    summarySchema.Properties["facts"].Items,
+		},
+		"interests": {
+			Type:     llm.TypeArray,
+			MaxItems: 20,
+			Items:    &llm.Schema{Type: llm.TypeString, MaxLength: 40},
+		},
+		"follow_ups": {
+			Type:     llm.TypeArray,
+			MaxItems: 20,
+			Items:    summarySchema.Properties["follow_ups"].Items,
+		},
+	},
+}
+
+// ProfileConfig holds the settings of the profile updater
+type ProfileConfig struct {
+	// PollInterval is how often the queue is checked when idle
+	PollInterval time.Duration
+	// MaxAttempts is how many times merging an encounter is tried before it is given up
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// ClaimDuration is how long a claimed update is reserved; it must exceed
+	// the time a merge can take, including every output attempt
+	ClaimDuration time.Duration
+	// OutputAttempts is how many times the model is asked within one attempt
+	// when its output does not match the schema
+	OutputAttempts int
+}
+
+// DefaultProfileConfig returns the default profile updater settings
+func DefaultProfileConfig() *ProfileConfig {
+	return &ProfileConfig{
+		PollInterval:   30 * time.Second,
+		MaxAttempts:    5,
+		BackoffBase:    time.Minute,
+		BackoffMax:     time.Hour,
+		ClaimDuration:  15 * time.Minute,
+		OutputAttempts: 3,
+	}
+}
+
+// GetProfileConfigFromEnv reads profile updater configuration from environment variables
+func GetProfileConfigFromEnv() *ProfileConfig {
+	config := DefaultProfileConfig()
+	config.PollInterval = utils.GetEnvDuration("PROFILE_POLL_INTERVAL", config.PollInterval)
+	config.MaxAttempts = utils.GetEnvInt("PROFILE_MAX_ATTEMPTS", config.MaxAttempts)
+	return config
+}
+
+// ProfileUpdater merges queued encounter summaries into person profiles with
+// a language model, one encounter at a time, and retries failures with
+// exponential backoff. Each merge adds a profile version.
+type ProfileUpdater struct {
+	provider      llm.Provider
+	personRepo    *repository.PersonRepository
+	encounterRepo *repository.EncounterRepository
+	profileRepo   *repository.ProfileRepository
+	config        *ProfileConfig
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewProfileUpdater creates a ProfileUpdater. Call Start to begin merging.
+func NewProfileUpdater(
+	provider llm.Provider,
+	personRepo *repository.PersonRepository,
+	encounterRepo *repository.EncounterRepository,
+	profileRepo *repository.ProfileRepository,
+	config *ProfileConfig,
+) *ProfileUpdater {
+	defaults := DefaultProfileConfig()
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaults.PollInterval
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaults.MaxAttempts
+	}
+	if config.BackoffBase <= 0 {
+		config.BackoffBase = defaults.BackoffBase
+	}
+	if config.BackoffMax < config.BackoffBase {
+		config.BackoffMax = config.BackoffBase
+	}
+	if config.ClaimDuration <= 0 {
+		config.ClaimDuration = defaults.ClaimDuration
+	}
+	if config.OutputAttempts <= 0 {
+		config.OutputAttempts = defaults.OutputAttempts
+	}
+
+	return &ProfileUpdater{
+		provider:      provider,
+		personRepo:    personRepo,
+		encounterRepo: encounterRepo,
+		profileRepo:   profileRepo,
+		config:        config,
+		wake:          make(chan struct{}, 1),
+	}
+}
+
+// Start queues summarized encounters that were never merged and launches the updater goroutine
+func (u *ProfileUpdater) Start(ctx context.Context) {
+	if queued, err := u.profileRepo.EnqueueMissing(time.Now()); err != nil {
+		log.Printf("Warning: failed to queue encounters for profiles: %v", err)
+	} else if queued > 0 {
+		log.Printf("Queued %d earlier encounter(s) for profiles", queued)
+	}
+
+	ctx, u.cancel = context.WithCancel(ctx)
+	u.wg.Add(1)
+	go func() {
+		defer u.wg.Done()
+		u.loop(ctx)
+	}()
+	log.Println("Profile updater started")
+}
+
+// Stop cancels the merge in progress and waits for the goroutine to exit
+func (u *ProfileUpdater) Stop() {
+	if u.cancel == nil {
+		return
+	}
+	u.cancel()
+	u.wg.Wait()
+}
+
+// Notify wakes an idle updater so a new encounter is merged without waiting for the next poll
+func (u *ProfileUpdater) Notify() {
+	select {
+	case u.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (u *ProfileUpdater) loop(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		merged, err := u.runOnce(ctx)
+		if err != nil {
+			log.Printf("Warning: profile updater: %v", err)
+		}
+		if merged && ctx.Err() == nil {
+			continue
+		}
+
+		timer.Reset(u.config.PollInterval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-u.wake:
+		case <-timer.C:
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+	}
+}
+
+// runOnce claims and merges at most one due encounter. It reports whether one was claimed.
+func (u *ProfileUpdater) runOnce(ctx context.Context) (bool, error) {
+	now := time.Now()
+	update, err := u.profileRepo.ClaimNextUpdate(now, now.Add(u.config.ClaimDuration))
+	if err != nil {
+		return false, fmt.Errorf("failed to claim profile update: %w", err)
+	}
+	if update == nil {
+		return false, nil
+	}
+
+	mergeErr := u.merge(ctx, update)
+	if ctx.Err() != nil || errors.Is(mergeErr, repository.ErrProfileConflict) {
+		// Shutting down, or the profile changed during the merge, e.g. by a
+		// revert: make the update due again without counting this attempt
+		return true, u.profileRepo.UpdateUpdate(update.EncounterID, map[string]interface{}{
+			"attempts":        gorm.Expr("attempts - 1"),
+			"next_attempt_at": time.Now(),
+		})
+	}
+	if mergeErr == nil {
+		return true, nil
+	}
+
+	updates := map[string]interface{}{"last_error": mergeErr.Error()}
+	var permanent *permanentProfileError
+	if errors.As(mergeErr, &permanent) || update.Attempts >= u.config.MaxAttempts {
+		log.Printf("Profile update for encounter %s failed after %d attempt(s): %v", update.EncounterID, update.Attempts, mergeErr)
+		updates["status"] = repository.ProfileUpdateFailed
+		updates["finished_at"] = time.Now()
+	} else {
+		delay := utils.Backoff(update.Attempts, u.config.BackoffBase, u.config.BackoffMax)
+		log.Printf("Profile update for encounter %s attempt %d failed, retrying in %v: %v", update.EncounterID, update.Attempts, delay, mergeErr)
+		updates["next_attempt_at"] = time.Now().Add(delay)
+	}
+	if err := u.profileRepo.UpdateUpdate(update.EncounterID, updates); err != nil {
+		return true, fmt.Errorf("failed to update profile update %s: %w", update.EncounterID, err)
+	}
+	return true, nil
+}
+
+// permanentProfileError marks a merge that cannot succeed on retry
+type permanentProfileError struct {
+	msg string
+}
+
+func (e *permanentProfileError) Error() string { return e.msg }
+
+// merge asks the model to fold the encounter's summary into the person's
+// current profile and stores the result as the next version
+func (u *ProfileUpdater) merge(ctx context.Context, update *repository.ProfileUpdateEntity) error {
+	encounter, err := u.encounterRepo.FindByID(update.EncounterID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &permanentProfileError{msg: "encounter not found"}
+		}
+		return err
+	}
+	if encounter.Summary == nil {
+		return &permanentProfileError{msg: "encounter has no summary"}
+	}
+	if _, err := u.personRepo.FindByID(encounter.PersonID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &permanentProfileError{msg: "person not found"}
+		}
+		return err
+	}
+
+	current := "なし（最初の会話です）"
+	version := 0
+	latest, err := u.profileRepo.FindLatest(encounter.PersonID)
+	switch {
+	case err == nil:
+		current = latest.Document
+		version = latest.Version
+	case err != gorm.ErrRecordNotFound:
+		return err
+	}
+	conversation := *encounter.Summary
+	if encounter.SummaryDetails != nil {
+		conversation = *encounter.SummaryDetails
+	}
+
+	req := llm.Request{
+		System: profileInstructions,
+		Prompt: fmt.Sprintf("現在のプロフィール:\n%s\n\n新しい会話（%s）:\n%s", current, encounter.RecognizedAt.Format("2006-01-02"), conversation),
+		Schema: profileSchema,
+	}
+	var document models.ProfileDocument
+	err = generateStructured(ctx, u.provider, req, u.config.OutputAttempts, func(data []byte) error {
+		document = models.ProfileDocument{}
+		return json.Unmarshal(data, &document)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to merge encounter into profile: %w", err)
+	}
+
+	// Keep empty lists as [] rather than null in responses
+	if document.Facts == nil {
+		document.Facts = []models.PersonFact{}
+	}
+	if document.Interests == nil {
+		document.Interests = []string{}
+	}
+	if document.FollowUps == nil {
+		document.FollowUps = []models.FollowUp{}
+	}
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return u.profileRepo.CommitUpdate(&repository.ProfileVersionEntity{
+		PersonID:    encounter.PersonID,
+		Document:    string(encoded),
+		EncounterID: &encounter.EncounterID,
+		CreatedAt:   time.Now(),
+	}, version, time.Now())
+}
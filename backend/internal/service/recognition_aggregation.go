@@ -0,0 +1,125 @@
+package service
+
+import (
+	"sort"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/utils"
+	"github.com/jphacks/os_2522/backend/internal/vectorindex"
+)
+
+// personScore is the aggregated match of one person against a query
+type personScore struct {
+	personID string
+	score    float64
+	faces    []models.MatchedFace
+}
+
+// aggregateByPerson groups face hits by person and scores each person with the given strategy.
+// Hit scores must already be expressed in the model's metric; centroid similarity is converted here.
+// The result is sorted by score descending.
+func aggregateByPerson(
+	hits []vectorindex.Result,
+	query []float32,
+	strategy models.ScoreAggregation,
+	topN int,
+	metric string,
+	faceIndex *vectorindex.Index,
+) []personScore {
+	byPerson := make(map[string]*personScore)
+	var order []string
+	for _, hit := range hits {
+		ps, ok := byPerson[hit.PersonID]
+		if !ok {
+			ps = &personScore{personID: hit.PersonID}
+			byPerson[hit.PersonID] = ps
+			order = append(order, hit.PersonID)
+		}
+		ps.faces = append(ps.faces, models.MatchedFace{FaceID: hit.FaceID, Score: hit.Score})
+	}
+
+	normalizedQuery := utils.NormalizeL2(query)
+	scores := make([]personScore, 0, len(order))
+	for _, personID := range order {
+		ps := byPerson[personID]
+		// Hits arrive best first, so faces are already sorted by score descending
+		switch strategy {
+		case models.ScoreAggregationMeanTopN:
+			ps.score = meanTopN(ps.faces, topN)
+		case models.ScoreAggregationCentroid:
+			cosine := centroidSimilarity(normalizedQuery, faceIndex.PersonEmbeddings(personID))
+			ps.score = scoreForMetric(metric, cosine)
+		default:
+			ps.score = ps.faces[0].Score
+		}
+		scores = append(scores, *ps)
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].score > scores[j].score
+	})
+	return scores
+}
+
+// meanTopN averages the n best face scores (or all of them when fewer are available)
+func meanTopN(faces []models.MatchedFace, n int) float64 {
+	if n <= 0 || n > len(faces) {
+		n = len(faces)
+	}
+	var sum float64
+	for _, f := range faces[:n] {
+		sum += f.Score
+	}
+	return sum / float64(n)
+}
+
+// centroidSimilarity compares the query with the normalized mean of a person's embeddings
+func centroidSimilarity(normalizedQuery []float32, embeddings [][]float32) float64 {
+	if len(embeddings) == 0 {
+		return 0
+	}
+	centroid := make([]float32, len(normalizedQuery))
+	for _, e := range embeddings {
+		if len(e) != len(centroid) {
+			continue
+		}
+		for i, v := range e {
+			centroid[i] += v
+		}
+	}
+	return utils.DotProduct(normalizedQuery, utils.NormalizeL2(centroid))
+}
+
+// assignOneToOne greedily pairs faces with persons by descending score so that
+// no person is assigned to two faces. It returns person ID -> face index.
+func assignOneToOne(ranked [][]personScore) map[string]int {
+	type pair struct {
+		face     int
+		personID string
+		score    float64
+	}
+
+	var pairs []pair
+	for face, scores := range ranked {
+		for _, ps := range scores {
+			pairs = append(pairs, pair{face: face, personID: ps.personID, score: ps.score})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].score > pairs[j].score
+	})
+
+	assigned := make(map[string]int)
+	faceTaken := make(map[int]bool)
+	for _, p := range pairs {
+		if faceTaken[p.face] {
+			continue
+		}
+		if _, taken := assigned[p.personID]; taken {
+			continue
+		}
+		assigned[p.personID] = p.face
+		faceTaken[p.face] = true
+	}
+	return assigned
+}
@@ -1,117 +1,312 @@
 package service
 
 import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/jphacks/os_2522/backend/internal/models"
 	"github.com/jphacks/os_2522/backend/internal/repository"
 	"github.com/jphacks/os_2522/backend/internal/utils"
+	"github.com/jphacks/os_2522/backend/internal/vectorindex"
 )
 
+// RecognitionConfig holds recognition tuning parameters
+type RecognitionConfig struct {
+	// Aggregation is the default strategy for combining face scores per person
+	Aggregation models.ScoreAggregation
+	// AggregationTopN is the default number of faces averaged by mean_top_n
+	AggregationTopN int
+	// SearchFanout is how many face hits are fetched per requested candidate before grouping
+	SearchFanout int
+	// AmbiguityMargin is the default minimum score gap between the top two persons
+	AmbiguityMargin float64
+	// EncounterDebounce is the minimum time between two logged encounters of the same person
+	EncounterDebounce time.Duration
+}
+
+// GetRecognitionConfigFromEnv reads recognition configuration from environment variables
+func GetRecognitionConfigFromEnv() *RecognitionConfig {
+	config := &RecognitionConfig{
+		Aggregation:       models.ScoreAggregation(utils.GetEnv("RECOGNITION_AGGREGATION", string(models.ScoreAggregationMax))),
+		AggregationTopN:   utils.GetEnvInt("RECOGNITION_AGGREGATION_TOP_N", 3),
+		SearchFanout:      utils.GetEnvInt("RECOGNITION_SEARCH_FANOUT", 10),
+		AmbiguityMargin:   utils.GetEnvFloat("RECOGNITION_AMBIGUITY_MARGIN", 0.05),
+		EncounterDebounce: utils.GetEnvDuration("RECOGNITION_ENCOUNTER_DEBOUNCE", 5*time.Minute),
+	}
+
+	switch config.Aggregation {
+	case models.ScoreAggregationMax, models.ScoreAggregationMeanTopN, models.ScoreAggregationCentroid:
+	default:
+		config.Aggregation = models.ScoreAggregationMax
+	}
+	if config.AggregationTopN < 1 {
+		config.AggregationTopN = 3
+	}
+	if config.SearchFanout < 1 {
+		config.SearchFanout = 10
+	}
+	if config.AmbiguityMargin < 0 {
+		config.AmbiguityMargin = 0
+	}
+	if config.EncounterDebounce < 0 {
+		config.EncounterDebounce = 0
+	}
+
+	return config
+}
+
 // RecognitionService handles face recognition business logic
 type RecognitionService struct {
-	faceRepo      *repository.FaceRepository
+	faceIndex     *vectorindex.Collection
+	modelService  *ModelService
 	personRepo    *repository.PersonRepository
 	encounterRepo *repository.EncounterRepository
+	config        *RecognitionConfig
+	debouncer     *encounterDebouncer
 }
 
 // NewRecognitionService creates a new RecognitionService
 func NewRecognitionService(
-	faceRepo *repository.FaceRepository,
+	faceIndex *vectorindex.Collection,
+	modelService *ModelService,
 	personRepo *repository.PersonRepository,
 	encounterRepo *repository.EncounterRepository,
+	config *RecognitionConfig,
 ) *RecognitionService {
 	return &RecognitionService{
-		faceRepo:      faceRepo,
+		faceIndex:     faceIndex,
+		modelService:  modelService,
 		personRepo:    personRepo,
 		encounterRepo: encounterRepo,
+		config:        config,
+		debouncer:     newEncounterDebouncer(encounterRepo, config.EncounterDebounce),
 	}
 }
 
+// recognitionOptions are the effective parameters of one recognition after applying defaults
+type recognitionOptions struct {
+	model           *repository.EmbeddingModelEntity
+	topK            int
+	minScore        float64
+	aggregation     models.ScoreAggregation
+	aggregationTopN int
+	ambiguityMargin float64
+	logEncounter    bool
+}
+
 // Recognize performs face recognition using client-provided embedding
 func (s *RecognitionService) Recognize(req *models.RecognitionRequest) (*models.RecognitionResponse, error) {
-	topK := req.TopK
-	if topK == 0 {
-		topK = 3 // Default
+	opts, err := s.resolveOptions(req)
+	if err != nil {
+		return nil, err
 	}
-	minScore := req.MinScore
-	if minScore == 0 {
-		minScore = 0.6 // Default
+
+	return s.respond(s.rankPersons(req.Embedding, opts), opts), nil
+}
+
+// RecognizeBatch recognizes several faces seen in the same frame.
+// A person is assigned to at most one face: pairs are taken greedily by descending score,
+// and persons claimed by another face are removed from each face's candidates.
+func (s *RecognitionService) RecognizeBatch(req *models.BatchRecognitionRequest) (*models.BatchRecognitionResponse, error) {
+	options := make([]*recognitionOptions, len(req.Items))
+	ranked := make([][]personScore, len(req.Items))
+	for i, item := range req.Items {
+		opts, err := s.resolveOptions(req.ItemRequest(i))
+		if err != nil {
+			return nil, err
+		}
+		options[i] = opts
+		ranked[i] = s.rankPersons(item.Embedding, opts)
+	}
+
+	// Only matches above each face's threshold claim a person
+	matches := make([][]personScore, len(ranked))
+	for i := range ranked {
+		matches[i] = aboveThreshold(ranked[i], options[i].minScore)
+	}
+	assigned := assignOneToOne(matches)
+
+	results := make([]models.BatchRecognitionItemResult, len(req.Items))
+	for i, item := range req.Items {
+		var available []personScore
+		for _, ps := range ranked[i] {
+			if owner, ok := assigned[ps.personID]; !ok || owner == i {
+				available = append(available, ps)
+			}
+		}
+
+		results[i] = models.BatchRecognitionItemResult{
+			TrackID:             item.TrackID,
+			RecognitionResponse: *s.respond(available, options[i]),
+		}
 	}
 
-	// Retrieve all face embeddings from database
-	faceEntities, err := s.faceRepo.FindAllEmbeddings()
+	return &models.BatchRecognitionResponse{
+		Items: results,
+	}, nil
+}
+
+// resolveOptions validates the embedding against its model and applies defaults
+func (s *RecognitionService) resolveOptions(req *models.RecognitionRequest) (*recognitionOptions, error) {
+	// Only embeddings of the same model family are comparable
+	model, err := s.modelService.ValidateEmbedding(req.ModelVersion, req.Embedding)
 	if err != nil {
 		return nil, err
 	}
 
-	// Calculate similarity scores
-	type candidateScore struct {
-		personID string
-		faceID   string
-		score    float64
+	opts := &recognitionOptions{
+		model:           model,
+		topK:            req.TopK,
+		minScore:        req.MinScore,
+		aggregation:     req.Aggregation,
+		aggregationTopN: req.AggregationTopN,
+		ambiguityMargin: s.config.AmbiguityMargin,
+		logEncounter:    req.LogEncounter == nil || *req.LogEncounter,
+	}
+	if opts.topK == 0 {
+		opts.topK = 3 // Default
+	}
+	if opts.minScore == 0 {
+		opts.minScore = model.DefaultThreshold
+	}
+	if opts.aggregation == "" {
+		opts.aggregation = s.config.Aggregation
+	}
+	if opts.aggregationTopN == 0 {
+		opts.aggregationTopN = s.config.AggregationTopN
+	}
+	if req.AmbiguityMargin != nil {
+		opts.ambiguityMargin = *req.AmbiguityMargin
 	}
 
-	var scores []candidateScore
-	for _, faceEntity := range faceEntities {
-		// Convert stored bytes back to float32
-		storedEmbedding := utils.BytesToFloat32Slice(faceEntity.Embedding)
-		if storedEmbedding == nil {
-			continue // Skip invalid embeddings
-		}
+	return opts, nil
+}
 
-		// Calculate cosine similarity
-		score := utils.CosineSimilarity(req.Embedding, storedEmbedding)
-		if score >= minScore {
-			scores = append(scores, candidateScore{
-				personID: faceEntity.PersonID,
-				faceID:   faceEntity.FaceID,
-				score:    score,
-			})
-		}
+// rankPersons returns every person found near the embedding, best first.
+// Persons below minScore are kept so that respond can compare the top two.
+func (s *RecognitionService) rankPersons(embedding []float32, opts *recognitionOptions) []personScore {
+	familyIndex := s.faceIndex.Partition(opts.model.Family)
+	if familyIndex == nil {
+		return nil
 	}
 
-	// Sort by score descending
-	for i := 0; i < len(scores); i++ {
-		for j := i + 1; j < len(scores); j++ {
-			if scores[j].score > scores[i].score {
-				scores[i], scores[j] = scores[j], scores[i]
-			}
-		}
+	// Fetch several faces per requested candidate so that one person with many
+	// enrolled faces cannot crowd everyone else out, then score per person.
+	// At least two persons are looked for so the runner-up is known even for top_k=1.
+	hits := familyIndex.Search(embedding, max(opts.topK, 2)*s.config.SearchFanout)
+	for i := range hits {
+		hits[i].Score = scoreForMetric(opts.model.Metric, hits[i].Score)
 	}
+	return aggregateByPerson(hits, embedding, opts.aggregation, opts.aggregationTopN, opts.model.Metric, familyIndex)
+}
 
-	// Limit to topK
-	if len(scores) > topK {
-		scores = scores[:topK]
+// aboveThreshold returns the ranked persons scoring at least minScore
+func aboveThreshold(ranked []personScore, minScore float64) []personScore {
+	var scores []personScore
+	for _, ps := range ranked {
+		if ps.score >= minScore {
+			scores = append(scores, ps)
+		}
 	}
+	return scores
+}
 
-	// If no matches found
-	if len(scores) == 0 {
-		return &models.RecognitionResponse{
-			Status:     models.RecognitionStatusUnknown,
-			Candidates: []models.RecognitionCandidate{},
-		}, nil
+// respond builds the response for ranked persons, deciding known/unknown/ambiguous
+func (s *RecognitionService) respond(ranked []personScore, opts *recognitionOptions) *models.RecognitionResponse {
+	// Refuse to pick a winner when the runner-up is within the margin, so the
+	// overlay never shows a wrong name with confidence. The top two are compared
+	// before min_score and top_k apply, as a runner-up just below the threshold
+	// or beyond top_k is just as close.
+	ambiguous := len(ranked) > 1 && ranked[0].score-ranked[1].score < opts.ambiguityMargin
+
+	scores := aboveThreshold(ranked, opts.minScore)
+
+	// Limit to topK
+	if len(scores) > opts.topK {
+		scores = scores[:opts.topK]
 	}
 
 	// Build candidates list
-	candidates := make([]models.RecognitionCandidate, len(scores))
-	for i, sc := range scores {
+	candidates := make([]models.RecognitionCandidate, 0, len(scores))
+	for _, sc := range scores {
 		person, err := s.personRepo.FindByID(sc.personID)
 		if err != nil {
 			continue
 		}
 
-		candidates[i] = models.RecognitionCandidate{
-			PersonID:    person.PersonID,
-			Name:        person.Name,
-			Score:       sc.score,
-			LastSummary: person.LastSummary,
+		candidates = append(candidates, models.RecognitionCandidate{
+			PersonID:     person.PersonID,
+			Name:         person.Name,
+			Score:        sc.score,
+			LastSummary:  person.LastSummary,
+			MatchedFaces: sc.faces,
+		})
+	}
+
+	// If no matches found
+	if len(candidates) == 0 {
+		return &models.RecognitionResponse{
+			Status:     models.RecognitionStatusUnknown,
+			Candidates: []models.RecognitionCandidate{},
+		}
+	}
+
+	if ambiguous {
+		return &models.RecognitionResponse{
+			Status:     models.RecognitionStatusAmbiguous,
+			Candidates: candidates,
 		}
 	}
 
 	bestMatch := &candidates[0]
 
-	return &models.RecognitionResponse{
+	response := &models.RecognitionResponse{
 		Status:     models.RecognitionStatusKnown,
 		BestMatch:  bestMatch,
 		Candidates: candidates,
+	}
+
+	if opts.logEncounter {
+		encounter, err := s.logEncounter(bestMatch)
+		if err != nil {
+			// A failed log must not hide the match from the overlay
+			log.Printf("Warning: Failed to log encounter for %s: %v", bestMatch.PersonID, err)
+		}
+		response.CreatedEncounter = encounter
+	}
+
+	return response
+}
+
+// logEncounter records an encounter for a match unless one was logged within the debounce window.
+// It returns nil when the encounter was debounced.
+func (s *RecognitionService) logEncounter(match *models.RecognitionCandidate) (*models.Encounter, error) {
+	now := time.Now()
+
+	allowed, err := s.debouncer.reserve(match.PersonID, now)
+	if err != nil || !allowed {
+		return nil, err
+	}
+
+	entity := &repository.EncounterEntity{
+		EncounterID:  fmt.Sprintf("e-%s", uuid.New().String()[:8]),
+		PersonID:     match.PersonID,
+		RecognizedAt: now,
+		Score:        match.Score,
+		CreatedAt:    now,
+	}
+
+	if err := s.encounterRepo.Create(entity); err != nil {
+		s.debouncer.release(match.PersonID, now)
+		return nil, err
+	}
+
+	return &models.Encounter{
+		EncounterID:  entity.EncounterID,
+		PersonID:     entity.PersonID,
+		RecognizedAt: entity.RecognizedAt,
+		Score:        entity.Score,
 	}, nil
 }
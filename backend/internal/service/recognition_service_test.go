@@ -0,0 +1,127 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/database"
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/vectorindex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRecognitionService enrolls one face per person whose cosine
+// similarity to the query [1, 0] is the given score
+func newTestRecognitionService(t *testing.T, scores map[string]float64) *RecognitionService {
+	db, err := database.NewDB(&database.Config{Driver: "sqlite", DBName: fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())})
+	require.NoError(t, err)
+	require.NoError(t, database.AutoMigrate(db))
+
+	now := time.Now()
+	modelRepo := repository.NewModelRepository(db)
+	require.NoError(t, modelRepo.Create(&repository.EmbeddingModelEntity{
+		ModelVersion: "test-v1", Family: "test", Dimension: 2, Metric: string(models.DistanceMetricCosine),
+		DefaultThreshold: 0.6, CreatedAt: now, UpdatedAt: now,
+	}))
+
+	personRepo := repository.NewPersonRepository(db)
+	faceIndex := vectorindex.NewCollection(vectorindex.DefaultConfig())
+	for personID, score := range scores {
+		require.NoError(t, personRepo.Create(&repository.PersonEntity{PersonID: personID, Name: personID, CreatedAt: now, UpdatedAt: now}))
+		faceIndex.Add("test", vectorindex.Entry{
+			FaceID:    "f-" + personID,
+			PersonID:  personID,
+			Embedding: []float32{float32(score), float32(math.Sqrt(1 - score*score))},
+		})
+	}
+
+	return NewRecognitionService(faceIndex, NewModelService(modelRepo), personRepo, repository.NewEncounterRepository(db), &RecognitionConfig{
+		Aggregation:     models.ScoreAggregationMax,
+		AggregationTopN: 3,
+		SearchFanout:    10,
+		AmbiguityMargin: 0.05,
+	})
+}
+
+func TestRecognitionService_Recognize_Ambiguity(t *testing.T) {
+	logEncounter := false
+
+	tests := []struct {
+		name               string
+		scores             map[string]float64
+		topK               int
+		minScore           float64
+		expectedStatus     models.RecognitionStatus
+		expectedCandidates []string
+	}{
+		{
+			name:               "clear winner",
+			scores:             map[string]float64{"p-alice": 0.9, "p-bob": 0.7},
+			topK:               3,
+			expectedStatus:     models.RecognitionStatusKnown,
+			expectedCandidates: []string{"p-alice", "p-bob"},
+		},
+		{
+			name:               "near tie with top_k 1",
+			scores:             map[string]float64{"p-alice": 0.8, "p-bob": 0.78},
+			topK:               1,
+			expectedStatus:     models.RecognitionStatusAmbiguous,
+			expectedCandidates: []string{"p-alice"},
+		},
+		{
+			name:               "runner-up just below min_score",
+			scores:             map[string]float64{"p-alice": 0.61, "p-bob": 0.59},
+			topK:               3,
+			minScore:           0.6,
+			expectedStatus:     models.RecognitionStatusAmbiguous,
+			expectedCandidates: []string{"p-alice"},
+		},
+		{
+			name:               "clear winner with top_k 1",
+			scores:             map[string]float64{"p-alice": 0.9, "p-bob": 0.7},
+			topK:               1,
+			expectedStatus:     models.RecognitionStatusKnown,
+			expectedCandidates: []string{"p-alice"},
+		},
+		{
+			name:               "nobody above min_score",
+			scores:             map[string]float64{"p-alice": 0.5, "p-bob": 0.49},
+			topK:               3,
+			expectedStatus:     models.RecognitionStatusUnknown,
+			expectedCandidates: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestRecognitionService(t, tt.scores)
+
+			response, err := s.Recognize(&models.RecognitionRequest{
+				Embedding:    []float32{1, 0},
+				EmbeddingDim: 2,
+				ModelVersion: "test-v1",
+				TopK:         tt.topK,
+				MinScore:     tt.minScore,
+				LogEncounter: &logEncounter,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedStatus, response.Status)
+			candidates := []string{}
+			for _, candidate := range response.Candidates {
+				candidates = append(candidates, candidate.PersonID)
+			}
+			assert.Equal(t, tt.expectedCandidates, candidates)
+			if tt.expectedStatus == models.RecognitionStatusKnown {
+				require.NotNil(t, response.BestMatch)
+				assert.Equal(t, tt.expectedCandidates[0], response.BestMatch.PersonID)
+			} else {
+				assert.Nil(t, response.BestMatch)
+			}
+		})
+	}
+}
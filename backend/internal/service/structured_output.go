@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/jphacks/os_2522/backend/internal/llm"
+)
+
+// generateStructured asks the provider for JSON matching req.Schema and
+// passes it to decode, which may reject it with further checks. Output that
+// does not match is sent back to the model with the error, up to maxAttempts
+// times, after which an error wrapping llm.ErrUnavailable is returned.
+func generateStructured(ctx context.Context, provider llm.Provider, req llm.Request, maxAttempts int, decode func(data []byte) error) error {
+	prompt := req.Prompt
+	var invalid error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if invalid != nil {
+			req.Prompt = fmt.Sprintf("%s\n\n前回の出力はスキーマに従っていませんでした（%v）。修正したJSONのみを出力してください。", prompt, invalid)
+		}
+
+		resp, err := provider.Generate(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		invalid = decodeStructured(resp.Text, req.Schema, decode)
+		if invalid == nil {
+			return nil
+		}
+		log.Printf("Attempt %d/%d from %s/%s was malformed: %v", attempt, maxAttempts, resp.Provider, resp.Model, invalid)
+	}
+	return fmt.Errorf("%w: malformed output after %d attempt(s): %v", llm.ErrUnavailable, maxAttempts, invalid)
+}
+
+func decodeStructured(text string, schema *llm.Schema, decode func(data []byte) error) error {
+	value, err := llm.ParseJSON(text, schema)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return decode(data)
+}
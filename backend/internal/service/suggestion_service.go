@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/llm"
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+// suggestionInstructions is the system prompt for conversation suggestions
+const suggestionInstructions = "あなたは会話のきっかけを提案するアシスタントです。" +
+	"ユーザーがこれから会う人物について、過去の会話の要約、分かっている事実、約束事、前回会ってからの経過日数が与えられます。" +
+	"ユーザーが最初に話しかける一言（opener）を、自然で相手が話しやすいものから順に提案してください。" +
+	"期日が近い・過ぎた約束事や、前回話題になった出来事のその後を尋ねるものを優先し、久しぶりに会う場合はそのことにも触れてください。" +
+	"健康などの繊細な話題は慎重に扱ってください。" +
+	"reason には提案の根拠となった情報を簡潔に書いてください。" +
+	"出力は指定されたJSONスキーマに従うJSONのみとしてください。"
+
+// SuggestionConfig holds conversation suggestion parameters
+type SuggestionConfig struct {
+	// Count is the maximum number of openers suggested
+	Count int
+	// RecentEncounters is how many of the latest summarized encounters the suggestions are based on
+	RecentEncounters int
+	// MaxAttempts is how many times the model is asked when its output does not match the schema
+	MaxAttempts int
+}
+
+// GetSuggestionConfigFromEnv reads suggestion configuration from environment variables
+func GetSuggestionConfigFromEnv() *SuggestionConfig {
+	config := &SuggestionConfig{
+		Count:            utils.GetEnvInt("SUGGESTION_COUNT", 5),
+		RecentEncounters: utils.GetEnvInt("SUGGESTION_RECENT_ENCOUNTERS", 5),
+		MaxAttempts:      utils.GetEnvInt("SUGGESTION_MAX_ATTEMPTS", 3),
+	}
+	if config.Count < 1 {
+		config.Count = 1
+	}
+	if config.RecentEncounters < 1 {
+		config.RecentEncounters = 1
+	}
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	return config
+}
+
+// SuggestionService suggests how to open the next conversation with a person
+type SuggestionService struct {
+	provider       llm.Provider
+	personRepo     *repository.PersonRepository
+	encounterRepo  *repository.EncounterRepository
+	suggestionRepo *repository.SuggestionRepository
+	config         *SuggestionConfig
+	schema         *llm.Schema
+}
+
+// NewSuggestionService creates a new SuggestionService.
+// provider may be nil when no language model is configured; only cached suggestions are then returned.
+func NewSuggestionService(
+	provider llm.Provider,
+	personRepo *repository.PersonRepository,
+	encounterRepo *repository.EncounterRepository,
+	suggestionRepo *repository.SuggestionRepository,
+	config *SuggestionConfig,
+) *SuggestionService {
+	return &SuggestionService{
+		provider:       provider,
+		personRepo:     personRepo,
+		encounterRepo:  encounterRepo,
+		suggestionRepo: suggestionRepo,
+		config:         config,
+		schema: &llm.Schema{
+			Type:     llm.TypeObject,
+			Required: []string{"suggestions"},
+			Properties: map[string]*llm.Schema{
+				"suggestions": {
+					Type:     llm.TypeArray,
+					MaxItems: config.Count,
+					Items: &llm.Schema{
+						Type:     llm.TypeObject,
+						Required: []string{"opener", "reason"},
+						Properties: map[string]*llm.Schema{
+							"opener": {Type: llm.TypeString, MaxLength: 200},
+							"reason": {Type: llm.TypeString, MaxLength: 200},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// GetSuggestions returns ranked conversation openers for a person, based on
+// their recent encounter summaries, the facts learned in them and the time
+// since the last meeting. Suggestions are cached until a new encounter is
+// logged or summarized; refresh generates them again regardless.
+// A person without summarized encounters gets no suggestions.
+func (s *SuggestionService) GetSuggestions(ctx context.Context, personID string, refresh bool) (*models.SuggestionList, error) {
+	person, err := s.personRepo.FindByID(personID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("person not found")
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	list := &models.SuggestionList{
+		PersonID:    personID,
+		Items:       []models.Suggestion{},
+		GeneratedAt: now,
+	}
+	latest, err := s.encounterRepo.FindLatestByPersonID(personID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return list, nil
+		}
+		return nil, err
+	}
+	days := int(now.Sub(latest.RecognizedAt).Hours() / 24)
+	list.LastMetAt = &latest.RecognizedAt
+	list.DaysSinceLastMet = &days
+
+	recent, err := s.encounterRepo.FindRecentSummarized(personID, s.config.RecentEncounters)
+	if err != nil {
+		return nil, err
+	}
+	if len(recent) == 0 {
+		return list, nil
+	}
+
+	sourceKey := latest.EncounterID + "/" + recent[0].EncounterID
+	if !refresh {
+		cache, err := s.suggestionRepo.FindByPersonID(personID)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return nil, err
+		}
+		if err == nil && cache.SourceKey == sourceKey {
+			if err := json.Unmarshal([]byte(cache.Suggestions), &list.Items); err != nil {
+				return nil, fmt.Errorf("failed to decode cached suggestions: %w", err)
+			}
+			list.GeneratedAt = cache.GeneratedAt
+			list.Cached = true
+			return list, nil
+		}
+	}
+
+	if s.provider == nil {
+		return nil, fmt.Errorf("suggestions are not configured")
+	}
+	req := llm.Request{
+		System: suggestionInstructions,
+		Prompt: suggestionPrompt(person, recent, now),
+		Schema: s.schema,
+	}
+	var output struct {
+		Suggestions []models.Suggestion `json:"suggestions"`
+	}
+	err = generateStructured(ctx, s.provider, req, s.config.MaxAttempts, func(data []byte) error {
+		output.Suggestions = nil
+		if err := json.Unmarshal(data, &output); err != nil {
+			return err
+		}
+		if len(output.Suggestions) == 0 {
+			return fmt.Errorf("$.suggestions: must have at least 1 item")
+		}
+		for i := range output.Suggestions {
+			if strings.TrimSpace(output.Suggestions[i].Opener) == "" {
+				return fmt.Errorf("$.suggestions[%d].opener: must not be empty", i)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, llm.ErrUnavailable) {
+			return nil, fmt.Errorf("suggestions are unavailable: %w", err)
+		}
+		return nil, fmt.Errorf("failed to generate suggestions: %w", err)
+	}
+
+	for i := range output.Suggestions {
+		output.Suggestions[i].Rank = i + 1
+		output.Suggestions[i].Opener = strings.TrimSpace(output.Suggestions[i].Opener)
+		output.Suggestions[i].Reason = strings.TrimSpace(output.Suggestions[i].Reason)
+	}
+	list.Items = output.Suggestions
+
+	encoded, err := json.Marshal(list.Items)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.suggestionRepo.Save(&repository.SuggestionCacheEntity{
+		PersonID:    personID,
+		SourceKey:   sourceKey,
+		Suggestions: string(encoded),
+		GeneratedAt: now,
+	}); err != nil {
+		log.Printf("Warning: failed to cache suggestions for %s: %v", personID, err)
+	}
+	return list, nil
+}
+
+// suggestionPrompt describes what is known about a person. recent holds
+// their summarized encounters, newest first; facts and follow-ups are listed
+// newest first without repeats. The time since the last conversation is
+// measured from the latest summary rather than the latest encounter, which is
+// usually the recognition of the meeting the suggestions are wanted for.
+func suggestionPrompt(person *repository.PersonEntity, recent []repository.EncounterEntity, now time.Time) string {
+	const dateFormat = "2006-01-02"
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "相手: %s\n", person.Name)
+	if person.Note != nil && strings.TrimSpace(*person.Note) != "" {
+		fmt.Fprintf(&prompt, "メモ: %s\n", strings.TrimSpace(*person.Note))
+	}
+	fmt.Fprintf(&prompt, "今日の日付: %s\n", now.Format(dateFormat))
+	fmt.Fprintf(&prompt, "前回会話した日: %s（%d日前）\n", recent[0].RecognizedAt.Format(dateFormat), int(now.Sub(recent[0].RecognizedAt).Hours()/24))
+
+	var conversations, facts, followUps []string
+	seen := make(map[string]bool)
+	for _, encounter := range recent {
+		date := encounter.RecognizedAt.Format(dateFormat)
+		details := encounterDetails(encounter)
+		if details == nil {
+			conversations = append(conversations, fmt.Sprintf("- %s: %s", date, *encounter.Summary))
+			continue
+		}
+
+		line := fmt.Sprintf("- %s: %s", date, details.Headline)
+		if len(details.Topics) > 0 {
+			line += fmt.Sprintf("（話題: %s）", strings.Join(details.Topics, "、"))
+		}
+		conversations = append(conversations, line)
+		for _, fact := range details.Facts {
+			if !seen["fact:"+fact.Fact] {
+				seen["fact:"+fact.Fact] = true
+				facts = append(facts, fmt.Sprintf("- [%s] %s（%s）", fact.Category, fact.Fact, date))
+			}
+		}
+		for _, followUp := range details.FollowUps {
+			if seen["follow_up:"+followUp.Description] {
+				continue
+			}
+			seen["follow_up:"+followUp.Description] = true
+			line := fmt.Sprintf("- %s（%sの会話", followUp.Description, date)
+			if followUp.DueDate != nil {
+				line += "、期日: " + *followUp.DueDate
+			}
+			followUps = append(followUps, line+"）")
+		}
+	}
+
+	for _, section := range []struct {
+		title string
+		lines []string
+	}{
+		{"分かっている事実", facts},
+		{"約束事", followUps},
+		{"最近の会話（新しい順）", conversations},
+	} {
+		if len(section.lines) > 0 {
+			fmt.Fprintf(&prompt, "\n%s:\n%s\n", section.title, strings.Join(section.lines, "\n"))
+		}
+	}
+	return prompt.String()
+}
+
+// encounterDetails decodes the structured summary of an encounter, or
+// returns nil when it has none or it cannot be read
+func encounterDetails(encounter repository.EncounterEntity) *models.ConversationSummary {
+	if encounter.SummaryDetails == nil {
+		return nil
+	}
+	var details models.ConversationSummary
+	if err := json.Unmarshal([]byte(*encounter.SummaryDetails), &details); err != nil {
+		log.Printf("Warning: failed to decode summary details of encounter %s: %v", encounter.EncounterID, err)
+		return nil
+	}
+	return &details
+}
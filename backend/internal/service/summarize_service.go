@@ -2,23 +2,264 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
 
+	"github.com/jphacks/os_2522/backend/internal/llm"
+	"github.com/jphacks/os_2522/backend/internal/models"
 	"github.com/jphacks/os_2522/backend/internal/utils"
 )
 
+// summaryFields describes the fields of a summary to the model
+const summaryFields = "- headline: 会話の内容を表す1行の見出し\n" +
+	"- topics: 話題になった事柄の短い名詞句\n" +
+	"- facts: 相手の人物について分かった事実（家族、仕事、勤務先、学校、趣味、健康、好みなど）。会話から明らかなものだけを含め、推測はしないでください\n" +
+	"- follow_ups: 会話中に約束したこと・後でやると決めたこと。期日が分かる場合は due_date に YYYY-MM-DD で記入し、「来週」などの相対的な表現は会話の日付を基準に解決してください。分からない場合は null にしてください\n" +
+	"該当するものがない項目は空の配列にしてください。"
+
+// summaryInstructions is the system prompt for summaries. Transcripts arrive
+// as "speaker_1: ..." lines when speakers were told apart, after a line with
+// the date of the conversation used to resolve relative dates.
+const summaryInstructions = "以下は、ユーザーとある人物との会話の書き起こしです。次回会ったときに役立つよう、会話を構造化して要約してください。\n" +
+	summaryFields +
+	"行頭に「speaker_1:」のような話者ラベルがある場合は、誰の発言かを区別して要約してください。" +
+	"出力は指定されたJSONスキーマに従うJSONのみとしてください。"
+
+// summaryReduceInstructions is the system prompt for combining the
+// summaries of the chunks of a long conversation
+const summaryReduceInstructions = "以下は、ユーザーとある人物との長い会話を分割し、部分ごとに構造化して要約したもの（会話の順のJSON配列）です。" +
+	"これらを統合して、次回会ったときに役立つ会話全体の構造化要約を1つ作成してください。\n" +
+	summaryFields +
+	"部分をまたいで重複する話題・事実・約束は1つにまとめ、後の部分で訂正・変更された内容はそちらを優先してください。" +
+	"出力は指定されたJSONスキーマに従うJSONのみとしてください。"
+
+// ErrTextTooLong is returned for text that would take more than MaxChunks chunks
+var ErrTextTooLong = errors.New("text is too long")
+
+// summarySchema is the JSON schema that summaries must match
+var summarySchema = &llm.Schema{
+	Type:     llm.TypeObject,
+	Required: []string{"headline", "topics", "facts", "follow_ups"},
+	Properties: map[string]*llm.Schema{
+		"headline": {Type: llm.TypeString, MaxLength: 80},
+		"topics": {
+			Type:     llm.TypeArray,
+			MaxItems: 10,
+			Items:    &llm.Schema{Type: llm.TypeString, MaxLength: 40},
+		},
+		"facts": {
+			Type:     llm.TypeArray,
+			MaxItems: 20,
+			Items: &llm.Schema{
+				Type:     llm.TypeObject,
+				Required: []string{"category", "fact"},
+				Properties: map[string]*llm.Schema{
+					"category": {Type: llm.TypeString, Enum: models.FactCategories},
+					"fact":     {Type: llm.TypeString, MaxLength: 200},
+				},
+			},
+		},
+		"follow_ups": {
+			Type:     llm.TypeArray,
+			MaxItems: 10,
+			Items: &llm.Schema{
+				Type:     llm.TypeObject,
+				Required: []string{"description", "due_date"},
+				Properties: map[string]*llm.Schema{
+					"description": {Type: llm.TypeString, MaxLength: 200},
+					"due_date":    {Type: llm.TypeString, Format: "date", Nullable: true},
+				},
+			},
+		},
+	},
+}
+
+// SummarizeConfig holds summarization parameters
+type SummarizeConfig struct {
+	// MaxAttempts is how many times the model is asked for a summary when
+	// its output does not match the schema
+	MaxAttempts int
+	// ChunkTokens is the most estimated tokens of text sent in one prompt.
+	// Longer text is split into chunks that are summarized separately and
+	// then combined. 0 sends the whole text at once.
+	ChunkTokens int
+	// ChunkOverlapTokens is how much of the end of a chunk is repeated at
+	// the start of the next
+	ChunkOverlapTokens int
+	// MaxChunks bounds the length of text accepted, and with it the number
+	// of requests made for one summary; 0 for no limit
+	MaxChunks int
+}
+
+// GetSummarizeConfigFromEnv reads summarization configuration from environment variables
+func GetSummarizeConfigFromEnv() *SummarizeConfig {
+	config := &SummarizeConfig{
+		MaxAttempts:        utils.GetEnvInt("SUMMARY_MAX_ATTEMPTS", 3),
+		ChunkTokens:        utils.GetEnvInt("SUMMARY_CHUNK_TOKENS", 4000),
+		ChunkOverlapTokens: utils.GetEnvInt("SUMMARY_CHUNK_OVERLAP_TOKENS", 200),
+		MaxChunks:          utils.GetEnvInt("SUMMARY_MAX_CHUNKS", 16),
+	}
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	// 0 turns chunking off; tiny chunks would only multiply requests
+	if config.ChunkTokens < 0 {
+		config.ChunkTokens = 0
+	} else if config.ChunkTokens > 0 && config.ChunkTokens < 500 {
+		config.ChunkTokens = 500
+	}
+	if config.ChunkOverlapTokens < 0 {
+		config.ChunkOverlapTokens = 0
+	}
+	if config.MaxChunks < 0 {
+		config.MaxChunks = 0
+	}
+	return config
+}
+
 // SummarizeService handles text summarization
 type SummarizeService struct {
-	geminiClient *utils.GeminiClient
+	provider llm.Provider
+	config   *SummarizeConfig
 }
 
-// NewSummarizeService creates a new SummarizeService
-func NewSummarizeService(geminiClient *utils.GeminiClient) *SummarizeService {
+// NewSummarizeService creates a new SummarizeService.
+// provider may be nil when no language model is configured; summaries then fail.
+func NewSummarizeService(provider llm.Provider, config *SummarizeConfig) *SummarizeService {
 	return &SummarizeService{
-		geminiClient: geminiClient,
+		provider: provider,
+		config:   config,
+	}
+}
+
+// Summarize generates a structured summary of a conversation held on the given date.
+// Text longer than ChunkTokens is split into overlapping chunks, each chunk
+// is summarized on its own and the chunk summaries are then combined.
+// Output that does not match the schema is sent back to the model with the
+// validation error, up to MaxAttempts times per request.
+func (s *SummarizeService) Summarize(ctx context.Context, text string, date time.Time) (*models.ConversationSummary, *models.SummaryMetadata, error) {
+	if s.provider == nil {
+		return nil, nil, fmt.Errorf("summarization is not configured")
+	}
+
+	chunks := llm.SplitText(text, s.config.ChunkTokens, s.config.ChunkOverlapTokens)
+	metadata := &models.SummaryMetadata{
+		EstimatedTokens: llm.EstimateTokens(text),
+		Chunks:          len(chunks),
+	}
+	if s.config.MaxChunks > 0 && len(chunks) > s.config.MaxChunks {
+		return nil, nil, fmt.Errorf("%w: about %d tokens would take %d chunks, at most %d are allowed",
+			ErrTextTooLong, metadata.EstimatedTokens, len(chunks), s.config.MaxChunks)
+	}
+
+	dateLine := fmt.Sprintf("会話の日付: %s", date.Format("2006-01-02"))
+	var summary *models.ConversationSummary
+	var err error
+	if len(chunks) == 1 {
+		summary, err = s.generate(ctx, summaryInstructions, dateLine+"\n\n"+text)
+	} else {
+		summary, metadata.ReduceRounds, err = s.mapReduce(ctx, chunks, dateLine)
+	}
+	if err != nil {
+		if errors.Is(err, llm.ErrUnavailable) {
+			return nil, nil, fmt.Errorf("summarization is unavailable: %w", err)
+		}
+		return nil, nil, fmt.Errorf("failed to generate summary: %w", err)
 	}
+	return summary, metadata, nil
 }
 
-// Summarize generates a summary of the given text
-func (s *SummarizeService) Summarize(ctx context.Context, text string) (string, error) {
-	return s.geminiClient.Summarize(ctx, text)
+// mapReduce summarizes each chunk and combines the summaries. Neighbouring
+// summaries are combined in groups that fit in a chunk, so a very long
+// conversation may take several rounds; it returns how many.
+func (s *SummarizeService) mapReduce(ctx context.Context, chunks []string, dateLine string) (*models.ConversationSummary, int, error) {
+	summaries := make([]*models.ConversationSummary, len(chunks))
+	for i, chunk := range chunks {
+		prompt := fmt.Sprintf("%s\n（長い会話を分割した %d/%d 番目の部分です）\n\n%s", dateLine, i+1, len(chunks), chunk)
+		summary, err := s.generate(ctx, summaryInstructions, prompt)
+		if err != nil {
+			return nil, 0, fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
+		}
+		summaries[i] = summary
+	}
+
+	rounds := 0
+	for len(summaries) > 1 {
+		rounds++
+		var combined []*models.ConversationSummary
+		for start := 0; start < len(summaries); {
+			end, tokens := start, 0
+			for end < len(summaries) {
+				t := estimateSummaryTokens(summaries[end])
+				if end-start >= 2 && tokens+t > s.config.ChunkTokens {
+					break
+				}
+				tokens += t
+				end++
+			}
+			if end-start == 1 {
+				combined = append(combined, summaries[start])
+				break
+			}
+
+			encoded, err := json.Marshal(summaries[start:end])
+			if err != nil {
+				return nil, 0, err
+			}
+			summary, err := s.generate(ctx, summaryReduceInstructions, dateLine+"\n\n"+string(encoded))
+			if err != nil {
+				return nil, 0, fmt.Errorf("reduce round %d: %w", rounds, err)
+			}
+			combined = append(combined, summary)
+			start = end
+		}
+		summaries = combined
+	}
+	log.Printf("Summarized %d chunks in %d reduce round(s)", len(chunks), rounds)
+	return summaries[0], rounds, nil
+}
+
+func estimateSummaryTokens(summary *models.ConversationSummary) int {
+	encoded, _ := json.Marshal(summary)
+	return llm.EstimateTokens(string(encoded))
+}
+
+// generate requests a single summary matching summarySchema
+func (s *SummarizeService) generate(ctx context.Context, system, prompt string) (*models.ConversationSummary, error) {
+	req := llm.Request{
+		System: system,
+		Prompt: prompt,
+		Schema: summarySchema,
+	}
+	var summary models.ConversationSummary
+	err := generateStructured(ctx, s.provider, req, s.config.MaxAttempts, func(data []byte) error {
+		summary = models.ConversationSummary{}
+		if err := json.Unmarshal(data, &summary); err != nil {
+			return err
+		}
+		summary.Headline = strings.TrimSpace(summary.Headline)
+		if summary.Headline == "" {
+			return fmt.Errorf("$.headline: must not be empty")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep empty lists as [] rather than null in responses
+	if summary.Topics == nil {
+		summary.Topics = []string{}
+	}
+	if summary.Facts == nil {
+		summary.Facts = []models.PersonFact{}
+	}
+	if summary.FollowUps == nil {
+		summary.FollowUps = []models.FollowUp{}
+	}
+	return &summary, nil
 }
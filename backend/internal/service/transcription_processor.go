@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/storage"
+	"github.com/jphacks/os_2522/backend/internal/transcription"
+	"github.com/jphacks/os_2522/backend/internal/worker"
+)
+
+// Summarizer generates a structured summary of a conversation held on the given date
+type Summarizer interface {
+	Summarize(ctx context.Context, text string, date time.Time) (*models.ConversationSummary, *models.SummaryMetadata, error)
+}
+
+// TranscriptionProcessor runs transcription jobs for the background worker
+type TranscriptionProcessor struct {
+	audioStore  storage.Store
+	transcriber transcription.Transcriber
+	summarizer  Summarizer
+}
+
+// NewTranscriptionProcessor creates a new TranscriptionProcessor.
+// transcriber may be nil when no backend is available; jobs then fail instead of waiting forever.
+// summarizer may be nil, in which case jobs finish with an empty summary, as
+// they do when the transcript is too long to summarize.
+func NewTranscriptionProcessor(audioStore storage.Store, transcriber transcription.Transcriber, summarizer Summarizer) *TranscriptionProcessor {
+	return &TranscriptionProcessor{
+		audioStore:  audioStore,
+		transcriber: transcriber,
+		summarizer:  summarizer,
+	}
+}
+
+// Process transcribes the job's audio and summarizes the transcript
+func (p *TranscriptionProcessor) Process(ctx context.Context, job *repository.JobEntity) (*worker.Result, error) {
+	if p.transcriber == nil {
+		return nil, worker.Permanent(fmt.Errorf("transcription backend is not configured"))
+	}
+	if job.AudioKey == nil || *job.AudioKey == "" {
+		return nil, worker.Permanent(fmt.Errorf("job has no audio"))
+	}
+
+	worker.ReportStage(ctx, models.JobStageTranscribing)
+	audioPath, err := p.fetchAudio(ctx, *job.AudioKey)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(audioPath)
+
+	output, err := p.transcriber.Transcribe(ctx, audioPath)
+	if err != nil {
+		if errors.Is(err, transcription.ErrInvalidAudio) {
+			return nil, worker.Permanent(fmt.Errorf("transcription failed: %w", err))
+		}
+		return nil, fmt.Errorf("transcription failed: %w", err)
+	}
+
+	result := &worker.Result{
+		Transcript:  output.Text,
+		Words:       output.Words,
+		Segments:    output.Segments,
+		Language:    output.Language,
+		DurationSec: output.DurationSec,
+	}
+	if len(result.Segments) == 0 && strings.TrimSpace(output.Text) != "" {
+		result.Segments = []models.TranscriptSegment{{
+			Start: 0,
+			End:   output.DurationSec,
+			Text:  strings.TrimSpace(output.Text),
+		}}
+	}
+
+	if p.summarizer != nil && strings.TrimSpace(output.Text) != "" {
+		worker.ReportStage(ctx, models.JobStageSummarizing)
+		text := speakerText(result.Segments)
+		if text == "" {
+			text = output.Text
+		}
+		summary, _, err := p.summarizer.Summarize(ctx, text, job.CreatedAt)
+		switch {
+		case errors.Is(err, ErrTextTooLong):
+			// Retrying will not make it shorter, and the transcript is still worth keeping
+			log.Printf("Job %s finishes without a summary: %v", job.JobID, err)
+		case err != nil:
+			return nil, fmt.Errorf("summarization failed: %w", err)
+		default:
+			result.Summary = summary.Headline
+			result.SummaryDetails = summary
+		}
+	}
+
+	return result, nil
+}
+
+// speakerText renders a transcript as one line per speaker turn, such as
+// "speaker_1: Hello. How are you?", so the summarizer can tell who said what.
+// It returns an empty string when no segment has a speaker.
+func speakerText(segments []models.TranscriptSegment) string {
+	labelled := false
+	for _, segment := range segments {
+		if segment.Speaker != "" {
+			labelled = true
+			break
+		}
+	}
+	if !labelled {
+		return ""
+	}
+
+	var text strings.Builder
+	previous := ""
+	for i, segment := range segments {
+		speaker := segment.Speaker
+		if speaker == "" {
+			speaker = "unknown"
+		}
+		if i == 0 || speaker != previous {
+			if i > 0 {
+				text.WriteString("\n")
+			}
+			text.WriteString(speaker + ":")
+		}
+		text.WriteString(" " + segment.Text)
+		previous = speaker
+	}
+	return text.String()
+}
+
+// fetchAudio copies a stored recording to a temporary file for the transcriber,
+// verifying its checksum on the way
+func (p *TranscriptionProcessor) fetchAudio(ctx context.Context, key string) (string, error) {
+	src, err := p.audioStore.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			return "", worker.Permanent(fmt.Errorf("audio is not available: %w", err))
+		}
+		return "", fmt.Errorf("failed to fetch audio: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp("", "transcribe-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	_, err = io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+		if errors.Is(err, storage.ErrChecksumMismatch) {
+			return "", worker.Permanent(fmt.Errorf("stored audio is corrupted: %w", err))
+		}
+		return "", fmt.Errorf("failed to fetch audio: %w", err)
+	}
+	return dst.Name(), nil
+}
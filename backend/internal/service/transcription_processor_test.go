@@ -0,0 +1,86 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/storage"
+	"github.com/jphacks/os_2522/backend/internal/transcription"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubTranscriber struct {
+	result *transcription.Result
+}
+
+func (t *stubTranscriber) Name() string { return "stub" }
+func (t *stubTranscriber) Transcribe(ctx context.Context, audioPath string) (*transcription.Result, error) {
+	return t.result, nil
+}
+
+type stubSummarizer struct {
+	err error
+}
+
+func (s *stubSummarizer) Summarize(ctx context.Context, text string, date time.Time) (*models.ConversationSummary, *models.SummaryMetadata, error) {
+	if s.err != nil {
+		return nil, nil, s.err
+	}
+	summary := &models.ConversationSummary{Headline: "要約", Topics: []string{}, Facts: []models.PersonFact{}, FollowUps: []models.FollowUp{}}
+	return summary, &models.SummaryMetadata{Chunks: 1}, nil
+}
+
+func TestTranscriptionProcessor_Summary(t *testing.T) {
+	store, err := storage.NewLocalStore(storage.LocalConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+	object, err := store.Put(context.Background(), bytes.NewReader([]byte("audio")))
+	require.NoError(t, err)
+
+	transcriber := &stubTranscriber{result: &transcription.Result{
+		Text:        "こんにちは",
+		Language:    "ja",
+		DurationSec: 2,
+		Segments:    []models.TranscriptSegment{{Start: 0, End: 2, Text: "こんにちは", Speaker: "speaker_1"}},
+	}}
+	job := &repository.JobEntity{JobID: "j-1", AudioKey: &object.Key, CreatedAt: time.Now()}
+
+	tests := []struct {
+		name            string
+		summarizer      Summarizer
+		expectedSummary string
+		expectedErr     string
+	}{
+		{name: "summarized", summarizer: &stubSummarizer{}, expectedSummary: "要約"},
+		{
+			name:       "too long to summarize keeps the transcript",
+			summarizer: &stubSummarizer{err: fmt.Errorf("%w: about 90000 tokens would take 23 chunks, at most 16 are allowed", ErrTextTooLong)},
+		},
+		{
+			name:        "summarization unavailable is retried",
+			summarizer:  &stubSummarizer{err: fmt.Errorf("summarization is unavailable: busy")},
+			expectedErr: "summarization failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewTranscriptionProcessor(store, transcriber, tt.summarizer)
+			result, err := processor.Process(context.Background(), job)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "こんにちは", result.Transcript)
+			assert.Len(t, result.Segments, 1)
+			assert.Equal(t, tt.expectedSummary, result.Summary)
+			assert.Equal(t, tt.expectedSummary == "", result.SummaryDetails == nil)
+		})
+	}
+}
@@ -0,0 +1,510 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jphacks/os_2522/backend/internal/audio"
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/storage"
+	"github.com/jphacks/os_2522/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+// UploadConfig holds resumable upload parameters. The size of the whole
+// upload is limited by AudioConfig.
+type UploadConfig struct {
+	// MaxChunkSize is the largest chunk accepted in one request, in bytes
+	MaxChunkSize int64
+	// Expiry is how long an upload is kept after its last chunk
+	Expiry time.Duration
+	// AssembleLease is how long completing an upload may take before another
+	// request may take it over or delete the upload. It must exceed the time
+	// to join and probe the largest upload.
+	AssembleLease time.Duration
+}
+
+// GetUploadConfigFromEnv reads upload configuration from environment variables
+func GetUploadConfigFromEnv() *UploadConfig {
+	return &UploadConfig{
+		MaxChunkSize:  int64(utils.GetEnvInt("UPLOAD_MAX_CHUNK_SIZE_MB", 16)) << 20,
+		Expiry:        utils.GetEnvDuration("UPLOAD_EXPIRY", 24*time.Hour),
+		AssembleLease: utils.GetEnvDuration("UPLOAD_ASSEMBLE_LEASE", 30*time.Minute),
+	}
+}
+
+var checksumPattern = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// errChunkTooLarge stops storing a chunk that exceeds the chunk size limit
+var errChunkTooLarge = errors.New("chunk is too large")
+
+// UploadService handles resumable audio uploads. Every chunk is stored as an
+// object of its own; completing the upload joins them into one object and
+// queues a transcription job for it.
+type UploadService struct {
+	uploadRepo *repository.UploadRepository
+	personRepo *repository.PersonRepository
+	audioStore storage.Store
+	jobService *JobService
+	config     *UploadConfig
+}
+
+// NewUploadService creates a new UploadService
+func NewUploadService(uploadRepo *repository.UploadRepository, personRepo *repository.PersonRepository, audioStore storage.Store, jobService *JobService, config *UploadConfig) *UploadService {
+	if config.AssembleLease <= 0 {
+		config.AssembleLease = 30 * time.Minute
+	}
+	return &UploadService{
+		uploadRepo: uploadRepo,
+		personRepo: personRepo,
+		audioStore: audioStore,
+		jobService: jobService,
+		config:     config,
+	}
+}
+
+// CreateUpload starts a resumable upload
+func (s *UploadService) CreateUpload(ctx context.Context, req *models.UploadCreate) (*models.Upload, error) {
+	if req.Length > s.jobService.config.MaxSize {
+		return nil, fmt.Errorf("audio is too large")
+	}
+	if req.Checksum != nil && !checksumPattern.MatchString(*req.Checksum) {
+		return nil, fmt.Errorf("invalid checksum")
+	}
+	if req.PersonID != nil {
+		if err := s.checkPerson(*req.PersonID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.jobService.validateWebhookURL(ctx, req.WebhookURL); err != nil {
+		return nil, err
+	}
+
+	s.purgeExpired()
+
+	now := time.Now()
+	entity := &repository.UploadEntity{
+		UploadID:   fmt.Sprintf("u-%s", uuid.New().String()[:8]),
+		PersonID:   req.PersonID,
+		WebhookURL: req.WebhookURL,
+		Length:     req.Length,
+		Checksum:   req.Checksum,
+		Status:     repository.UploadStatusUploading,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		ExpiresAt:  now.Add(s.config.Expiry),
+	}
+	if err := s.uploadRepo.Create(entity); err != nil {
+		return nil, err
+	}
+	return toUpload(entity), nil
+}
+
+// GetUpload retrieves an upload, including how many bytes have been received
+func (s *UploadService) GetUpload(uploadID string) (*models.Upload, error) {
+	entity, err := s.findUpload(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	return toUpload(entity), nil
+}
+
+// AppendChunk stores the chunk read from r at offset, which must equal the
+// number of bytes received so far. An empty chunk leaves the upload unchanged.
+func (s *UploadService) AppendChunk(ctx context.Context, uploadID string, offset int64, r io.Reader) (*models.Upload, error) {
+	entity, err := s.findUpload(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if entity.Status != repository.UploadStatusUploading {
+		return nil, fmt.Errorf("upload is not in progress")
+	}
+	if offset != entity.Received {
+		return nil, fmt.Errorf("upload offset mismatch: expected %d", entity.Received)
+	}
+
+	body := bufio.NewReader(r)
+	if _, err := body.Peek(1); err == io.EOF {
+		return toUpload(entity), nil
+	}
+
+	remaining := entity.Length - entity.Received
+	limit := s.config.MaxChunkSize
+	if remaining < limit {
+		limit = remaining
+	}
+	object, end, err := s.jobService.putAudio(ctx, &limitReader{r: body, remaining: limit})
+	if err != nil {
+		if errors.Is(err, errChunkTooLarge) {
+			if limit == remaining {
+				return nil, fmt.Errorf("chunk exceeds upload length")
+			}
+			return nil, errChunkTooLarge
+		}
+		return nil, fmt.Errorf("failed to store chunk: %w", err)
+	}
+	defer end()
+
+	entity, err = s.uploadRepo.AppendChunk(&repository.UploadChunkEntity{
+		UploadID:    uploadID,
+		ChunkOffset: offset,
+		Size:        object.Size,
+		ChunkKey:    object.Key,
+	}, time.Now().Add(s.config.Expiry))
+	if err != nil {
+		s.jobService.releaseAudio(object.Key)
+		if err == repository.ErrUploadConflict {
+			// Another request wrote at this offset first
+			return nil, s.offsetMismatch(uploadID)
+		}
+		return nil, err
+	}
+	return toUpload(entity), nil
+}
+
+// CompleteUpload joins the chunks of a fully received upload, verifies its
+// checksum and format and queues a transcription job for it. Uploads that
+// fail verification are discarded. Completing an upload again
+// returns the job created the first time. A completion that stalled for
+// longer than the assemble lease, such as after a crash, is started anew.
+func (s *UploadService) CompleteUpload(ctx context.Context, uploadID string) (*models.Job, error) {
+	entity, err := s.findUpload(uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep other requests from changing or completing the upload meanwhile
+	claim := map[string]interface{}{
+		"expires_at": time.Now().Add(s.config.Expiry),
+	}
+	switch {
+	case entity.JobID != nil:
+		// The job was queued, though marking the upload completed may have failed
+		if entity.Status == repository.UploadStatusAssembling {
+			s.finishCompletion(uploadID)
+		}
+		return s.jobService.GetJob(*entity.JobID)
+	case entity.Status == repository.UploadStatusAssembling:
+		err = s.uploadRepo.ReclaimAssembling(uploadID, time.Now().Add(-s.config.AssembleLease), claim)
+	case entity.Status != repository.UploadStatusUploading:
+		return nil, fmt.Errorf("upload is being completed")
+	case entity.Received < entity.Length:
+		return nil, fmt.Errorf("upload is incomplete")
+	default:
+		err = s.uploadRepo.Transition(uploadID, repository.UploadStatusUploading, repository.UploadStatusAssembling, claim)
+	}
+	if err == repository.ErrUploadConflict {
+		return nil, fmt.Errorf("upload is being completed")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if entity.PersonID != nil {
+		if err := s.checkPerson(*entity.PersonID); err != nil {
+			// The person was deleted meanwhile; their audio must not be kept
+			s.discard(uploadID)
+			return nil, err
+		}
+	}
+
+	// The assembled object is kept until the job referencing it is queued
+	object, end, err := s.assemble(ctx, uploadID)
+	if err != nil {
+		s.reopen(uploadID)
+		return nil, err
+	}
+	defer end()
+	if object.Size != entity.Length || (entity.Checksum != nil && object.Checksum != *entity.Checksum) {
+		// A corrupted chunk cannot be rewritten, so the client starts over
+		log.Printf("Upload %s failed verification: got %s (%d bytes)", uploadID, object.Checksum, object.Size)
+		s.discard(uploadID)
+		s.jobService.releaseAudio(object.Key)
+		return nil, fmt.Errorf("checksum mismatch")
+	}
+
+	info, rejected, err := s.probe(ctx, object.Key)
+	if err != nil {
+		if rejected {
+			s.discard(uploadID)
+		} else {
+			s.reopen(uploadID)
+		}
+		s.jobService.releaseAudio(object.Key)
+		return nil, err
+	}
+
+	// The job is recorded on the upload together with queueing it, so a
+	// retried completion finds it whatever fails afterwards
+	job, err := s.jobService.queueJob(entity.PersonID, entity.WebhookURL, object, info, func(tx *gorm.DB, jobID string) error {
+		return s.uploadRepo.AttachJob(tx, uploadID, jobID)
+	})
+	if err != nil {
+		s.jobService.releaseAudio(object.Key)
+		if errors.Is(err, repository.ErrUploadConflict) {
+			// The upload was taken over or deleted after its lease ran out
+			return s.takenOver(uploadID)
+		}
+		s.reopen(uploadID)
+		return nil, err
+	}
+
+	s.finishCompletion(uploadID)
+	return job, nil
+}
+
+// finishCompletion marks an upload whose job was queued completed and
+// deletes its chunks. Failures are logged: the upload already counts as
+// completed, and the next completion request tries again.
+func (s *UploadService) finishCompletion(uploadID string) {
+	if err := s.uploadRepo.Transition(uploadID, repository.UploadStatusAssembling, repository.UploadStatusCompleted, nil); err != nil {
+		log.Printf("Warning: failed to mark upload %s completed: %v", uploadID, err)
+		return
+	}
+	keys, err := s.uploadRepo.DeleteChunks(uploadID)
+	if err != nil {
+		log.Printf("Warning: failed to delete chunks of upload %s: %v", uploadID, err)
+	}
+	for _, key := range keys {
+		s.jobService.releaseAudio(key)
+	}
+}
+
+// takenOver reports the outcome of an upload that another request completed
+// or deleted while this one was still completing it
+func (s *UploadService) takenOver(uploadID string) (*models.Job, error) {
+	entity, err := s.findUpload(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if entity.JobID != nil {
+		return s.jobService.GetJob(*entity.JobID)
+	}
+	return nil, fmt.Errorf("upload is being completed")
+}
+
+// DeleteUpload aborts an upload and deletes its chunks. An upload that is
+// being completed can only be deleted once its assemble lease ran out.
+func (s *UploadService) DeleteUpload(ctx context.Context, uploadID string) error {
+	entity, err := s.findUpload(uploadID)
+	if err != nil {
+		return err
+	}
+	if entity.Status == repository.UploadStatusAssembling && entity.JobID == nil &&
+		entity.UpdatedAt.After(time.Now().Add(-s.config.AssembleLease)) {
+		return fmt.Errorf("upload is being completed")
+	}
+	keys, err := s.uploadRepo.Delete(uploadID)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		s.jobService.releaseAudio(key)
+	}
+	return nil
+}
+
+// findUpload retrieves an upload that has not expired
+func (s *UploadService) findUpload(uploadID string) (*repository.UploadEntity, error) {
+	entity, err := s.uploadRepo.FindByID(uploadID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("upload not found")
+		}
+		return nil, err
+	}
+	if time.Now().After(entity.ExpiresAt) {
+		return nil, fmt.Errorf("upload not found")
+	}
+	return entity, nil
+}
+
+func (s *UploadService) checkPerson(personID string) error {
+	if _, err := s.personRepo.FindByID(personID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("person not found")
+		}
+		return err
+	}
+	return nil
+}
+
+// offsetMismatch reports the current offset of an upload that moved on
+func (s *UploadService) offsetMismatch(uploadID string) error {
+	entity, err := s.findUpload(uploadID)
+	if err != nil {
+		return err
+	}
+	if entity.Status != repository.UploadStatusUploading {
+		return fmt.Errorf("upload is not in progress")
+	}
+	return fmt.Errorf("upload offset mismatch: expected %d", entity.Received)
+}
+
+// assemble stores the concatenated chunks of an upload as one object. As
+// with JobService.putAudio, end must be called once the object is referenced.
+func (s *UploadService) assemble(ctx context.Context, uploadID string) (object *storage.Object, end func(), err error) {
+	chunks, err := s.uploadRepo.FindChunks(uploadID)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := &chunkReader{ctx: ctx, store: s.audioStore, chunks: chunks}
+	defer r.Close()
+
+	object, end, err = s.jobService.putAudio(ctx, r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to assemble upload: %w", err)
+	}
+	return object, end, nil
+}
+
+// probe identifies an assembled recording. rejected reports whether the
+// error is caused by the recording itself, so the upload cannot succeed.
+func (s *UploadService) probe(ctx context.Context, key string) (info *audio.Info, rejected bool, err error) {
+	rc, err := s.audioStore.Get(ctx, key)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read assembled upload: %w", err)
+	}
+	defer rc.Close()
+
+	info, err = s.jobService.probeAudio(rc)
+	if err != nil {
+		msg := err.Error()
+		return nil, strings.HasPrefix(msg, "unsupported audio") || msg == "audio is too long", err
+	}
+	return info, false, nil
+}
+
+// reopen returns an upload to uploading after a failed completion so it can be retried
+func (s *UploadService) reopen(uploadID string) {
+	if err := s.uploadRepo.Transition(uploadID, repository.UploadStatusAssembling, repository.UploadStatusUploading, nil); err != nil {
+		log.Printf("Warning: failed to reopen upload %s: %v", uploadID, err)
+	}
+}
+
+// discard deletes an upload and its chunks, logging failures
+func (s *UploadService) discard(uploadID string) {
+	keys, err := s.uploadRepo.Delete(uploadID)
+	if err != nil {
+		log.Printf("Warning: failed to delete upload %s: %v", uploadID, err)
+		return
+	}
+	for _, key := range keys {
+		s.jobService.releaseAudio(key)
+	}
+}
+
+// purgeExpired deletes a batch of expired uploads. It runs whenever an upload
+// is created, which keeps abandoned chunks from piling up.
+func (s *UploadService) purgeExpired() {
+	expired, err := s.uploadRepo.FindExpired(time.Now(), 20)
+	if err != nil {
+		log.Printf("Warning: failed to find expired uploads: %v", err)
+		return
+	}
+	for _, upload := range expired {
+		s.discard(upload.UploadID)
+	}
+}
+
+func toUpload(entity *repository.UploadEntity) *models.Upload {
+	status := models.UploadStatus(entity.Status)
+	if entity.JobID != nil {
+		// Marking the upload completed may have failed after its job was queued
+		status = models.UploadStatus(repository.UploadStatusCompleted)
+	}
+	return &models.Upload{
+		UploadID:  entity.UploadID,
+		Status:    status,
+		Offset:    entity.Received,
+		Length:    entity.Length,
+		PersonID:  entity.PersonID,
+		Checksum:  entity.Checksum,
+		JobID:     entity.JobID,
+		CreatedAt: entity.CreatedAt,
+		ExpiresAt: entity.ExpiresAt,
+	}
+}
+
+// limitReader reads at most remaining bytes and fails with errChunkTooLarge
+// if the underlying reader has more
+type limitReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		var probe [1]byte
+		if n, _ := l.r.Read(probe[:]); n > 0 {
+			return 0, errChunkTooLarge
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// chunkReader reads the chunks of an upload one after another
+type chunkReader struct {
+	ctx     context.Context
+	store   storage.Store
+	chunks  []repository.UploadChunkEntity
+	current io.ReadCloser
+	next    int64 // Offset the next chunk must start at
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.chunks) == 0 {
+				return 0, io.EOF
+			}
+			chunk := r.chunks[0]
+			r.chunks = r.chunks[1:]
+			if chunk.ChunkOffset != r.next {
+				return 0, fmt.Errorf("chunk at offset %d is missing", r.next)
+			}
+			r.next += chunk.Size
+			rc, err := r.store.Get(r.ctx, chunk.ChunkKey)
+			if err != nil {
+				return 0, fmt.Errorf("failed to open chunk at offset %d: %w", chunk.ChunkOffset, err)
+			}
+			r.current = rc
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Close closes the chunk being read, if any
+func (r *chunkReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/webhook"
+	"gorm.io/gorm"
+)
+
+// WebhookService records webhook notifications for finished jobs. It is a
+// worker.CompletionHook: deliveries are written in the same transaction as
+// the job outcome and sent by a webhook.Dispatcher.
+type WebhookService struct {
+	deliveryRepo *repository.WebhookDeliveryRepository
+	config       webhook.Config
+	notifier     JobNotifier
+}
+
+// NewWebhookService creates a new WebhookService. notifier wakes the dispatcher and may be nil.
+func NewWebhookService(deliveryRepo *repository.WebhookDeliveryRepository, config webhook.Config, notifier JobNotifier) *WebhookService {
+	return &WebhookService{
+		deliveryRepo: deliveryRepo,
+		config:       config,
+		notifier:     notifier,
+	}
+}
+
+// ValidateTarget checks that url can receive webhooks
+func (s *WebhookService) ValidateTarget(ctx context.Context, url string) error {
+	if s == nil || len(s.config.SigningKeys) == 0 {
+		return fmt.Errorf("webhooks are not configured")
+	}
+	return webhook.ValidateTarget(ctx, url, s.config.AllowPrivateTargets)
+}
+
+// JobFinishing queues a delivery for jobs that have a webhook URL.
+// Nothing is queued while no signing key is configured, as receivers could not verify it.
+func (s *WebhookService) JobFinishing(tx *gorm.DB, entity *repository.JobEntity) error {
+	if entity.WebhookURL == nil || *entity.WebhookURL == "" || len(s.config.SigningKeys) == 0 {
+		return nil
+	}
+
+	event := webhook.EventJobSucceeded
+	if entity.Status == repository.JobStatusFailed {
+		event = webhook.EventJobFailed
+	}
+	job, err := toJob(entity)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	delivery := &repository.WebhookDeliveryEntity{
+		DeliveryID:    fmt.Sprintf("d-%s", uuid.New().String()[:8]),
+		JobID:         entity.JobID,
+		Event:         event,
+		URL:           *entity.WebhookURL,
+		Status:        repository.WebhookDeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	payload, err := json.Marshal(models.WebhookPayload{
+		DeliveryID: delivery.DeliveryID,
+		Event:      event,
+		CreatedAt:  now,
+		Job:        *job,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	delivery.Payload = string(payload)
+
+	return repository.NewWebhookDeliveryRepository(tx).Create(delivery)
+}
+
+// JobFinished wakes the dispatcher once the delivery has been committed
+func (s *WebhookService) JobFinished(entity *repository.JobEntity) {
+	if s.notifier != nil && entity.WebhookURL != nil {
+		s.notifier.Notify()
+	}
+}
+
+// ListDeliveries returns the deliveries of a job, oldest first
+func (s *WebhookService) ListDeliveries(jobID string) (*models.WebhookDeliveryList, error) {
+	entities, err := s.deliveryRepo.FindByJobID(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.WebhookDelivery, 0, len(entities))
+	for _, entity := range entities {
+		delivery := models.WebhookDelivery{
+			DeliveryID:     entity.DeliveryID,
+			Event:          entity.Event,
+			URL:            entity.URL,
+			Status:         models.WebhookDeliveryStatus(entity.Status),
+			Attempts:       entity.Attempts,
+			LastStatusCode: entity.LastStatusCode,
+			LastError:      entity.LastError,
+			CreatedAt:      entity.CreatedAt,
+			DeliveredAt:    entity.DeliveredAt,
+		}
+		if entity.Status == repository.WebhookDeliveryPending {
+			nextAttemptAt := entity.NextAttemptAt
+			delivery.NextAttemptAt = &nextAttemptAt
+		}
+		items = append(items, delivery)
+	}
+	return &models.WebhookDeliveryList{Items: items}, nil
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LocalConfig configures the filesystem backend
+type LocalConfig struct {
+	// Dir is the root directory; objects live under Dir/sha256/<xx>/<digest>
+	Dir string
+}
+
+// LocalStore keeps objects on the local filesystem
+type LocalStore struct {
+	dir string
+}
+
+// NewLocalStore creates a LocalStore, creating its directory if needed
+func NewLocalStore(config LocalConfig) (*LocalStore, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("storage directory is required")
+	}
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &LocalStore{dir: config.Dir}, nil
+}
+
+// Name returns the backend name
+func (s *LocalStore) Name() string {
+	return BackendLocal
+}
+
+// Put writes the content to a temporary file while hashing it, then moves it
+// into place. The rename is atomic, so readers never see partial objects.
+func (s *LocalStore) Put(ctx context.Context, r io.Reader) (*Object, error) {
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	object := newObject(h.Sum(nil), size)
+	path := s.path(object.Key)
+	if _, err := os.Stat(path); err == nil {
+		now := time.Now()
+		if err := os.Chtimes(path, now, now); err != nil {
+			return nil, fmt.Errorf("failed to refresh object: %w", err)
+		}
+		return object, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create object directory: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("failed to store object: %w", err)
+	}
+	return object, nil
+}
+
+// Get opens an object for reading
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	digest, err := keyDigest(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(s.path(key))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return newVerifyingReader(f, digest), nil
+}
+
+// Delete removes an object
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	if _, err := keyDigest(key); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List walks the object directories. Temporary files and anything else that
+// is not an object are skipped.
+func (s *LocalStore) List(ctx context.Context, fn func(ObjectInfo) error) error {
+	root := filepath.Join(s.dir, "sha256")
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil // Nothing stored yet, or deleted meanwhile
+		}
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if _, err := keyDigest(key); err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return fn(ObjectInfo{Key: key, ModTime: info.ModTime()})
+	})
+}
+
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
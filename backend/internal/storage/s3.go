@@ -0,0 +1,356 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config configures the S3-compatible backend (AWS S3, MinIO, Cloudflare R2, ...)
+type S3Config struct {
+	// Endpoint is the service URL, e.g. https://s3.ap-northeast-1.amazonaws.com or http://localhost:9000.
+	// Buckets are addressed path-style, which every S3-compatible service supports.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Timeout         time.Duration
+}
+
+// S3Store keeps objects in an S3-compatible bucket. Requests are signed with
+// Signature Version 4 over the SHA-256 of the body, so the service rejects
+// uploads that were corrupted in transit.
+type S3Store struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+var prefixPattern = regexp.MustCompile(`^[A-Za-z0-9._\-/]*$`)
+
+// NewS3Store creates an S3Store
+func NewS3Store(config S3Config) (*S3Store, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", config.Endpoint)
+	}
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 credentials are required")
+	}
+	if !prefixPattern.MatchString(config.Prefix) {
+		return nil, fmt.Errorf("invalid S3 prefix %q", config.Prefix)
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Minute
+	}
+
+	return &S3Store{
+		config:   config,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: config.Timeout},
+		now:      time.Now,
+	}, nil
+}
+
+// Name returns the backend name
+func (s *S3Store) Name() string {
+	return BackendS3
+}
+
+// Put spools the content to a temporary file to learn its digest, then uploads
+// it unless an object with the same content already exists. An existing
+// object is copied onto itself instead, which refreshes its modification time.
+func (s *S3Store) Put(ctx context.Context, r io.Reader) (*Object, error) {
+	tmp, err := os.CreateTemp("", "s3-upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to buffer object: %w", err)
+	}
+	sum := h.Sum(nil)
+	object := newObject(sum, size)
+
+	exists, err := s.exists(ctx, object.Key)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		if err := s.touch(ctx, object.Key); err != nil {
+			return nil, err
+		}
+		return object, nil
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(object.Key), io.NopCloser(tmp))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := s.do(req, hex.EncodeToString(sum))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(req, resp)
+	}
+	return object, nil
+}
+
+// Get downloads an object
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	digest, err := keyDigest(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return newVerifyingReader(resp.Body, digest), nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	default:
+		defer resp.Body.Close()
+		return nil, responseError(req, resp)
+	}
+}
+
+// Delete removes an object
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if _, err := keyDigest(key); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return responseError(req, resp)
+	}
+	return nil
+}
+
+// List pages through the objects under the prefix with ListObjectsV2
+func (s *S3Store) List(ctx context.Context, fn func(ObjectInfo) error) error {
+	prefix := strings.TrimPrefix(s.config.Prefix, "/")
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix + "sha256/"}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		u := *s.endpoint
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.config.Bucket
+		u.RawQuery = query.Encode()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := s.do(req, emptyPayloadHash)
+		if err != nil {
+			return err
+		}
+		var result listBucketResult
+		if resp.StatusCode != http.StatusOK {
+			err = responseError(req, resp)
+		} else if decodeErr := xml.NewDecoder(resp.Body).Decode(&result); decodeErr != nil {
+			err = fmt.Errorf("failed to decode S3 listing: %w", decodeErr)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, content := range result.Contents {
+			key := strings.TrimPrefix(content.Key, prefix)
+			if _, err := keyDigest(key); err != nil {
+				continue
+			}
+			if err := fn(ObjectInfo{Key: key, ModTime: content.LastModified}); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// listBucketResult is the part of a ListObjectsV2 response that List reads
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// touch copies an object onto itself, which S3 only allows when the metadata
+// is replaced. The copy gets a new modification time.
+func (s *S3Store) touch(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Amz-Copy-Source", "/"+s.config.Bucket+"/"+s.objectName(key))
+	req.Header.Set("X-Amz-Metadata-Directive", "REPLACE")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// A copy that fails after it started is reported in a 200 response
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK || strings.Contains(string(body), "<Error>") {
+		return fmt.Errorf("S3 copy returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (s *S3Store) exists(ctx context.Context, key string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.objectURL(key), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, responseError(req, resp)
+	}
+}
+
+func (s *S3Store) objectURL(key string) string {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.config.Bucket + "/" + s.objectName(key)
+	return u.String()
+}
+
+// objectName returns the name of an object within the bucket
+func (s *S3Store) objectName(key string) string {
+	return strings.TrimPrefix(s.config.Prefix+key, "/")
+}
+
+func (s *S3Store) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	signV4(req, payloadHash, s.config.AccessKeyID, s.config.SecretAccessKey, s.config.Region, s.now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 %s request failed: %w", req.Method, err)
+	}
+	return resp, nil
+}
+
+func responseError(req *http.Request, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("S3 %s returned status %d: %s", req.Method, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// emptyPayloadHash is the SHA-256 of an empty body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// signV4 adds AWS Signature Version 4 headers for the S3 service.
+// Every header already set on the request is signed, together with Host.
+func signV4(req *http.Request, payloadHash, accessKeyID, secretAccessKey, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		strings.ReplaceAll(req.URL.Query().Encode(), "+", "%20"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/utils"
+)
+
+// Backend names accepted by STORAGE_BACKEND
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+var (
+	// ErrNotFound is returned when no object exists under a key
+	ErrNotFound = errors.New("object not found")
+	// ErrChecksumMismatch is returned when stored content no longer matches its key
+	ErrChecksumMismatch = errors.New("object checksum mismatch")
+	// ErrInvalidKey is returned for keys that were not produced by Put
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Object describes stored content
+type Object struct {
+	// Key is derived from the content, so identical uploads share one object
+	Key string
+	// Checksum is the SHA-256 of the content in "sha256:<hex>" form
+	Checksum string
+	Size     int64
+}
+
+// ObjectInfo describes a listed object
+type ObjectInfo struct {
+	Key string
+	// ModTime is when the object was last stored
+	ModTime time.Time
+}
+
+// Store keeps immutable blobs under content-addressed keys
+type Store interface {
+	// Put stores the content read from r and returns where it was stored.
+	// Storing content that already exists only refreshes its modification
+	// time, so sweeps measure an object's age from its latest store.
+	Put(ctx context.Context, r io.Reader) (*Object, error)
+	// Get opens an object. The returned reader fails with ErrChecksumMismatch
+	// at the end of the content if it does not match the key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes an object. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// List calls fn for every object, in no particular order. Listing stops
+	// at the first error fn returns.
+	List(ctx context.Context, fn func(ObjectInfo) error) error
+	// Name returns the backend name for logging
+	Name() string
+}
+
+// Config selects and configures a storage backend
+type Config struct {
+	Backend string
+	Local   LocalConfig
+	S3      S3Config
+}
+
+// GetConfigFromEnv reads storage configuration from environment variables
+func GetConfigFromEnv() Config {
+	return Config{
+		Backend: utils.GetEnv("STORAGE_BACKEND", BackendLocal),
+		Local: LocalConfig{
+			Dir: utils.GetEnv("STORAGE_LOCAL_DIR", "uploads"),
+		},
+		S3: S3Config{
+			Endpoint:        utils.GetEnv("STORAGE_S3_ENDPOINT", ""),
+			Region:          utils.GetEnv("STORAGE_S3_REGION", "us-east-1"),
+			Bucket:          utils.GetEnv("STORAGE_S3_BUCKET", ""),
+			Prefix:          utils.GetEnv("STORAGE_S3_PREFIX", ""),
+			AccessKeyID:     utils.GetEnv("STORAGE_S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: utils.GetEnv("STORAGE_S3_SECRET_ACCESS_KEY", ""),
+		},
+	}
+}
+
+// New creates the store selected by config.Backend
+func New(config Config) (Store, error) {
+	switch config.Backend {
+	case BackendLocal, "":
+		return NewLocalStore(config.Local)
+	case BackendS3:
+		return NewS3Store(config.S3)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", config.Backend)
+	}
+}
+
+var keyPattern = regexp.MustCompile(`^sha256/[0-9a-f]{2}/[0-9a-f]{64}$`)
+
+// contentKey returns the key for content with the given SHA-256
+func contentKey(sum []byte) string {
+	digest := hex.EncodeToString(sum)
+	return "sha256/" + digest[:2] + "/" + digest
+}
+
+// keyDigest validates a key and returns the hex digest it encodes
+func keyDigest(key string) (string, error) {
+	if !keyPattern.MatchString(key) {
+		return "", ErrInvalidKey
+	}
+	return key[strings.LastIndex(key, "/")+1:], nil
+}
+
+func newObject(sum []byte, size int64) *Object {
+	return &Object{
+		Key:      contentKey(sum),
+		Checksum: "sha256:" + hex.EncodeToString(sum),
+		Size:     size,
+	}
+}
+
+// verifyingReader hashes content as it is read and checks it at EOF
+type verifyingReader struct {
+	io.ReadCloser
+	hash   hash.Hash
+	digest string
+}
+
+func newVerifyingReader(rc io.ReadCloser, digest string) *verifyingReader {
+	return &verifyingReader{ReadCloser: rc, hash: sha256.New(), digest: digest}
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(r.hash.Sum(nil)) != r.digest {
+		return n, ErrChecksumMismatch
+	}
+	return n, err
+}
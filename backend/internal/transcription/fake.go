@@ -0,0 +1,115 @@
+package transcription
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+)
+
+// FakeConfig holds the settings of the fixture-based fake
+type FakeConfig struct {
+	// FixtureDir holds Result JSON files named <sha256 of the audio>.json, plus an
+	// optional default.json used for any other recording
+	FixtureDir string
+}
+
+// FakeTranscriber returns canned results without running a speech engine.
+// It is meant for tests, demos and developing the rest of the pipeline offline.
+type FakeTranscriber struct {
+	config FakeConfig
+}
+
+// NewFakeTranscriber creates a FakeTranscriber
+func NewFakeTranscriber(config FakeConfig) *FakeTranscriber {
+	return &FakeTranscriber{config: config}
+}
+
+// Name returns the backend name
+func (t *FakeTranscriber) Name() string {
+	return BackendFake
+}
+
+// Transcribe returns the fixture for the recording's content, falling back to
+// default.json and then to a built-in result
+func (t *FakeTranscriber) Transcribe(ctx context.Context, audioPath string) (*Result, error) {
+	digest, err := fileDigest(audioPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if t.config.FixtureDir != "" {
+		for _, name := range []string{digest + ".json", "default.json"} {
+			result, err := loadFixture(filepath.Join(t.config.FixtureDir, name))
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return result, err
+		}
+	}
+	return defaultFakeResult(), nil
+}
+
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func loadFixture(path string) (*Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	labelSpeakers(result.Segments)
+	return &result, nil
+}
+
+func defaultFakeResult() *Result {
+	words := []string{"This", "is", "a", "fake", "transcript."}
+	result := &Result{
+		Language:    "en",
+		DurationSec: float64(len(words)) * 0.5,
+		Words:       make([]models.TranscriptWord, 0, len(words)),
+	}
+	for i, word := range words {
+		if i > 0 {
+			result.Text += " "
+		}
+		result.Text += word
+		result.Words = append(result.Words, models.TranscriptWord{
+			Word:  word,
+			Start: float64(i) * 0.5,
+			End:   float64(i)*0.5 + 0.4,
+		})
+	}
+	result.Segments = []models.TranscriptSegment{{
+		Speaker: "speaker_1",
+		Start:   0,
+		End:     result.Words[len(result.Words)-1].End,
+		Text:    result.Text,
+	}}
+	return result
+}
@@ -0,0 +1,243 @@
+package transcription
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+)
+
+// HTTPConfig holds the settings of an external transcription service
+type HTTPConfig struct {
+	// URL is an OpenAI-compatible transcription endpoint, e.g. https://api.openai.com/v1/audio/transcriptions
+	URL string
+	// APIKey is sent as a bearer token when set
+	APIKey string
+	// Model is sent as the model form field
+	Model string
+	// Language is an ISO 639-1 hint; empty lets the service detect it
+	Language string
+	// Diarize requests response_format=diarized_json, which labels each segment
+	// with its speaker but has no word timestamps. The model must support it,
+	// e.g. gpt-4o-transcribe-diarize.
+	Diarize bool
+	Timeout time.Duration
+}
+
+// DefaultHTTPConfig returns the default HTTP transcriber settings
+func DefaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		Model:   "whisper-1",
+		Timeout: 10 * time.Minute,
+	}
+}
+
+// HTTPTranscriber delegates transcription to a service implementing the OpenAI
+// audio transcription API, which faster-whisper-server, the whisper.cpp server
+// and several hosted providers also speak. Word and segment timestamps are
+// requested with response_format=verbose_json and timestamp_granularities[].
+type HTTPTranscriber struct {
+	config HTTPConfig
+	client *http.Client
+}
+
+// NewHTTPTranscriber creates an HTTPTranscriber
+func NewHTTPTranscriber(config HTTPConfig) (*HTTPTranscriber, error) {
+	parsed, err := url.Parse(config.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid transcription URL %q", config.URL)
+	}
+	defaults := DefaultHTTPConfig()
+	if config.Model == "" {
+		config.Model = defaults.Model
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+
+	return &HTTPTranscriber{
+		config: config,
+		client: &http.Client{},
+	}, nil
+}
+
+// Name returns the backend name
+func (t *HTTPTranscriber) Name() string {
+	return BackendHTTP
+}
+
+// Transcribe uploads the recording to the transcription service
+func (t *HTTPTranscriber) Transcribe(ctx context.Context, audioPath string) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.config.Timeout)
+	defer cancel()
+
+	audio, err := os.Open(audioPath)
+	if err != nil {
+		return nil, err
+	}
+	defer audio.Close()
+
+	// Stream the multipart body instead of buffering the recording in memory
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		writer.CloseWithError(t.writeForm(form, audio, filepath.Base(audioPath)))
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.config.URL, body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Accept", "application/json")
+	if t.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.config.APIKey)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return parseVerboseJSON(data)
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAudio, errorMessage(data, resp.Status))
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnavailable, errorMessage(data, resp.Status))
+	}
+}
+
+func (t *HTTPTranscriber) writeForm(form *multipart.Writer, audio io.Reader, filename string) error {
+	fields := [][2]string{
+		{"model", t.config.Model},
+		{"response_format", "verbose_json"},
+		{"timestamp_granularities[]", "word"},
+		{"timestamp_granularities[]", "segment"},
+	}
+	if t.config.Diarize {
+		fields = [][2]string{
+			{"model", t.config.Model},
+			{"response_format", "diarized_json"},
+			{"chunking_strategy", "auto"},
+		}
+	}
+	if t.config.Language != "" {
+		fields = append(fields, [2]string{"language", t.config.Language})
+	}
+	for _, field := range fields {
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, audio); err != nil {
+		return err
+	}
+	return form.Close()
+}
+
+// parseVerboseJSON converts a verbose_json or diarized_json transcription response into a Result
+func parseVerboseJSON(data []byte) (*Result, error) {
+	var output struct {
+		Text     string  `json:"text"`
+		Language string  `json:"language"`
+		Duration float64 `json:"duration"`
+		Words    []struct {
+			Word        string   `json:"word"`
+			Start       float64  `json:"start"`
+			End         float64  `json:"end"`
+			Probability *float64 `json:"probability"`
+		} `json:"words"`
+		Segments []struct {
+			Start   float64 `json:"start"`
+			End     float64 `json:"end"`
+			Text    string  `json:"text"`
+			Speaker string  `json:"speaker"` // Only in diarized_json
+		} `json:"segments"`
+	}
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("%w: invalid response: %v", ErrUnavailable, err)
+	}
+
+	result := &Result{
+		Text:        output.Text,
+		Language:    normalizeLanguage(output.Language),
+		DurationSec: output.Duration,
+		Words:       make([]models.TranscriptWord, 0, len(output.Words)),
+		Segments:    make([]models.TranscriptSegment, 0, len(output.Segments)),
+	}
+	for _, word := range output.Words {
+		result.Words = append(result.Words, models.TranscriptWord{
+			Word:       word.Word,
+			Start:      word.Start,
+			End:        word.End,
+			Confidence: word.Probability,
+		})
+	}
+	for _, segment := range output.Segments {
+		if text := strings.TrimSpace(segment.Text); text != "" {
+			result.Segments = append(result.Segments, models.TranscriptSegment{
+				Speaker: segment.Speaker,
+				Start:   segment.Start,
+				End:     segment.End,
+				Text:    text,
+			})
+		}
+		if segment.End > result.DurationSec {
+			result.DurationSec = segment.End // diarized_json has no duration
+		}
+	}
+	labelSpeakers(result.Segments)
+	return result, nil
+}
+
+// errorMessage extracts a human readable message from an error body.
+// It understands the OpenAI {"error": {"message"}} shape as well as {"error"} and {"detail"}.
+func errorMessage(body []byte, fallback string) string {
+	var nested struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &nested); err == nil && nested.Error.Message != "" {
+		return nested.Error.Message
+	}
+	var flat struct {
+		Error  string `json:"error"`
+		Detail string `json:"detail"`
+	}
+	if err := json.Unmarshal(body, &flat); err == nil {
+		if flat.Error != "" {
+			return flat.Error
+		}
+		if flat.Detail != "" {
+			return flat.Detail
+		}
+	}
+	return fallback
+}
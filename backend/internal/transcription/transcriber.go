@@ -0,0 +1,141 @@
+package transcription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/utils"
+)
+
+// Backend names accepted by TRANSCRIPTION_BACKEND
+const (
+	BackendWhisper = "whisper"
+	BackendHTTP    = "http"
+	BackendFake    = "fake"
+)
+
+var (
+	// ErrInvalidAudio is returned when the audio itself cannot be transcribed; retrying will not help
+	ErrInvalidAudio = errors.New("invalid audio")
+	// ErrUnavailable is returned when the transcription backend cannot be reached or is overloaded
+	ErrUnavailable = errors.New("transcription backend unavailable")
+)
+
+// Result is the transcript of one recording
+type Result struct {
+	Text        string                  `json:"text"`
+	Language    string                  `json:"language"`
+	DurationSec float64                 `json:"duration_sec"`
+	Words       []models.TranscriptWord `json:"words"`
+	// Segments split the text by speaker turn, or by the engine's own
+	// segmentation when it does not tell speakers apart
+	Segments []models.TranscriptSegment `json:"segments"`
+}
+
+// Transcriber converts speech in an audio file to text
+type Transcriber interface {
+	Transcribe(ctx context.Context, audioPath string) (*Result, error)
+	// Name returns the backend name for logging
+	Name() string
+}
+
+// Config selects and configures a transcription backend
+type Config struct {
+	Backend string
+	Whisper WhisperConfig
+	HTTP    HTTPConfig
+	Fake    FakeConfig
+}
+
+// GetConfigFromEnv returns the transcription settings from environment variables
+func GetConfigFromEnv() Config {
+	whisper := DefaultWhisperConfig()
+	httpConfig := DefaultHTTPConfig()
+	timeout := utils.GetEnvDuration("TRANSCRIPTION_TIMEOUT", 10*time.Minute)
+	language := utils.GetEnv("TRANSCRIPTION_LANGUAGE", "")
+
+	return Config{
+		Backend: utils.GetEnv("TRANSCRIPTION_BACKEND", BackendWhisper),
+		Whisper: WhisperConfig{
+			Command:    utils.GetEnv("TRANSCRIPTION_WHISPER_PATH", whisper.Command),
+			Model:      utils.GetEnv("TRANSCRIPTION_WHISPER_MODEL", whisper.Model),
+			Language:   language,
+			Threads:    utils.GetEnvInt("TRANSCRIPTION_WHISPER_THREADS", whisper.Threads),
+			Diarize:    utils.GetEnv("TRANSCRIPTION_WHISPER_DIARIZE", ""),
+			FFmpegPath: utils.GetEnv("TRANSCRIPTION_FFMPEG_PATH", whisper.FFmpegPath),
+			Timeout:    timeout,
+		},
+		HTTP: HTTPConfig{
+			URL:      utils.GetEnv("TRANSCRIPTION_HTTP_URL", httpConfig.URL),
+			APIKey:   utils.GetEnv("TRANSCRIPTION_HTTP_API_KEY", httpConfig.APIKey),
+			Model:    utils.GetEnv("TRANSCRIPTION_HTTP_MODEL", httpConfig.Model),
+			Language: language,
+			Diarize:  utils.GetEnvBool("TRANSCRIPTION_HTTP_DIARIZE", false),
+			Timeout:  timeout,
+		},
+		Fake: FakeConfig{
+			FixtureDir: utils.GetEnv("TRANSCRIPTION_FAKE_FIXTURES", ""),
+		},
+	}
+}
+
+// New creates the transcriber selected by config.Backend
+func New(config Config) (Transcriber, error) {
+	switch config.Backend {
+	case BackendWhisper, "":
+		return NewWhisperTranscriber(config.Whisper)
+	case BackendHTTP:
+		return NewHTTPTranscriber(config.HTTP)
+	case BackendFake:
+		return NewFakeTranscriber(config.Fake), nil
+	default:
+		return nil, fmt.Errorf("unknown transcription backend %q (expected %s, %s or %s)", config.Backend, BackendWhisper, BackendHTTP, BackendFake)
+	}
+}
+
+// labelSpeakers renames the speaker labels an engine reports, such as "A" or
+// "0", to speaker_1, speaker_2, ... in order of first appearance. Segments the
+// engine could not attribute are left unlabelled.
+func labelSpeakers(segments []models.TranscriptSegment) {
+	labels := make(map[string]string)
+	for i := range segments {
+		speaker := strings.TrimSpace(segments[i].Speaker)
+		if speaker == "" || speaker == "?" {
+			segments[i].Speaker = ""
+			continue
+		}
+		label, ok := labels[speaker]
+		if !ok {
+			label = fmt.Sprintf("speaker_%d", len(labels)+1)
+			labels[speaker] = label
+		}
+		segments[i].Speaker = label
+	}
+}
+
+// languageCodes maps the language names some engines report to ISO 639-1 codes
+var languageCodes = map[string]string{
+	"japanese":   "ja",
+	"english":    "en",
+	"chinese":    "zh",
+	"korean":     "ko",
+	"french":     "fr",
+	"german":     "de",
+	"spanish":    "es",
+	"italian":    "it",
+	"portuguese": "pt",
+	"russian":    "ru",
+}
+
+// normalizeLanguage returns an ISO 639-1 code where possible
+func normalizeLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if code, ok := languageCodes[language]; ok {
+		return code
+	}
+	return language
+}
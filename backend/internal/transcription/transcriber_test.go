@@ -0,0 +1,238 @@
+package transcription
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, dir, name, content string, mode os.FileMode) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), mode))
+	return path
+}
+
+func TestNew_SelectsBackend(t *testing.T) {
+	fake, err := New(Config{Backend: BackendFake})
+	assert.NoError(t, err)
+	assert.Equal(t, BackendFake, fake.Name())
+
+	httpTranscriber, err := New(Config{Backend: BackendHTTP, HTTP: HTTPConfig{URL: "http://localhost:8000/v1/audio/transcriptions"}})
+	assert.NoError(t, err)
+	assert.Equal(t, BackendHTTP, httpTranscriber.Name())
+
+	_, err = New(Config{Backend: BackendWhisper, Whisper: WhisperConfig{Command: "/nonexistent/whisper-cli"}})
+	assert.Error(t, err)
+
+	_, err = New(Config{Backend: "google"})
+	assert.Error(t, err)
+}
+
+// whisperOutputJSON is trimmed -ojf output of whisper.cpp
+const whisperOutputJSON = `{
+  "result": {"language": "en"},
+  "transcription": [
+    {
+      "offsets": {"from": 0, "to": 1500},
+      "text": " Hello world.",
+      "tokens": [
+        {"text": "[_BEG_]", "offsets": {"from": 0, "to": 0}, "p": 0.9},
+        {"text": " Hello", "offsets": {"from": 0, "to": 600}, "p": 0.9},
+        {"text": " wor", "offsets": {"from": 700, "to": 1000}, "p": 0.8},
+        {"text": "ld.", "offsets": {"from": 1000, "to": 1400}, "p": 0.6},
+        {"text": "[_TT_75]", "offsets": {"from": 1500, "to": 1500}, "p": 0.1}
+      ]
+    },
+    {
+      "offsets": {"from": 1500, "to": 3000},
+      "text": " Bye.",
+      "tokens": [
+        {"text": " Bye.", "offsets": {"from": 1600, "to": 2500}, "p": 0.7}
+      ]
+    }
+  ]
+}`
+
+func TestWhisperTranscriber(t *testing.T) {
+	dir := t.TempDir()
+	model := writeFile(t, dir, "ggml-base.bin", "model", 0o644)
+	writeFile(t, dir, "output.json", whisperOutputJSON, 0o644)
+	// Stand-in for whisper-cli: copies the canned output to the path given with -of
+	script := writeFile(t, dir, "whisper-cli", `#!/bin/sh
+while [ $# -gt 0 ]; do
+  case "$1" in
+    -f) input="$2"; shift ;;
+    -of) out="$2"; shift ;;
+  esac
+  shift
+done
+[ -f "$input" ] || { echo "failed to read $input" >&2; exit 1; }
+cp "$(dirname "$0")/output.json" "$out.json"
+`, 0o755)
+
+	transcriber, err := NewWhisperTranscriber(WhisperConfig{Command: script, Model: model})
+	require.NoError(t, err)
+
+	audio := writeFile(t, dir, "audio.wav", "RIFF", 0o644)
+	result, err := transcriber.Transcribe(context.Background(), audio)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world. Bye.", result.Text)
+	assert.Equal(t, "en", result.Language)
+	assert.Equal(t, 3.0, result.DurationSec)
+	require.Len(t, result.Words, 3)
+	assert.Equal(t, "Hello", result.Words[0].Word)
+	assert.Equal(t, "world.", result.Words[1].Word)
+	assert.Equal(t, 0.7, result.Words[1].Start)
+	assert.Equal(t, 1.4, result.Words[1].End)
+	assert.InDelta(t, 0.7, *result.Words[1].Confidence, 1e-9)
+	assert.Equal(t, "Bye.", result.Words[2].Word)
+	assert.Equal(t, []models.TranscriptSegment{
+		{Start: 0, End: 1.5, Text: "Hello world."},
+		{Start: 1.5, End: 3, Text: "Bye."},
+	}, result.Segments)
+
+	_, err = transcriber.Transcribe(context.Background(), filepath.Join(dir, "missing.wav"))
+	assert.ErrorContains(t, err, "failed to read")
+}
+
+func TestParseWhisperOutput_Speakers(t *testing.T) {
+	// Stereo diarization labels each segment with the louder channel
+	result, err := parseWhisperOutput([]byte(`{"transcription": [
+		{"offsets": {"from": 0, "to": 1000}, "text": " Hi.", "speaker": "1"},
+		{"offsets": {"from": 1000, "to": 2000}, "text": " Hello.", "speaker": "0"},
+		{"offsets": {"from": 2000, "to": 2500}, "text": " Hmm.", "speaker": "?"},
+		{"offsets": {"from": 2500, "to": 3000}, "text": " ", "speaker": "1"}
+	]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []models.TranscriptSegment{
+		{Speaker: "speaker_1", Start: 0, End: 1, Text: "Hi."},
+		{Speaker: "speaker_2", Start: 1, End: 2, Text: "Hello."},
+		{Start: 2, End: 2.5, Text: "Hmm."},
+	}, result.Segments)
+
+	// tinydiarize only marks turns, so speakers alternate
+	result, err = parseWhisperOutput([]byte(`{"transcription": [
+		{"offsets": {"from": 0, "to": 1000}, "text": " How are you?", "speaker_turn_next": true},
+		{"offsets": {"from": 1000, "to": 2000}, "text": " Fine.", "speaker_turn_next": false},
+		{"offsets": {"from": 2000, "to": 3000}, "text": " Thanks.", "speaker_turn_next": true},
+		{"offsets": {"from": 3000, "to": 4000}, "text": " Good.", "speaker_turn_next": false}
+	]}`))
+	require.NoError(t, err)
+	speakers := make([]string, 0, len(result.Segments))
+	for _, segment := range result.Segments {
+		speakers = append(speakers, segment.Speaker)
+	}
+	assert.Equal(t, []string{"speaker_1", "speaker_2", "speaker_2", "speaker_1"}, speakers)
+}
+
+func TestHTTPTranscriber(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.NoError(t, r.ParseMultipartForm(1<<20))
+		assert.Equal(t, "whisper-1", r.FormValue("model"))
+		assert.Equal(t, "verbose_json", r.FormValue("response_format"))
+		assert.Contains(t, r.MultipartForm.Value["timestamp_granularities[]"], "word")
+
+		file, _, err := r.FormFile("file")
+		require.NoError(t, err)
+		audio, _ := io.ReadAll(file)
+
+		switch string(audio) {
+		case "speech":
+			_, _ = w.Write([]byte(`{"text":"こんにちは","language":"japanese","duration":2.5,
+				"words":[{"word":"こんにちは","start":0.2,"end":1.1}],
+				"segments":[{"id":0,"start":0.0,"end":1.2,"text":" こんにちは"}]}`))
+		case "garbage":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"Invalid file format."}}`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	transcriber, err := NewHTTPTranscriber(HTTPConfig{URL: server.URL, APIKey: "secret"})
+	require.NoError(t, err)
+	dir := t.TempDir()
+
+	result, err := transcriber.Transcribe(context.Background(), writeFile(t, dir, "a.wav", "speech", 0o644))
+	require.NoError(t, err)
+	assert.Equal(t, "こんにちは", result.Text)
+	assert.Equal(t, "ja", result.Language)
+	assert.Equal(t, 2.5, result.DurationSec)
+	require.Len(t, result.Words, 1)
+	assert.Equal(t, 1.1, result.Words[0].End)
+	assert.Equal(t, []models.TranscriptSegment{{Start: 0, End: 1.2, Text: "こんにちは"}}, result.Segments)
+
+	_, err = transcriber.Transcribe(context.Background(), writeFile(t, dir, "b.wav", "garbage", 0o644))
+	assert.ErrorIs(t, err, ErrInvalidAudio)
+	assert.ErrorContains(t, err, "Invalid file format.")
+
+	_, err = transcriber.Transcribe(context.Background(), writeFile(t, dir, "c.wav", "busy", 0o644))
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestHTTPTranscriber_Diarize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseMultipartForm(1<<20))
+		assert.Equal(t, "diarized_json", r.FormValue("response_format"))
+		assert.Equal(t, "auto", r.FormValue("chunking_strategy"))
+		assert.Empty(t, r.MultipartForm.Value["timestamp_granularities[]"])
+		_, _ = w.Write([]byte(`{"text":"Hi. Hello.","segments":[
+			{"type":"transcript.text.segment","id":"seg_0","start":0.0,"end":0.8,"text":"Hi.","speaker":"B"},
+			{"type":"transcript.text.segment","id":"seg_1","start":0.9,"end":1.6,"text":"Hello.","speaker":"A"}]}`))
+	}))
+	defer server.Close()
+
+	transcriber, err := NewHTTPTranscriber(HTTPConfig{URL: server.URL, Model: "gpt-4o-transcribe-diarize", Diarize: true})
+	require.NoError(t, err)
+	result, err := transcriber.Transcribe(context.Background(), writeFile(t, t.TempDir(), "a.wav", "speech", 0o644))
+	require.NoError(t, err)
+	assert.Equal(t, 1.6, result.DurationSec)
+	assert.Equal(t, []models.TranscriptSegment{
+		{Speaker: "speaker_1", Start: 0, End: 0.8, Text: "Hi."},
+		{Speaker: "speaker_2", Start: 0.9, End: 1.6, Text: "Hello."},
+	}, result.Segments)
+}
+
+func TestFakeTranscriber(t *testing.T) {
+	dir := t.TempDir()
+	audio := writeFile(t, dir, "a.wav", "recording", 0o644)
+	sum := sha256.Sum256([]byte("recording"))
+	writeFile(t, dir, hex.EncodeToString(sum[:])+".json", `{"text":"matched","language":"ja","duration_sec":4,"words":[],
+		"segments":[{"speaker":"guest","start":0,"end":4,"text":"matched"}]}`, 0o644)
+
+	// Without fixtures the built-in result is returned
+	result, err := NewFakeTranscriber(FakeConfig{}).Transcribe(context.Background(), audio)
+	require.NoError(t, err)
+	assert.Equal(t, "This is a fake transcript.", result.Text)
+	assert.Len(t, result.Words, 5)
+	require.Len(t, result.Segments, 1)
+	assert.Equal(t, "speaker_1", result.Segments[0].Speaker)
+
+	transcriber := NewFakeTranscriber(FakeConfig{FixtureDir: dir})
+	result, err = transcriber.Transcribe(context.Background(), audio)
+	require.NoError(t, err)
+	assert.Equal(t, "matched", result.Text)
+	assert.Equal(t, "speaker_1", result.Segments[0].Speaker)
+
+	other := writeFile(t, dir, "b.wav", "other", 0o644)
+	result, err = transcriber.Transcribe(context.Background(), other)
+	require.NoError(t, err)
+	assert.Equal(t, "This is a fake transcript.", result.Text)
+
+	writeFile(t, dir, "default.json", `{"text":"default","language":"en"}`, 0o644)
+	result, err = transcriber.Transcribe(context.Background(), other)
+	require.NoError(t, err)
+	assert.Equal(t, "default", result.Text)
+}
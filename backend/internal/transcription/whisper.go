@@ -0,0 +1,264 @@
+package transcription
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/models"
+)
+
+// Speaker diarization modes of whisper.cpp accepted by WhisperConfig.Diarize
+const (
+	// DiarizeStereo tells speakers apart by comparing the channels of a stereo
+	// recording (-di), e.g. one microphone per speaker
+	DiarizeStereo = "stereo"
+	// DiarizeTinydiarize detects speaker turns with a tinydiarize model (-tdrz).
+	// It only marks turns, so speakers alternate between speaker_1 and speaker_2,
+	// which matches a conversation between two people.
+	DiarizeTinydiarize = "tdrz"
+)
+
+// WhisperConfig holds the settings of the whisper.cpp command line backend
+type WhisperConfig struct {
+	// Command is the whisper.cpp CLI binary (whisper-cli, formerly main)
+	Command string
+	// Model is the path to a ggml model file
+	Model string
+	// Language is an ISO 639-1 code; empty detects the language automatically
+	Language string
+	// Threads is passed as -t when positive
+	Threads int
+	// Diarize is DiarizeStereo, DiarizeTinydiarize or empty to leave speakers unlabelled
+	Diarize string
+	// FFmpegPath converts the input to 16 kHz mono WAV first when set.
+	// Without it the audio must already be in a format whisper.cpp reads.
+	FFmpegPath string
+	Timeout    time.Duration
+}
+
+// DefaultWhisperConfig returns the default whisper.cpp settings
+func DefaultWhisperConfig() WhisperConfig {
+	return WhisperConfig{
+		Command: "whisper-cli",
+		Model:   "models/ggml-base.bin",
+		Timeout: 10 * time.Minute,
+	}
+}
+
+// WhisperTranscriber runs a whisper.cpp style CLI per recording and reads its full JSON output (-ojf)
+type WhisperTranscriber struct {
+	config WhisperConfig
+}
+
+// NewWhisperTranscriber creates a WhisperTranscriber after checking that the binary and model exist
+func NewWhisperTranscriber(config WhisperConfig) (*WhisperTranscriber, error) {
+	command, err := exec.LookPath(config.Command)
+	if err != nil {
+		return nil, fmt.Errorf("whisper binary %q not found: %w", config.Command, err)
+	}
+	config.Command = command
+	if _, err := os.Stat(config.Model); err != nil {
+		return nil, fmt.Errorf("whisper model %q not found: %w", config.Model, err)
+	}
+	if config.FFmpegPath != "" {
+		ffmpeg, err := exec.LookPath(config.FFmpegPath)
+		if err != nil {
+			return nil, fmt.Errorf("ffmpeg binary %q not found: %w", config.FFmpegPath, err)
+		}
+		config.FFmpegPath = ffmpeg
+	}
+	switch config.Diarize {
+	case "", DiarizeStereo, DiarizeTinydiarize:
+	default:
+		return nil, fmt.Errorf("unknown whisper diarization mode %q (expected %s or %s)", config.Diarize, DiarizeStereo, DiarizeTinydiarize)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultWhisperConfig().Timeout
+	}
+	return &WhisperTranscriber{config: config}, nil
+}
+
+// Name returns the backend name
+func (t *WhisperTranscriber) Name() string {
+	return BackendWhisper
+}
+
+// Transcribe runs whisper on the recording
+func (t *WhisperTranscriber) Transcribe(ctx context.Context, audioPath string) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.config.Timeout)
+	defer cancel()
+
+	workDir, err := os.MkdirTemp("", "whisper-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	input := audioPath
+	if t.config.FFmpegPath != "" {
+		input = filepath.Join(workDir, "input.wav")
+		channels := "1"
+		if t.config.Diarize == DiarizeStereo {
+			channels = "2" // Stereo diarization compares the channels
+		}
+		if _, err := run(ctx, t.config.FFmpegPath, "-nostdin", "-y", "-i", audioPath, "-ar", "16000", "-ac", channels, "-c:a", "pcm_s16le", input); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("%w: ffmpeg could not decode the audio: %v", ErrInvalidAudio, err)
+		}
+	}
+
+	outputBase := filepath.Join(workDir, "output")
+	language := t.config.Language
+	if language == "" {
+		language = "auto"
+	}
+	args := []string{"-m", t.config.Model, "-f", input, "-l", language, "-ojf", "-of", outputBase, "-np"}
+	if t.config.Threads > 0 {
+		args = append(args, "-t", strconv.Itoa(t.config.Threads))
+	}
+	switch t.config.Diarize {
+	case DiarizeStereo:
+		args = append(args, "-di")
+	case DiarizeTinydiarize:
+		args = append(args, "-tdrz")
+	}
+	if _, err := run(ctx, t.config.Command, args...); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("whisper failed: %w", err)
+	}
+
+	data, err := os.ReadFile(outputBase + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("whisper produced no output: %w", err)
+	}
+	return parseWhisperOutput(data)
+}
+
+// run executes a command and includes the end of its stderr in errors
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if len(message) > 500 {
+			message = "..." + message[len(message)-500:]
+		}
+		if message != "" {
+			return nil, fmt.Errorf("%v: %s", err, message)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+type whisperOffsets struct {
+	From int64 `json:"from"` // Milliseconds
+	To   int64 `json:"to"`
+}
+
+type whisperOutput struct {
+	Result struct {
+		Language string `json:"language"`
+	} `json:"result"`
+	Transcription []struct {
+		Offsets whisperOffsets `json:"offsets"`
+		Text    string         `json:"text"`
+		// Speaker is "0", "1" or "?" with stereo diarization
+		Speaker string `json:"speaker"`
+		// SpeakerTurnNext is set with tinydiarize when the next segment starts a new turn
+		SpeakerTurnNext *bool `json:"speaker_turn_next"`
+		Tokens          []struct {
+			Text    string         `json:"text"`
+			Offsets whisperOffsets `json:"offsets"`
+			P       float64        `json:"p"`
+		} `json:"tokens"`
+	} `json:"transcription"`
+}
+
+// parseWhisperOutput converts whisper.cpp full JSON output into a Result.
+// Tokens are merged into words at leading spaces and segment boundaries, so
+// languages written without spaces get one word per segment.
+func parseWhisperOutput(data []byte) (*Result, error) {
+	var output whisperOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("invalid whisper output: %w", err)
+	}
+
+	result := &Result{
+		Language: normalizeLanguage(output.Result.Language),
+		Words:    []models.TranscriptWord{},
+		Segments: []models.TranscriptSegment{},
+	}
+	var text strings.Builder
+	turn := 0
+	for _, segment := range output.Transcription {
+		text.WriteString(segment.Text)
+		if end := float64(segment.Offsets.To) / 1000; end > result.DurationSec {
+			result.DurationSec = end
+		}
+
+		speaker := segment.Speaker
+		if segment.SpeakerTurnNext != nil {
+			speaker = strconv.Itoa(turn % 2)
+			if *segment.SpeakerTurnNext {
+				turn++
+			}
+		}
+		if segmentText := strings.TrimSpace(segment.Text); segmentText != "" {
+			result.Segments = append(result.Segments, models.TranscriptSegment{
+				Speaker: speaker,
+				Start:   float64(segment.Offsets.From) / 1000,
+				End:     float64(segment.Offsets.To) / 1000,
+				Text:    segmentText,
+			})
+		}
+
+		var word *models.TranscriptWord
+		var probabilitySum float64
+		var tokenCount int
+		flush := func() {
+			if word == nil {
+				return
+			}
+			word.Word = strings.TrimSpace(word.Word)
+			if word.Word != "" {
+				confidence := probabilitySum / float64(tokenCount)
+				word.Confidence = &confidence
+				result.Words = append(result.Words, *word)
+			}
+			word = nil
+		}
+		for _, token := range segment.Tokens {
+			if strings.HasPrefix(token.Text, "[_") {
+				continue // Special tokens such as [_BEG_] and [_TT_123]
+			}
+			if word == nil || strings.HasPrefix(token.Text, " ") {
+				flush()
+				word = &models.TranscriptWord{Start: float64(token.Offsets.From) / 1000}
+				probabilitySum, tokenCount = 0, 0
+			}
+			word.Word += token.Text
+			word.End = float64(token.Offsets.To) / 1000
+			probabilitySum += token.P
+			tokenCount++
+		}
+		flush()
+	}
+	result.Text = strings.TrimSpace(text.String())
+	labelSpeakers(result.Segments)
+	return result, nil
+}
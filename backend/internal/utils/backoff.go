@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff returns the delay before the retry following the given attempt
+// (1 for the first attempt): base doubled per attempt, capped at max, with up
+// to 50% jitter so that operations failing together do not retry together.
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
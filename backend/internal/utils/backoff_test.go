@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	for attempt, ceiling := range map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 3: 40 * time.Second, 10: time.Minute} {
+		delay := Backoff(attempt, 10*time.Second, time.Minute)
+		assert.GreaterOrEqual(t, delay, ceiling/2, "attempt %d", attempt)
+		assert.LessOrEqual(t, delay, ceiling, "attempt %d", attempt)
+	}
+}
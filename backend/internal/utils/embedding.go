@@ -56,3 +56,37 @@ func CosineSimilarity(a, b []float32) float64 {
 
 	return dotProduct / (math.Sqrt(normA) * math.Sqrt(normB))
 }
+
+// NormalizeL2 returns a copy of the embedding scaled to unit length.
+// A zero vector is returned unchanged.
+func NormalizeL2(embedding []float32) []float32 {
+	var sum float64
+	for _, v := range embedding {
+		sum += float64(v) * float64(v)
+	}
+
+	normalized := make([]float32, len(embedding))
+	if sum == 0 {
+		copy(normalized, embedding)
+		return normalized
+	}
+
+	norm := math.Sqrt(sum)
+	for i, v := range embedding {
+		normalized[i] = float32(float64(v) / norm)
+	}
+	return normalized
+}
+
+// DotProduct calculates the dot product of two vectors of equal length
+func DotProduct(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -284,6 +284,55 @@ func TestCosineSimilarity_Range(t *testing.T) {
 	}
 }
 
+func TestNormalizeL2(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []float32
+		expected []float32
+	}{
+		{
+			name:     "already unit length",
+			input:    []float32{1.0, 0.0, 0.0},
+			expected: []float32{1.0, 0.0, 0.0},
+		},
+		{
+			name:     "3-4-5 triangle",
+			input:    []float32{3.0, 4.0},
+			expected: []float32{0.6, 0.8},
+		},
+		{
+			name:     "zero vector",
+			input:    []float32{0.0, 0.0},
+			expected: []float32{0.0, 0.0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NormalizeL2(tt.input)
+			assert.Equal(t, len(tt.expected), len(result))
+			for i := range tt.expected {
+				assert.InDelta(t, tt.expected[i], result[i], 0.0001)
+			}
+		})
+	}
+}
+
+func TestNormalizeL2_DoesNotMutateInput(t *testing.T) {
+	input := []float32{3.0, 4.0}
+	NormalizeL2(input)
+	assert.Equal(t, []float32{3.0, 4.0}, input)
+}
+
+func TestDotProduct_MatchesCosineForNormalizedVectors(t *testing.T) {
+	a := []float32{1.0, 2.0, 3.0}
+	b := []float32{4.0, 5.0, 6.0}
+
+	dot := DotProduct(NormalizeL2(a), NormalizeL2(b))
+	assert.InDelta(t, CosineSimilarity(a, b), dot, 0.0001)
+	assert.Equal(t, 0.0, DotProduct(a, []float32{1.0}))
+}
+
 func BenchmarkFloat32SliceToBytes(b *testing.B) {
 	embedding := make([]float32, 512)
 	for i := range embedding {
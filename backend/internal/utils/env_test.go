@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEnvHelpers(t *testing.T) {
+	t.Setenv("TEST_ENV_STRING", "value")
+	t.Setenv("TEST_ENV_INT", "42")
+	t.Setenv("TEST_ENV_FLOAT", "0.75")
+	t.Setenv("TEST_ENV_BOOL", "true")
+	t.Setenv("TEST_ENV_DURATION", "1m30s")
+	t.Setenv("TEST_ENV_INVALID", "not-a-number")
+
+	assert.Equal(t, "value", GetEnv("TEST_ENV_STRING", "default"))
+	assert.Equal(t, "default", GetEnv("TEST_ENV_UNSET", "default"))
+
+	assert.Equal(t, 42, GetEnvInt("TEST_ENV_INT", 1))
+	assert.Equal(t, 1, GetEnvInt("TEST_ENV_INVALID", 1))
+
+	assert.Equal(t, 0.75, GetEnvFloat("TEST_ENV_FLOAT", 0.5))
+	assert.Equal(t, 0.5, GetEnvFloat("TEST_ENV_UNSET", 0.5))
+
+	assert.True(t, GetEnvBool("TEST_ENV_BOOL", false))
+	assert.False(t, GetEnvBool("TEST_ENV_INVALID", false))
+
+	assert.Equal(t, 90*time.Second, GetEnvDuration("TEST_ENV_DURATION", time.Second))
+	assert.Equal(t, time.Second, GetEnvDuration("TEST_ENV_INVALID", time.Second))
+}
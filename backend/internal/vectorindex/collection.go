@@ -0,0 +1,81 @@
+package vectorindex
+
+import "sync"
+
+// Collection holds one Index per partition key, such as an embedding model family.
+// Embeddings from different partitions are never compared with each other.
+type Collection struct {
+	mu         sync.RWMutex
+	config     Config
+	partitions map[string]*Index
+	faceKey    map[string]string
+}
+
+// NewCollection creates an empty Collection whose partitions share the given configuration
+func NewCollection(config Config) *Collection {
+	return &Collection{
+		config:     config,
+		partitions: make(map[string]*Index),
+		faceKey:    make(map[string]string),
+	}
+}
+
+// Add inserts an entry into the partition for key, moving it if the face was stored under another key
+func (c *Collection) Add(key string, entry Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if previous, ok := c.faceKey[entry.FaceID]; ok && previous != key {
+		c.partitions[previous].Remove(entry.FaceID)
+	}
+
+	idx, ok := c.partitions[key]
+	if !ok {
+		idx = New(c.config)
+		c.partitions[key] = idx
+	}
+	idx.Add(entry)
+	c.faceKey[entry.FaceID] = key
+}
+
+// Remove deletes a face from whichever partition holds it. It reports whether the face was present.
+func (c *Collection) Remove(faceID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, ok := c.faceKey[faceID]
+	if !ok {
+		return false
+	}
+	delete(c.faceKey, faceID)
+	return c.partitions[key].Remove(faceID)
+}
+
+// RemovePerson deletes every face of a person from all partitions and returns how many were removed
+func (c *Collection) RemovePerson(personID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for _, idx := range c.partitions {
+		for _, faceID := range idx.RemovePerson(personID) {
+			delete(c.faceKey, faceID)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Partition returns the Index for key, or nil when nothing has been stored under it
+func (c *Collection) Partition(key string) *Index {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.partitions[key]
+}
+
+// Len returns the number of live entries across all partitions
+func (c *Collection) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.faceKey)
+}
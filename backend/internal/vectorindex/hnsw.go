@@ -0,0 +1,203 @@
+package vectorindex
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+
+	"github.com/jphacks/os_2522/backend/internal/utils"
+)
+
+// hnsw is a Hierarchical Navigable Small World graph over item positions.
+// It only stores adjacency; vectors are looked up through the owning Index.
+// Removed items stay in the graph as navigation points and are filtered from results.
+type hnsw struct {
+	m              int
+	mMax0          int
+	efConstruction int
+	levelMult      float64
+	rng            *rand.Rand
+	vector         func(pos int) []float32
+
+	// links[pos][level] lists the neighbours of pos on that level
+	links      [][][]int
+	entryPoint int
+	maxLevel   int
+}
+
+func newHNSW(config Config, vector func(pos int) []float32) *hnsw {
+	return &hnsw{
+		m:              config.M,
+		mMax0:          config.M * 2,
+		efConstruction: config.EfConstruction,
+		levelMult:      1 / math.Log(float64(config.M)),
+		rng:            rand.New(rand.NewSource(config.Seed)),
+		vector:         vector,
+		entryPoint:     -1,
+	}
+}
+
+func (g *hnsw) randomLevel() int {
+	return int(math.Floor(-math.Log(1-g.rng.Float64()) * g.levelMult))
+}
+
+func (g *hnsw) similarity(q []float32, pos int) float64 {
+	return utils.DotProduct(q, g.vector(pos))
+}
+
+// insert links the item at pos into the graph. Positions must be inserted in order.
+func (g *hnsw) insert(pos int) {
+	level := g.randomLevel()
+	g.links = append(g.links, make([][]int, level+1))
+
+	if g.entryPoint < 0 {
+		g.entryPoint = pos
+		g.maxLevel = level
+		return
+	}
+
+	q := g.vector(pos)
+	ep := scored{pos: g.entryPoint, score: g.similarity(q, g.entryPoint)}
+
+	// Greedy descent through the layers above the new node's level
+	for l := g.maxLevel; l > level; l-- {
+		ep = g.greedy(q, ep, l)
+	}
+
+	entries := []scored{ep}
+	for l := min(level, g.maxLevel); l >= 0; l-- {
+		candidates := g.searchLayer(q, entries, g.efConstruction, l)
+		maxLinks := g.m
+		if l == 0 {
+			maxLinks = g.mMax0
+		}
+
+		neighbours := closest(candidates, g.m)
+		g.links[pos][l] = positions(neighbours)
+		for _, n := range neighbours {
+			g.links[n.pos][l] = append(g.links[n.pos][l], pos)
+			if len(g.links[n.pos][l]) > maxLinks {
+				g.shrink(n.pos, l, maxLinks)
+			}
+		}
+		entries = candidates
+	}
+
+	if level > g.maxLevel {
+		g.entryPoint = pos
+		g.maxLevel = level
+	}
+}
+
+// search returns up to ef live items closest to q, best first
+func (g *hnsw) search(q []float32, ef int, live func(pos int) bool) []scored {
+	if g.entryPoint < 0 {
+		return nil
+	}
+
+	ep := scored{pos: g.entryPoint, score: g.similarity(q, g.entryPoint)}
+	for l := g.maxLevel; l > 0; l-- {
+		ep = g.greedy(q, ep, l)
+	}
+
+	candidates := g.searchLayer(q, []scored{ep}, ef, 0)
+	results := make([]scored, 0, len(candidates))
+	for _, c := range candidates {
+		if live(c.pos) {
+			results = append(results, c)
+		}
+	}
+	return results
+}
+
+// greedy walks a single layer towards q until no neighbour improves the score
+func (g *hnsw) greedy(q []float32, ep scored, level int) scored {
+	for changed := true; changed; {
+		changed = false
+		for _, n := range g.neighbours(ep.pos, level) {
+			if s := g.similarity(q, n); s > ep.score {
+				ep = scored{pos: n, score: s}
+				changed = true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer is the beam search from the HNSW paper; it returns candidates best first
+func (g *hnsw) searchLayer(q []float32, entries []scored, ef int, level int) []scored {
+	visited := make(map[int]struct{}, ef*4)
+	frontier := &maxHeap{}
+	found := &minHeap{}
+
+	for _, e := range entries {
+		if _, seen := visited[e.pos]; seen {
+			continue
+		}
+		visited[e.pos] = struct{}{}
+		heap.Push(frontier, e)
+		heap.Push(found, e)
+		if found.Len() > ef {
+			heap.Pop(found)
+		}
+	}
+
+	for frontier.Len() > 0 {
+		current := heap.Pop(frontier).(scored)
+		if found.Len() >= ef && current.score < (*found)[0].score {
+			break
+		}
+
+		for _, n := range g.neighbours(current.pos, level) {
+			if _, seen := visited[n]; seen {
+				continue
+			}
+			visited[n] = struct{}{}
+
+			s := scored{pos: n, score: g.similarity(q, n)}
+			if found.Len() < ef || s.score > (*found)[0].score {
+				heap.Push(frontier, s)
+				heap.Push(found, s)
+				if found.Len() > ef {
+					heap.Pop(found)
+				}
+			}
+		}
+	}
+
+	return sortDescending(*found)
+}
+
+// shrink trims the neighbour list of pos on a level down to the maxLinks closest
+func (g *hnsw) shrink(pos, level, maxLinks int) {
+	v := g.vector(pos)
+	current := g.links[pos][level]
+	ranked := make([]scored, len(current))
+	for i, n := range current {
+		ranked[i] = scored{pos: n, score: g.similarity(v, n)}
+	}
+	g.links[pos][level] = positions(closest(ranked, maxLinks))
+}
+
+func (g *hnsw) neighbours(pos, level int) []int {
+	if level >= len(g.links[pos]) {
+		return nil
+	}
+	return g.links[pos][level]
+}
+
+func closest(candidates []scored, n int) []scored {
+	sorted := sortDescending(candidates)
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+func positions(hits []scored) []int {
+	out := make([]int, len(hits))
+	for i, h := range hits {
+		out[i] = h.pos
+	}
+	return out
+}
@@ -0,0 +1,326 @@
+package vectorindex
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+
+	"github.com/jphacks/os_2522/backend/internal/utils"
+)
+
+// Config holds tuning parameters for the embedding index
+type Config struct {
+	// ExactThreshold is the gallery size up to which searches use an exact scan
+	ExactThreshold int
+	// M is the number of neighbours kept per node on the upper HNSW layers
+	M int
+	// EfConstruction is the candidate list size used while inserting
+	EfConstruction int
+	// EfSearch is the minimum candidate list size used while searching
+	EfSearch int
+	// Seed makes level assignment reproducible
+	Seed int64
+}
+
+// DefaultConfig returns a configuration suitable for galleries of up to a few hundred thousand faces
+func DefaultConfig() Config {
+	return Config{
+		ExactThreshold: 2000,
+		M:              16,
+		EfConstruction: 200,
+		EfSearch:       64,
+		Seed:           42,
+	}
+}
+
+// Entry is a face embedding stored in the index
+type Entry struct {
+	FaceID    string
+	PersonID  string
+	Embedding []float32
+}
+
+// Result is a single search hit
+type Result struct {
+	FaceID   string
+	PersonID string
+	Score    float64 // cosine similarity
+}
+
+type item struct {
+	faceID   string
+	personID string
+	vector   []float32 // L2-normalized
+	deleted  bool
+}
+
+// Index is a thread-safe in-memory nearest-neighbour index over face embeddings.
+// Vectors are normalized on insert so that cosine similarity is a dot product.
+// Small galleries are searched exactly; larger ones go through an HNSW graph
+// and fall back to an exact scan whenever the graph cannot fill the request.
+type Index struct {
+	mu       sync.RWMutex
+	config   Config
+	items    []item
+	byFace   map[string]int
+	byPerson map[string]map[int]struct{}
+	live     int
+	graph    *hnsw
+}
+
+// New creates an empty Index
+func New(config Config) *Index {
+	defaults := DefaultConfig()
+	if config.M <= 0 {
+		config.M = defaults.M
+	}
+	if config.EfConstruction <= 0 {
+		config.EfConstruction = defaults.EfConstruction
+	}
+	if config.EfSearch <= 0 {
+		config.EfSearch = defaults.EfSearch
+	}
+
+	idx := &Index{
+		config:   config,
+		byFace:   make(map[string]int),
+		byPerson: make(map[string]map[int]struct{}),
+	}
+	idx.graph = newHNSW(config, idx.vector)
+	return idx
+}
+
+// Len returns the number of live entries
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.live
+}
+
+// Add inserts an entry, replacing any existing entry with the same face ID
+func (idx *Index) Add(entry Entry) {
+	if len(entry.Embedding) == 0 {
+		return
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if pos, ok := idx.byFace[entry.FaceID]; ok {
+		idx.removeLocked(pos)
+	}
+
+	pos := len(idx.items)
+	idx.items = append(idx.items, item{
+		faceID:   entry.FaceID,
+		personID: entry.PersonID,
+		vector:   utils.NormalizeL2(entry.Embedding),
+	})
+	idx.track(pos)
+	idx.live++
+	idx.graph.insert(pos)
+}
+
+// Remove deletes the entry for a face. It reports whether the face was present.
+func (idx *Index) Remove(faceID string) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	pos, ok := idx.byFace[faceID]
+	if !ok {
+		return false
+	}
+	idx.removeLocked(pos)
+	idx.compactIfNeeded()
+	return true
+}
+
+// RemovePerson deletes every entry belonging to a person and returns the removed face IDs
+func (idx *Index) RemovePerson(personID string) []string {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	var removed []string
+	for pos := range idx.byPerson[personID] {
+		removed = append(removed, idx.items[pos].faceID)
+		idx.removeLocked(pos)
+	}
+	idx.compactIfNeeded()
+	return removed
+}
+
+// PersonEmbeddings returns the normalized embeddings of every live face of a person.
+// The returned slices must not be modified.
+func (idx *Index) PersonEmbeddings(personID string) [][]float32 {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	positions := idx.byPerson[personID]
+	embeddings := make([][]float32, 0, len(positions))
+	for pos := range positions {
+		embeddings = append(embeddings, idx.items[pos].vector)
+	}
+	return embeddings
+}
+
+// Search returns up to k entries most similar to the query, best first
+func (idx *Index) Search(query []float32, k int) []Result {
+	if k <= 0 || len(query) == 0 {
+		return nil
+	}
+	q := utils.NormalizeL2(query)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if idx.live == 0 {
+		return nil
+	}
+
+	var hits []scored
+	if idx.live > idx.config.ExactThreshold {
+		ef := idx.config.EfSearch
+		if k > ef {
+			ef = k
+		}
+		hits = idx.graph.search(q, ef, idx.isLive)
+		if len(hits) > k {
+			hits = hits[:k]
+		}
+	}
+
+	// Fall back to an exact scan for small galleries or when the graph
+	// could not return enough live neighbours (e.g. many tombstones).
+	want := k
+	if want > idx.live {
+		want = idx.live
+	}
+	if len(hits) < want {
+		hits = idx.exactSearch(q, k)
+	}
+
+	results := make([]Result, len(hits))
+	for i, h := range hits {
+		it := idx.items[h.pos]
+		results[i] = Result{
+			FaceID:   it.faceID,
+			PersonID: it.personID,
+			Score:    h.score,
+		}
+	}
+	return results
+}
+
+// exactSearch scans every live entry and keeps the k best in a bounded min-heap
+func (idx *Index) exactSearch(q []float32, k int) []scored {
+	h := &minHeap{}
+	for pos := range idx.items {
+		if idx.items[pos].deleted || len(idx.items[pos].vector) != len(q) {
+			continue
+		}
+		s := scored{pos: pos, score: utils.DotProduct(q, idx.items[pos].vector)}
+		if h.Len() < k {
+			heap.Push(h, s)
+		} else if s.score > (*h)[0].score {
+			(*h)[0] = s
+			heap.Fix(h, 0)
+		}
+	}
+	return sortDescending(*h)
+}
+
+func (idx *Index) vector(pos int) []float32 {
+	return idx.items[pos].vector
+}
+
+func (idx *Index) isLive(pos int) bool {
+	return !idx.items[pos].deleted
+}
+
+func (idx *Index) track(pos int) {
+	it := idx.items[pos]
+	idx.byFace[it.faceID] = pos
+	if idx.byPerson[it.personID] == nil {
+		idx.byPerson[it.personID] = make(map[int]struct{})
+	}
+	idx.byPerson[it.personID][pos] = struct{}{}
+}
+
+func (idx *Index) removeLocked(pos int) {
+	it := &idx.items[pos]
+	it.deleted = true
+	delete(idx.byFace, it.faceID)
+	delete(idx.byPerson[it.personID], pos)
+	if len(idx.byPerson[it.personID]) == 0 {
+		delete(idx.byPerson, it.personID)
+	}
+	idx.live--
+}
+
+// compactIfNeeded rebuilds storage and graph once tombstones outnumber live entries
+func (idx *Index) compactIfNeeded() {
+	dead := len(idx.items) - idx.live
+	if dead < 64 || dead < idx.live {
+		return
+	}
+
+	old := idx.items
+	idx.items = make([]item, 0, idx.live)
+	idx.byFace = make(map[string]int, idx.live)
+	idx.byPerson = make(map[string]map[int]struct{})
+	idx.graph = newHNSW(idx.config, idx.vector)
+	for _, it := range old {
+		if it.deleted {
+			continue
+		}
+		pos := len(idx.items)
+		idx.items = append(idx.items, it)
+		idx.track(pos)
+		idx.graph.insert(pos)
+	}
+}
+
+// scored pairs an item position with its similarity to the query
+type scored struct {
+	pos   int
+	score float64
+}
+
+// minHeap keeps the lowest score on top
+type minHeap []scored
+
+func (h minHeap) Len() int           { return len(h) }
+func (h minHeap) Less(i, j int) bool { return h[i].score < h[j].score }
+func (h minHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x any)        { *h = append(*h, x.(scored)) }
+func (h *minHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// maxHeap keeps the highest score on top
+type maxHeap []scored
+
+func (h maxHeap) Len() int           { return len(h) }
+func (h maxHeap) Less(i, j int) bool { return h[i].score > h[j].score }
+func (h maxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x any)        { *h = append(*h, x.(scored)) }
+func (h *maxHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+func sortDescending(hits []scored) []scored {
+	out := make([]scored, len(hits))
+	copy(out, hits)
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].score > out[j].score
+	})
+	return out
+}
@@ -0,0 +1,176 @@
+package vectorindex
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func randomVector(rng *rand.Rand, dim int) []float32 {
+	v := make([]float32, dim)
+	for i := range v {
+		v[i] = float32(rng.NormFloat64())
+	}
+	return v
+}
+
+func TestIndex_ExactSearch(t *testing.T) {
+	idx := New(DefaultConfig())
+	idx.Add(Entry{FaceID: "f-1", PersonID: "p-1", Embedding: []float32{1, 0, 0}})
+	idx.Add(Entry{FaceID: "f-2", PersonID: "p-2", Embedding: []float32{0, 1, 0}})
+	idx.Add(Entry{FaceID: "f-3", PersonID: "p-3", Embedding: []float32{1, 1, 0}})
+
+	results := idx.Search([]float32{2, 0, 0}, 2)
+
+	assert.Len(t, results, 2)
+	assert.Equal(t, "f-1", results[0].FaceID)
+	assert.InDelta(t, 1.0, results[0].Score, 0.0001)
+	assert.Equal(t, "f-3", results[1].FaceID)
+	assert.InDelta(t, 0.7071, results[1].Score, 0.0001)
+}
+
+func TestIndex_AddReplacesExistingFace(t *testing.T) {
+	idx := New(DefaultConfig())
+	idx.Add(Entry{FaceID: "f-1", PersonID: "p-1", Embedding: []float32{1, 0}})
+	idx.Add(Entry{FaceID: "f-1", PersonID: "p-1", Embedding: []float32{0, 1}})
+
+	assert.Equal(t, 1, idx.Len())
+	results := idx.Search([]float32{0, 1}, 5)
+	assert.Len(t, results, 1)
+	assert.InDelta(t, 1.0, results[0].Score, 0.0001)
+}
+
+func TestIndex_Remove(t *testing.T) {
+	idx := New(DefaultConfig())
+	idx.Add(Entry{FaceID: "f-1", PersonID: "p-1", Embedding: []float32{1, 0}})
+	idx.Add(Entry{FaceID: "f-2", PersonID: "p-1", Embedding: []float32{0.9, 0.1}})
+	idx.Add(Entry{FaceID: "f-3", PersonID: "p-2", Embedding: []float32{0, 1}})
+
+	assert.True(t, idx.Remove("f-1"))
+	assert.False(t, idx.Remove("f-1"))
+	assert.Equal(t, 2, idx.Len())
+
+	assert.Equal(t, []string{"f-2"}, idx.RemovePerson("p-1"))
+	assert.Equal(t, 1, idx.Len())
+
+	results := idx.Search([]float32{1, 0}, 3)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "p-2", results[0].PersonID)
+}
+
+func TestIndex_PersonEmbeddings(t *testing.T) {
+	idx := New(DefaultConfig())
+	idx.Add(Entry{FaceID: "f-1", PersonID: "p-1", Embedding: []float32{3, 4}})
+	idx.Add(Entry{FaceID: "f-2", PersonID: "p-1", Embedding: []float32{0, 1}})
+	idx.Add(Entry{FaceID: "f-3", PersonID: "p-2", Embedding: []float32{1, 0}})
+
+	embeddings := idx.PersonEmbeddings("p-1")
+	assert.Len(t, embeddings, 2)
+	assert.ElementsMatch(t, [][]float32{{0.6, 0.8}, {0, 1}}, embeddings)
+
+	idx.Remove("f-1")
+	assert.Len(t, idx.PersonEmbeddings("p-1"), 1)
+	assert.Empty(t, idx.PersonEmbeddings("p-unknown"))
+}
+
+func TestIndex_EmptyAndInvalidQueries(t *testing.T) {
+	idx := New(DefaultConfig())
+	assert.Empty(t, idx.Search([]float32{1, 0}, 3))
+
+	idx.Add(Entry{FaceID: "f-1", PersonID: "p-1", Embedding: []float32{1, 0}})
+	assert.Empty(t, idx.Search([]float32{1, 0}, 0))
+	assert.Empty(t, idx.Search(nil, 3))
+}
+
+func TestIndex_HNSWRecall(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const (
+		dim     = 64
+		gallery = 3000
+		queries = 50
+		k       = 5
+	)
+
+	approx := New(Config{ExactThreshold: 0, M: 16, EfConstruction: 100, EfSearch: 64, Seed: 7})
+	exact := New(Config{ExactThreshold: gallery})
+	for i := 0; i < gallery; i++ {
+		entry := Entry{
+			FaceID:    fmt.Sprintf("f-%d", i),
+			PersonID:  fmt.Sprintf("p-%d", i),
+			Embedding: randomVector(rng, dim),
+		}
+		approx.Add(entry)
+		exact.Add(entry)
+	}
+
+	hits := 0
+	for i := 0; i < queries; i++ {
+		q := randomVector(rng, dim)
+		truth := map[string]bool{}
+		for _, r := range exact.Search(q, k) {
+			truth[r.FaceID] = true
+		}
+		got := approx.Search(q, k)
+		assert.Len(t, got, k)
+		for _, r := range got {
+			if truth[r.FaceID] {
+				hits++
+			}
+		}
+	}
+
+	recall := float64(hits) / float64(queries*k)
+	assert.Greater(t, recall, 0.9)
+}
+
+func TestIndex_FallsBackWhenGraphIsMostlyTombstones(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	idx := New(Config{ExactThreshold: 0, M: 8, EfConstruction: 50, EfSearch: 8, Seed: 3})
+	for i := 0; i < 60; i++ {
+		idx.Add(Entry{FaceID: fmt.Sprintf("f-%d", i), PersonID: "p-gone", Embedding: randomVector(rng, 16)})
+	}
+	idx.Add(Entry{FaceID: "f-keep", PersonID: "p-keep", Embedding: randomVector(rng, 16)})
+	idx.RemovePerson("p-gone")
+
+	results := idx.Search(randomVector(rng, 16), 3)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "f-keep", results[0].FaceID)
+}
+
+func BenchmarkIndex_Search(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	idx := New(Config{ExactThreshold: 0})
+	for i := 0; i < 20000; i++ {
+		idx.Add(Entry{FaceID: fmt.Sprintf("f-%d", i), PersonID: "p", Embedding: randomVector(rng, 512)})
+	}
+	q := randomVector(rng, 512)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx.Search(q, 3)
+	}
+}
+
+func TestCollection_PartitionsAreIsolated(t *testing.T) {
+	c := NewCollection(DefaultConfig())
+	c.Add("facenet", Entry{FaceID: "f-1", PersonID: "p-1", Embedding: []float32{1, 0}})
+	c.Add("arcface", Entry{FaceID: "f-2", PersonID: "p-1", Embedding: []float32{1, 0}})
+	c.Add("arcface", Entry{FaceID: "f-3", PersonID: "p-2", Embedding: []float32{0, 1}})
+
+	assert.Equal(t, 3, c.Len())
+	assert.Len(t, c.Partition("facenet").Search([]float32{1, 0}, 5), 1)
+	assert.Len(t, c.Partition("arcface").Search([]float32{1, 0}, 5), 2)
+	assert.Nil(t, c.Partition("unknown"))
+
+	// Re-adding a face under another key moves it
+	c.Add("facenet", Entry{FaceID: "f-3", PersonID: "p-2", Embedding: []float32{0, 1}})
+	assert.Equal(t, 2, c.Partition("facenet").Len())
+	assert.Equal(t, 1, c.Partition("arcface").Len())
+
+	assert.True(t, c.Remove("f-3"))
+	assert.False(t, c.Remove("f-3"))
+	assert.Equal(t, 2, c.RemovePerson("p-1"))
+	assert.Equal(t, 0, c.Len())
+}
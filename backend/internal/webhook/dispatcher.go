@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+// maxErrorBody is how much of a failed response is kept in the delivery log
+const maxErrorBody = 500
+
+// Dispatcher sends pending deliveries from the webhook_deliveries table and
+// retries failures with exponential backoff. Deliveries are claimed by
+// pushing their next attempt into the future, so several server instances
+// can dispatch from the same database.
+type Dispatcher struct {
+	config       Config
+	deliveryRepo *repository.WebhookDeliveryRepository
+	client       *http.Client
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher creates a Dispatcher. Call Start to begin sending.
+func NewDispatcher(deliveryRepo *repository.WebhookDeliveryRepository, config Config) *Dispatcher {
+	defaults := DefaultConfig()
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaults.MaxAttempts
+	}
+	if config.BackoffBase <= 0 {
+		config.BackoffBase = defaults.BackoffBase
+	}
+	if config.BackoffMax < config.BackoffBase {
+		config.BackoffMax = config.BackoffBase
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaults.PollInterval
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaults.Concurrency
+	}
+
+	return &Dispatcher{
+		config:       config,
+		deliveryRepo: deliveryRepo,
+		client:       newHTTPClient(config.Timeout, config.AllowPrivateTargets),
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Start launches the dispatcher goroutines
+func (d *Dispatcher) Start(ctx context.Context) {
+	ctx, d.cancel = context.WithCancel(ctx)
+	for i := 0; i < d.config.Concurrency; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.loop(ctx)
+		}()
+	}
+	log.Printf("Webhook dispatcher started with concurrency %d", d.config.Concurrency)
+}
+
+// Stop cancels in-flight deliveries and waits for the goroutines to exit
+func (d *Dispatcher) Stop() {
+	if d.cancel == nil {
+		return
+	}
+	d.cancel()
+	d.wg.Wait()
+}
+
+// Notify wakes an idle dispatcher so a new delivery is sent without waiting for the next poll
+func (d *Dispatcher) Notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) loop(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		sent, err := d.runOnce(ctx)
+		if err != nil {
+			log.Printf("Warning: webhook dispatcher: %v", err)
+		}
+		if sent && ctx.Err() == nil {
+			continue
+		}
+
+		timer.Reset(d.config.PollInterval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-timer.C:
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+	}
+}
+
+// runOnce claims and sends at most one due delivery. It reports whether one was claimed.
+func (d *Dispatcher) runOnce(ctx context.Context) (bool, error) {
+	now := time.Now()
+	// Keep the claim until the attempt has certainly timed out
+	delivery, err := d.deliveryRepo.ClaimNext(now, now.Add(2*d.config.Timeout))
+	if err != nil {
+		return false, fmt.Errorf("failed to claim delivery: %w", err)
+	}
+	if delivery == nil {
+		return false, nil
+	}
+
+	statusCode, sendErr := d.send(ctx, delivery)
+	if ctx.Err() != nil {
+		// Shutting down: make the delivery due again without counting this attempt
+		return true, d.deliveryRepo.Update(delivery.DeliveryID, map[string]interface{}{
+			"attempts":        gorm.Expr("attempts - 1"),
+			"next_attempt_at": time.Now(),
+		})
+	}
+
+	updates := map[string]interface{}{
+		"last_status_code": nil,
+		"last_error":       nil,
+	}
+	if statusCode != 0 {
+		updates["last_status_code"] = statusCode
+	}
+	switch {
+	case sendErr == nil:
+		updates["status"] = repository.WebhookDeliverySucceeded
+		updates["delivered_at"] = time.Now()
+	case delivery.Attempts >= d.config.MaxAttempts:
+		log.Printf("Webhook delivery %s for job %s failed after %d attempt(s): %v", delivery.DeliveryID, delivery.JobID, delivery.Attempts, sendErr)
+		updates["status"] = repository.WebhookDeliveryFailed
+		updates["last_error"] = sendErr.Error()
+	default:
+		delay := utils.Backoff(delivery.Attempts, d.config.BackoffBase, d.config.BackoffMax)
+		log.Printf("Webhook delivery %s attempt %d failed, retrying in %v: %v", delivery.DeliveryID, delivery.Attempts, delay, sendErr)
+		updates["last_error"] = sendErr.Error()
+		updates["next_attempt_at"] = time.Now().Add(delay)
+	}
+
+	if err := d.deliveryRepo.Update(delivery.DeliveryID, updates); err != nil {
+		return true, fmt.Errorf("failed to update delivery %s: %w", delivery.DeliveryID, err)
+	}
+	return true, nil
+}
+
+// send posts the signed payload. Any 2xx response counts as delivered.
+func (d *Dispatcher) send(ctx context.Context, delivery *repository.WebhookDeliveryEntity) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "arsome-webhooks/1")
+	req.Header.Set(HeaderID, delivery.DeliveryID)
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(d.config.SigningKeys, now, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return resp.StatusCode, nil
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if message := strings.TrimSpace(strings.ToValidUTF8(string(data), "")); message != "" {
+		return resp.StatusCode, fmt.Errorf("%s: %s", resp.Status, message)
+	}
+	return resp.StatusCode, fmt.Errorf("%s", resp.Status)
+}
@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenTarget is returned for webhook targets in blocked address ranges
+var ErrForbiddenTarget = errors.New("webhook target address is not allowed")
+
+// blockedPrefixes are address ranges webhooks may not reach unless private
+// targets are allowed, so callers cannot use the server to probe internal
+// services. Loopback, private, link-local and multicast addresses are checked
+// separately.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // Documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // Documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // Documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved, including broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, may map to internal IPv4
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation
+}
+
+// isForbiddenAddr reports whether addr lies in a range webhooks may not reach
+func isForbiddenAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTarget checks that rawURL is an absolute http(s) URL and, unless
+// allowPrivate is set, that its host does not resolve to a forbidden address.
+// The check is repeated when connecting, so a host that later resolves
+// elsewhere is still refused.
+func ValidateTarget(ctx context.Context, rawURL string, allowPrivate bool) error {
+	if len(rawURL) > 500 {
+		return fmt.Errorf("URL must be at most 500 characters")
+	}
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("malformed URL")
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https")
+	}
+	if target.Hostname() == "" {
+		return fmt.Errorf("host is required")
+	}
+	if target.User != nil {
+		return fmt.Errorf("credentials in the URL are not allowed")
+	}
+	if allowPrivate {
+		return nil
+	}
+
+	host := target.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if isForbiddenAddr(addr) {
+			return ErrForbiddenTarget
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("cannot resolve host %q", host)
+	}
+	for _, addr := range addrs {
+		if isForbiddenAddr(addr) {
+			return ErrForbiddenTarget
+		}
+	}
+	return nil
+}
+
+// newHTTPClient returns a client for sending deliveries. It ignores proxy
+// settings, does not follow redirects and, unless allowPrivate is set, refuses
+// to connect to forbidden addresses after DNS resolution.
+func newHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if isForbiddenAddr(addrPort.Addr()) {
+				return ErrForbiddenTarget
+			}
+			return nil
+		}
+	}
+
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConnsPerHost:   2,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
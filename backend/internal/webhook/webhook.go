@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/utils"
+)
+
+// Event names sent in X-Webhook-Event
+const (
+	EventJobSucceeded = "job.succeeded"
+	EventJobFailed    = "job.failed"
+)
+
+// Headers set on every delivery
+const (
+	HeaderID        = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// SigningKey is a secret shared with webhook receivers. The ID lets receivers
+// pick the right secret while keys are being rotated.
+type SigningKey struct {
+	ID     string
+	Secret string
+}
+
+// Config holds the settings of webhook delivery
+type Config struct {
+	// SigningKeys sign every delivery; each key adds one signature to the header.
+	// Webhooks are disabled when no key is configured.
+	SigningKeys []SigningKey
+	// AllowPrivateTargets permits loopback, private and link-local addresses.
+	// Only enable it for local development.
+	AllowPrivateTargets bool
+	// Timeout bounds a single delivery attempt
+	Timeout time.Duration
+	// MaxAttempts includes the first attempt
+	MaxAttempts int
+	// BackoffBase is the delay before the first retry; it doubles for every further attempt
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// PollInterval is how often idle dispatchers look for due deliveries
+	PollInterval time.Duration
+	// Concurrency is the number of deliveries sent at the same time
+	Concurrency int
+}
+
+// DefaultConfig returns the default webhook settings
+func DefaultConfig() Config {
+	return Config{
+		Timeout:      10 * time.Second,
+		MaxAttempts:  8,
+		BackoffBase:  30 * time.Second,
+		BackoffMax:   time.Hour,
+		PollInterval: 5 * time.Second,
+		Concurrency:  2,
+	}
+}
+
+// GetConfigFromEnv returns the webhook settings from environment variables
+func GetConfigFromEnv() (Config, error) {
+	defaults := DefaultConfig()
+	keys, err := ParseSigningKeys(utils.GetEnv("WEBHOOK_SIGNING_KEYS", ""))
+	if err != nil {
+		return Config{}, err
+	}
+	return Config{
+		SigningKeys:         keys,
+		AllowPrivateTargets: utils.GetEnvBool("WEBHOOK_ALLOW_PRIVATE_TARGETS", false),
+		Timeout:             utils.GetEnvDuration("WEBHOOK_TIMEOUT", defaults.Timeout),
+		MaxAttempts:         utils.GetEnvInt("WEBHOOK_MAX_ATTEMPTS", defaults.MaxAttempts),
+		BackoffBase:         utils.GetEnvDuration("WEBHOOK_BACKOFF_BASE", defaults.BackoffBase),
+		BackoffMax:          utils.GetEnvDuration("WEBHOOK_BACKOFF_MAX", defaults.BackoffMax),
+		PollInterval:        utils.GetEnvDuration("WEBHOOK_POLL_INTERVAL", defaults.PollInterval),
+		Concurrency:         utils.GetEnvInt("WEBHOOK_CONCURRENCY", defaults.Concurrency),
+	}, nil
+}
+
+// ParseSigningKeys parses a comma separated list of id:secret pairs
+func ParseSigningKeys(value string) ([]SigningKey, error) {
+	var keys []SigningKey
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(entry, ":")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("invalid webhook signing key %q (expected id:secret)", id)
+		}
+		if strings.ContainsAny(id, "=, ") {
+			return nil, fmt.Errorf("invalid webhook signing key id %q", id)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate webhook signing key id %q", id)
+		}
+		seen[id] = true
+		keys = append(keys, SigningKey{ID: id, Secret: secret})
+	}
+	return keys, nil
+}
+
+// Sign computes the X-Webhook-Signature header value for a delivery sent at timestamp.
+// Each key contributes "<id>=<hex HMAC-SHA256 of "<timestamp>.<body>">"; receivers
+// verify the entry for a key they know and reject stale timestamps.
+func Sign(keys []SigningKey, timestamp time.Time, body []byte) string {
+	signed := strconv.FormatInt(timestamp.Unix(), 10) + "." + string(body)
+	signatures := make([]string, 0, len(keys))
+	for _, key := range keys {
+		mac := hmac.New(sha256.New, []byte(key.Secret))
+		mac.Write([]byte(signed))
+		signatures = append(signatures, key.ID+"="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return strings.Join(signatures, ",")
+}
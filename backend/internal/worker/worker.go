@@ -0,0 +1,463 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/jphacks/os_2522/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Result is the outcome of a successfully processed job
+type Result struct {
+	Transcript string
+	Words      []models.TranscriptWord
+	Segments   []models.TranscriptSegment
+	Summary    string
+	// SummaryDetails is the structured summary whose headline is Summary
+	SummaryDetails *models.ConversationSummary
+	Language       string
+	DurationSec    float64
+}
+
+// Processor runs a single job. Returned errors are retried with backoff
+// unless they are wrapped with Permanent.
+type Processor interface {
+	Process(ctx context.Context, job *repository.JobEntity) (*Result, error)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error as not worth retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Config holds the settings of the job worker
+type Config struct {
+	// Concurrency is the number of jobs processed at the same time
+	Concurrency int
+	// PollInterval is how often idle workers look for new jobs
+	PollInterval time.Duration
+	// LeaseDuration is how long a claim stays valid without a heartbeat.
+	// Jobs of a crashed worker are picked up again once their lease expires.
+	LeaseDuration time.Duration
+	// JobTimeout bounds a single attempt
+	JobTimeout time.Duration
+	// MaxAttempts includes the first attempt
+	MaxAttempts int
+	// BackoffBase is the delay before the first retry; it doubles for every further attempt
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// DefaultConfig returns the default worker settings
+func DefaultConfig() Config {
+	return Config{
+		Concurrency:   2,
+		PollInterval:  2 * time.Second,
+		LeaseDuration: time.Minute,
+		JobTimeout:    10 * time.Minute,
+		MaxAttempts:   5,
+		BackoffBase:   10 * time.Second,
+		BackoffMax:    10 * time.Minute,
+	}
+}
+
+// GetConfigFromEnv returns the worker settings from environment variables
+func GetConfigFromEnv() Config {
+	defaults := DefaultConfig()
+	return Config{
+		Concurrency:   utils.GetEnvInt("WORKER_CONCURRENCY", defaults.Concurrency),
+		PollInterval:  utils.GetEnvDuration("WORKER_POLL_INTERVAL", defaults.PollInterval),
+		LeaseDuration: utils.GetEnvDuration("WORKER_LEASE_DURATION", defaults.LeaseDuration),
+		JobTimeout:    utils.GetEnvDuration("WORKER_JOB_TIMEOUT", defaults.JobTimeout),
+		MaxAttempts:   utils.GetEnvInt("WORKER_MAX_ATTEMPTS", defaults.MaxAttempts),
+		BackoffBase:   utils.GetEnvDuration("WORKER_BACKOFF_BASE", defaults.BackoffBase),
+		BackoffMax:    utils.GetEnvDuration("WORKER_BACKOFF_MAX", defaults.BackoffMax),
+	}
+}
+
+// ProgressObserver may be implemented by a CompletionHook to also follow jobs
+// while they run: it is told when a processor reports a stage and when a job
+// goes back to the queue.
+type ProgressObserver interface {
+	JobProgress(job *repository.JobEntity, stage models.JobStage)
+}
+
+type stageReporterKey struct{}
+
+// ReportStage tells the progress observers which stage the job processed
+// under ctx has reached. It does nothing outside of a worker.
+func ReportStage(ctx context.Context, stage models.JobStage) {
+	if report, ok := ctx.Value(stageReporterKey{}).(func(models.JobStage)); ok {
+		report(stage)
+	}
+}
+
+// CompletionHook is notified when a job reaches a terminal state (succeeded or failed)
+type CompletionHook interface {
+	// JobFinishing runs inside the transaction that records the outcome, with the
+	// updated job. Returning an error rolls the outcome back and the job is
+	// picked up again once its lease expires.
+	JobFinishing(tx *gorm.DB, job *repository.JobEntity) error
+	// JobFinished runs after the outcome has been committed
+	JobFinished(job *repository.JobEntity)
+}
+
+// Worker claims queued jobs from the database and runs them through a Processor.
+// Several server instances may run workers against the same database; leases
+// guarantee that a job is processed by one worker at a time.
+type Worker struct {
+	config    Config
+	jobRepo   *repository.JobRepository
+	processor Processor
+	hooks     []CompletionHook
+	owner     string
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a Worker. Call Start to begin processing.
+func New(jobRepo *repository.JobRepository, processor Processor, config Config, hooks ...CompletionHook) *Worker {
+	defaults := DefaultConfig()
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaults.Concurrency
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaults.PollInterval
+	}
+	if config.LeaseDuration <= 0 {
+		config.LeaseDuration = defaults.LeaseDuration
+	}
+	if config.JobTimeout <= 0 {
+		config.JobTimeout = defaults.JobTimeout
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaults.MaxAttempts
+	}
+	if config.BackoffBase <= 0 {
+		config.BackoffBase = defaults.BackoffBase
+	}
+	if config.BackoffMax < config.BackoffBase {
+		config.BackoffMax = config.BackoffBase
+	}
+
+	hostname, _ := os.Hostname()
+	return &Worker{
+		config:    config,
+		jobRepo:   jobRepo,
+		processor: processor,
+		hooks:     hooks,
+		owner:     fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.New().String()[:8]),
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Start launches the worker goroutines
+func (w *Worker) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+	for i := 0; i < w.config.Concurrency; i++ {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	log.Printf("Job worker %s started with concurrency %d", w.owner, w.config.Concurrency)
+}
+
+// Stop cancels running jobs, returns them to the queue and waits for the goroutines to exit
+func (w *Worker) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	w.wg.Wait()
+}
+
+// Notify wakes an idle worker so a newly queued job starts without waiting for the next poll
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		processed, err := w.runOnce(ctx)
+		if err != nil {
+			log.Printf("Warning: job worker: %v", err)
+		}
+		if processed && ctx.Err() == nil {
+			continue
+		}
+
+		timer.Reset(w.config.PollInterval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wake:
+		case <-timer.C:
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+	}
+}
+
+// runOnce claims and processes at most one job. It reports whether a job was claimed.
+func (w *Worker) runOnce(ctx context.Context) (bool, error) {
+	now := time.Now()
+	job, err := w.jobRepo.ClaimNext(w.owner, now, now.Add(w.config.LeaseDuration))
+	if err != nil {
+		return false, fmt.Errorf("failed to claim job: %w", err)
+	}
+	if job == nil {
+		return false, nil
+	}
+
+	// Attempts cut short by crashes count too; give up on jobs that keep killing workers
+	if job.Attempts > w.config.MaxAttempts {
+		return true, w.fail(job, fmt.Errorf("exceeded %d attempts", w.config.MaxAttempts))
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, w.config.JobTimeout)
+	defer cancel()
+
+	heartbeatDone := make(chan struct{})
+	leaseLost := make(chan struct{})
+	go w.heartbeat(jobCtx, cancel, job.JobID, heartbeatDone, leaseLost)
+
+	reportCtx := context.WithValue(jobCtx, stageReporterKey{}, func(stage models.JobStage) {
+		w.progress(job, job.Status, stage)
+	})
+	result, processErr := w.processor.Process(reportCtx, job)
+	cancel()
+	<-heartbeatDone
+
+	select {
+	case <-leaseLost:
+		// The job was cancelled or another worker has taken over; their outcome wins
+		log.Printf("Job %s was cancelled or taken over, discarding this attempt", job.JobID)
+		return true, nil
+	default:
+	}
+
+	if ctx.Err() != nil {
+		// Shutting down: hand the job back without counting this attempt
+		return true, w.requeue(job, map[string]interface{}{
+			"status":   repository.JobStatusQueued,
+			"attempts": gorm.Expr("attempts - 1"),
+		})
+	}
+
+	if processErr == nil {
+		return true, w.succeed(job, result)
+	}
+	if IsPermanent(processErr) || job.Attempts >= w.config.MaxAttempts {
+		return true, w.fail(job, processErr)
+	}
+	return true, w.retry(job, processErr)
+}
+
+// heartbeat renews the lease until done, cancelling the job if the lease is lost
+func (w *Worker) heartbeat(ctx context.Context, cancel context.CancelFunc, jobID string, done, lost chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(w.config.LeaseDuration / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := w.jobRepo.RenewLease(jobID, w.owner, time.Now().Add(w.config.LeaseDuration))
+			if errors.Is(err, repository.ErrLeaseLost) {
+				close(lost)
+				cancel()
+				return
+			}
+			if err != nil {
+				log.Printf("Warning: failed to renew lease on job %s: %v", jobID, err)
+			}
+		}
+	}
+}
+
+func (w *Worker) succeed(job *repository.JobEntity, result *Result) error {
+	if result == nil {
+		result = &Result{}
+	}
+	var words *string
+	if len(result.Words) > 0 {
+		encoded, err := json.Marshal(result.Words)
+		if err != nil {
+			return w.fail(job, fmt.Errorf("failed to encode words: %w", err))
+		}
+		encodedWords := string(encoded)
+		words = &encodedWords
+	}
+	var details *string
+	if result.SummaryDetails != nil {
+		encoded, err := json.Marshal(result.SummaryDetails)
+		if err != nil {
+			return w.fail(job, fmt.Errorf("failed to encode summary details: %w", err))
+		}
+		encodedDetails := string(encoded)
+		details = &encodedDetails
+	}
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":          repository.JobStatusSucceeded,
+		"transcript":      result.Transcript,
+		"words":           words,
+		"summary":         result.Summary,
+		"summary_details": details,
+		"language":        result.Language,
+		"error_message":   nil,
+		"finished_at":     now,
+	}
+	// The duration read from the audio on upload is exact; the transcriber's is an estimate
+	if job.DurationSec == nil {
+		updates["duration_sec"] = result.DurationSec
+	}
+
+	segments := make([]repository.TranscriptSegmentEntity, 0, len(result.Segments))
+	for _, segment := range result.Segments {
+		segments = append(segments, repository.TranscriptSegmentEntity{
+			Speaker:  segment.Speaker,
+			StartSec: segment.Start,
+			EndSec:   segment.End,
+			Text:     segment.Text,
+		})
+	}
+	return w.complete(job, updates, func(tx *gorm.DB) error {
+		return repository.NewTranscriptRepository(tx).ReplaceSegments(job.JobID, segments)
+	})
+}
+
+func (w *Worker) fail(job *repository.JobEntity, cause error) error {
+	log.Printf("Job %s failed after %d attempt(s): %v", job.JobID, job.Attempts, cause)
+	now := time.Now()
+	return w.complete(job, map[string]interface{}{
+		"status":        repository.JobStatusFailed,
+		"error_message": cause.Error(),
+		"finished_at":   now,
+	}, nil)
+}
+
+func (w *Worker) retry(job *repository.JobEntity, cause error) error {
+	delay := w.backoff(job.Attempts)
+	log.Printf("Job %s attempt %d failed, retrying in %v: %v", job.JobID, job.Attempts, delay, cause)
+	return w.requeue(job, map[string]interface{}{
+		"status":          repository.JobStatusQueued,
+		"error_message":   cause.Error(),
+		"next_attempt_at": time.Now().Add(delay),
+	})
+}
+
+func (w *Worker) finish(job *repository.JobEntity, updates map[string]interface{}) error {
+	return w.leaseError(job, w.jobRepo.FinishLeased(job.JobID, w.owner, updates, nil))
+}
+
+// requeue hands a job back to the queue and tells the progress observers
+func (w *Worker) requeue(job *repository.JobEntity, updates map[string]interface{}) error {
+	err := w.jobRepo.FinishLeased(job.JobID, w.owner, updates, nil)
+	if err == nil {
+		w.progress(job, repository.JobStatusQueued, models.JobStageUploaded)
+	}
+	return w.leaseError(job, err)
+}
+
+// progress notifies the hooks that observe progress
+func (w *Worker) progress(job *repository.JobEntity, status repository.JobStatus, stage models.JobStage) {
+	snapshot := *job
+	snapshot.Status = status
+	for _, hook := range w.hooks {
+		if observer, ok := hook.(ProgressObserver); ok {
+			observer.JobProgress(&snapshot, stage)
+		}
+	}
+}
+
+// complete records a terminal outcome and runs the completion hooks. store,
+// when set, saves data belonging to the outcome in the same transaction.
+func (w *Worker) complete(job *repository.JobEntity, updates map[string]interface{}, store func(tx *gorm.DB) error) error {
+	if len(w.hooks) == 0 && store == nil {
+		return w.finish(job, updates)
+	}
+
+	var finished *repository.JobEntity
+	err := w.jobRepo.FinishLeased(job.JobID, w.owner, updates, func(tx *gorm.DB, updated *repository.JobEntity) error {
+		if store != nil {
+			if err := store(tx); err != nil {
+				return err
+			}
+		}
+		for _, hook := range w.hooks {
+			if err := hook.JobFinishing(tx, updated); err != nil {
+				return err
+			}
+		}
+		finished = updated
+		return nil
+	})
+	if err != nil {
+		return w.leaseError(job, err)
+	}
+	for _, hook := range w.hooks {
+		hook.JobFinished(finished)
+	}
+	return nil
+}
+
+// leaseError wraps a failed outcome update. Losing the lease between the last
+// heartbeat and the update is expected when a job is cancelled, so it is not an error.
+func (w *Worker) leaseError(job *repository.JobEntity, err error) error {
+	if errors.Is(err, repository.ErrLeaseLost) {
+		log.Printf("Job %s was cancelled or taken over, discarding this attempt", job.JobID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update job %s: %w", job.JobID, err)
+	}
+	return nil
+}
+
+func (w *Worker) backoff(attempt int) time.Duration {
+	return utils.Backoff(attempt, w.config.BackoffBase, w.config.BackoffMax)
+}
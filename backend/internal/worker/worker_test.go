@@ -0,0 +1,429 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jphacks/os_2522/backend/internal/database"
+	"github.com/jphacks/os_2522/backend/internal/models"
+	"github.com/jphacks/os_2522/backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// processorFunc adapts a function to the Processor interface
+type processorFunc func(ctx context.Context, job *repository.JobEntity) (*Result, error)
+
+func (f processorFunc) Process(ctx context.Context, job *repository.JobEntity) (*Result, error) {
+	return f(ctx, job)
+}
+
+func newTestRepo(t *testing.T) (*repository.JobRepository, *gorm.DB) {
+	db, err := database.NewDB(&database.Config{
+		Driver: "sqlite",
+		DBName: fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()),
+	})
+	require.NoError(t, err)
+	require.NoError(t, database.AutoMigrate(db))
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return repository.NewJobRepository(db), db
+}
+
+func createJob(t *testing.T, repo *repository.JobRepository, jobID string) {
+	audioKey := "sha256/00/" + jobID
+	require.NoError(t, repo.Create(&repository.JobEntity{
+		JobID:     jobID,
+		Status:    repository.JobStatusQueued,
+		AudioKey:  &audioKey,
+		CreatedAt: time.Now(),
+	}))
+}
+
+func testConfig() Config {
+	return Config{
+		Concurrency:   1,
+		PollInterval:  10 * time.Millisecond,
+		LeaseDuration: time.Minute,
+		JobTimeout:    time.Second,
+		MaxAttempts:   3,
+		BackoffBase:   time.Minute,
+		BackoffMax:    time.Hour,
+	}
+}
+
+func succeeding() Processor {
+	return processorFunc(func(ctx context.Context, job *repository.JobEntity) (*Result, error) {
+		return &Result{
+			Transcript: "hello",
+			Words:      []models.TranscriptWord{{Word: "hello", Start: 0.1, End: 0.6}},
+			Segments:   []models.TranscriptSegment{{Speaker: "speaker_1", Start: 0, End: 0.7, Text: "hello"}},
+			Summary:    "greeting",
+			SummaryDetails: &models.ConversationSummary{
+				Headline:  "greeting",
+				Topics:    []string{"greetings"},
+				Facts:     []models.PersonFact{},
+				FollowUps: []models.FollowUp{},
+			},
+			Language:    "ja",
+			DurationSec: 1.5,
+		}, nil
+	})
+}
+
+func TestWorker_ProcessesJob(t *testing.T) {
+	repo, db := newTestRepo(t)
+	createJob(t, repo, "j-1")
+	w := New(repo, succeeding(), testConfig())
+
+	processed, err := w.runOnce(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, processed)
+
+	job, err := repo.FindByID("j-1")
+	require.NoError(t, err)
+	assert.Equal(t, repository.JobStatusSucceeded, job.Status)
+	assert.Equal(t, "hello", *job.Transcript)
+	assert.JSONEq(t, `[{"word":"hello","start":0.1,"end":0.6}]`, *job.Words)
+	assert.Equal(t, "greeting", *job.Summary)
+	assert.JSONEq(t, `{"headline":"greeting","topics":["greetings"],"facts":[],"follow_ups":[]}`, *job.SummaryDetails)
+	assert.Equal(t, "ja", *job.Language)
+	assert.Equal(t, 1.5, *job.DurationSec)
+	assert.Equal(t, 1, job.Attempts)
+	assert.NotNil(t, job.StartedAt)
+	assert.NotNil(t, job.FinishedAt)
+	assert.Nil(t, job.LeaseOwner)
+	assert.Nil(t, job.LeaseExpiresAt)
+
+	segments, err := repository.NewTranscriptRepository(db).FindSegments("j-1")
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	assert.Equal(t, repository.TranscriptSegmentEntity{
+		JobID: "j-1", Seq: 0, Speaker: "speaker_1", StartSec: 0, EndSec: 0.7, Text: "hello",
+	}, segments[0])
+
+	processed, err = w.runOnce(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, processed)
+}
+
+func TestWorker_RetriesWithBackoff(t *testing.T) {
+	repo, db := newTestRepo(t)
+	createJob(t, repo, "j-1")
+
+	calls := 0
+	w := New(repo, processorFunc(func(ctx context.Context, job *repository.JobEntity) (*Result, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("backend unavailable")
+		}
+		return &Result{Transcript: "hello"}, nil
+	}), testConfig())
+
+	before := time.Now()
+	_, err := w.runOnce(context.Background())
+	assert.NoError(t, err)
+
+	job, err := repo.FindByID("j-1")
+	require.NoError(t, err)
+	assert.Equal(t, repository.JobStatusQueued, job.Status)
+	assert.Equal(t, "backend unavailable", *job.ErrorMessage)
+	require.NotNil(t, job.NextAttemptAt)
+	assert.True(t, job.NextAttemptAt.After(before.Add(29*time.Second)))
+	assert.Nil(t, job.FinishedAt)
+
+	// Not retried before its backoff has passed
+	processed, err := w.runOnce(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, processed)
+
+	require.NoError(t, db.Model(&repository.JobEntity{}).Where("job_id = ?", "j-1").
+		Update("next_attempt_at", time.Now().Add(-time.Second)).Error)
+	processed, err = w.runOnce(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, processed)
+
+	job, err = repo.FindByID("j-1")
+	require.NoError(t, err)
+	assert.Equal(t, repository.JobStatusSucceeded, job.Status)
+	assert.Equal(t, 2, job.Attempts)
+	assert.Nil(t, job.ErrorMessage)
+}
+
+func TestWorker_FailsAfterMaxAttempts(t *testing.T) {
+	repo, db := newTestRepo(t)
+	createJob(t, repo, "j-1")
+	w := New(repo, processorFunc(func(ctx context.Context, job *repository.JobEntity) (*Result, error) {
+		return nil, errors.New("backend unavailable")
+	}), testConfig())
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, db.Model(&repository.JobEntity{}).Where("job_id = ?", "j-1").
+			Update("next_attempt_at", nil).Error)
+		processed, err := w.runOnce(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, processed)
+	}
+
+	job, err := repo.FindByID("j-1")
+	require.NoError(t, err)
+	assert.Equal(t, repository.JobStatusFailed, job.Status)
+	assert.Equal(t, 3, job.Attempts)
+	assert.NotNil(t, job.FinishedAt)
+}
+
+func TestWorker_PermanentErrorIsNotRetried(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	createJob(t, repo, "j-1")
+	w := New(repo, processorFunc(func(ctx context.Context, job *repository.JobEntity) (*Result, error) {
+		return nil, Permanent(errors.New("job has no audio"))
+	}), testConfig())
+
+	_, err := w.runOnce(context.Background())
+	assert.NoError(t, err)
+
+	job, err := repo.FindByID("j-1")
+	require.NoError(t, err)
+	assert.Equal(t, repository.JobStatusFailed, job.Status)
+	assert.Equal(t, "job has no audio", *job.ErrorMessage)
+	assert.Equal(t, 1, job.Attempts)
+}
+
+// recordingHook records completion callbacks and can fail the transaction
+type recordingHook struct {
+	finishing []string
+	finished  []repository.JobStatus
+	err       error
+}
+
+func (h *recordingHook) JobFinishing(tx *gorm.DB, job *repository.JobEntity) error {
+	h.finishing = append(h.finishing, job.JobID)
+	return h.err
+}
+
+func (h *recordingHook) JobFinished(job *repository.JobEntity) {
+	h.finished = append(h.finished, job.Status)
+}
+
+func TestWorker_CompletionHooks(t *testing.T) {
+	repo, db := newTestRepo(t)
+	createJob(t, repo, "j-1")
+	hook := &recordingHook{err: errors.New("outbox unavailable")}
+	w := New(repo, succeeding(), testConfig(), hook)
+
+	// A failing hook rolls the outcome back and leaves the lease to expire
+	_, err := w.runOnce(context.Background())
+	assert.Error(t, err)
+	job, err := repo.FindByID("j-1")
+	require.NoError(t, err)
+	assert.Equal(t, repository.JobStatusRunning, job.Status)
+	assert.Empty(t, hook.finished)
+
+	hook.err = nil
+	require.NoError(t, db.Model(&repository.JobEntity{}).Where("job_id = ?", "j-1").
+		Update("lease_expires_at", time.Now().Add(-time.Second)).Error)
+	_, err = w.runOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"j-1", "j-1"}, hook.finishing)
+	assert.Equal(t, []repository.JobStatus{repository.JobStatusSucceeded}, hook.finished)
+
+	// Retries are not terminal and do not reach the hooks
+	createJob(t, repo, "j-2")
+	w = New(repo, processorFunc(func(ctx context.Context, job *repository.JobEntity) (*Result, error) {
+		return nil, errors.New("backend unavailable")
+	}), testConfig(), hook)
+	_, err = w.runOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, hook.finished, 1)
+}
+
+// progressHook records the stages reported to a ProgressObserver
+type progressHook struct {
+	recordingHook
+	stages []string
+}
+
+func (h *progressHook) JobProgress(job *repository.JobEntity, stage models.JobStage) {
+	h.stages = append(h.stages, string(job.Status)+"/"+string(stage))
+}
+
+func TestWorker_ReportsProgress(t *testing.T) {
+	repo, db := newTestRepo(t)
+	createJob(t, repo, "j-1")
+
+	calls := 0
+	hook := &progressHook{}
+	w := New(repo, processorFunc(func(ctx context.Context, job *repository.JobEntity) (*Result, error) {
+		calls++
+		ReportStage(ctx, models.JobStageTranscribing)
+		if calls == 1 {
+			return nil, errors.New("backend unavailable")
+		}
+		ReportStage(ctx, models.JobStageSummarizing)
+		return &Result{Transcript: "hello"}, nil
+	}), testConfig(), hook)
+
+	_, err := w.runOnce(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&repository.JobEntity{}).Where("job_id = ?", "j-1").
+		Update("next_attempt_at", time.Now().Add(-time.Second)).Error)
+	_, err = w.runOnce(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"running/transcribing", "queued/uploaded", "running/transcribing", "running/summarizing"}, hook.stages)
+	assert.Equal(t, []repository.JobStatus{repository.JobStatusSucceeded}, hook.finished)
+
+	// Outside of a worker reporting is a no-op
+	ReportStage(context.Background(), models.JobStageTranscribing)
+}
+
+func TestWorker_RecoversExpiredLease(t *testing.T) {
+	repo, db := newTestRepo(t)
+	createJob(t, repo, "j-expired")
+	createJob(t, repo, "j-active")
+
+	// Simulate a worker that crashed mid-job and one that is still alive
+	expired := time.Now().Add(-time.Minute)
+	active := time.Now().Add(time.Hour)
+	require.NoError(t, db.Model(&repository.JobEntity{}).Where("job_id = ?", "j-expired").Updates(map[string]interface{}{
+		"status": repository.JobStatusRunning, "lease_owner": "crashed", "lease_expires_at": expired, "attempts": 1,
+	}).Error)
+	require.NoError(t, db.Model(&repository.JobEntity{}).Where("job_id = ?", "j-active").Updates(map[string]interface{}{
+		"status": repository.JobStatusRunning, "lease_owner": "alive", "lease_expires_at": active, "attempts": 1,
+	}).Error)
+
+	w := New(repo, succeeding(), testConfig())
+	processed, err := w.runOnce(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, processed)
+	processed, err = w.runOnce(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, processed)
+
+	job, err := repo.FindByID("j-expired")
+	require.NoError(t, err)
+	assert.Equal(t, repository.JobStatusSucceeded, job.Status)
+	assert.Equal(t, 2, job.Attempts)
+
+	job, err = repo.FindByID("j-active")
+	require.NoError(t, err)
+	assert.Equal(t, repository.JobStatusRunning, job.Status)
+	assert.Equal(t, "alive", *job.LeaseOwner)
+
+	// The crashed worker can no longer write its result
+	assert.ErrorIs(t, repo.FinishLeased("j-expired", "crashed", map[string]interface{}{}, nil), repository.ErrLeaseLost)
+}
+
+func TestWorker_CancelledJobIsDiscarded(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	createJob(t, repo, "j-1")
+
+	config := testConfig()
+	config.LeaseDuration = 30 * time.Millisecond
+	w := New(repo, processorFunc(func(ctx context.Context, job *repository.JobEntity) (*Result, error) {
+		_, err := repo.Transition(job.JobID, repository.JobStatusCancelled, map[string]interface{}{
+			"lease_owner":      nil,
+			"lease_expires_at": nil,
+		})
+		require.NoError(t, err)
+		// The next heartbeat notices the cancellation and stops the attempt
+		<-ctx.Done()
+		return &Result{Transcript: "too late"}, nil
+	}), config)
+
+	processed, err := w.runOnce(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, processed)
+
+	job, err := repo.FindByID("j-1")
+	require.NoError(t, err)
+	assert.Equal(t, repository.JobStatusCancelled, job.Status)
+	assert.Nil(t, job.Transcript)
+
+	// Cancelled jobs are final
+	_, err = repo.Transition("j-1", repository.JobStatusQueued, map[string]interface{}{})
+	assert.ErrorIs(t, err, repository.ErrInvalidTransition)
+	_, err = repo.Transition("j-missing", repository.JobStatusCancelled, map[string]interface{}{})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestWorker_ShutdownRequeuesJob(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	createJob(t, repo, "j-1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := New(repo, processorFunc(func(jobCtx context.Context, job *repository.JobEntity) (*Result, error) {
+		cancel()
+		<-jobCtx.Done()
+		return nil, jobCtx.Err()
+	}), testConfig())
+
+	_, err := w.runOnce(ctx)
+	assert.NoError(t, err)
+
+	job, err := repo.FindByID("j-1")
+	require.NoError(t, err)
+	assert.Equal(t, repository.JobStatusQueued, job.Status)
+	assert.Equal(t, 0, job.Attempts)
+	assert.Nil(t, job.LeaseOwner)
+}
+
+func TestWorker_StartProcessesEachJobOnce(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	for i := 0; i < 6; i++ {
+		createJob(t, repo, fmt.Sprintf("j-%d", i))
+	}
+
+	var mu sync.Mutex
+	seen := map[string]int{}
+	var running, maxRunning int32
+	config := testConfig()
+	config.Concurrency = 3
+	w := New(repo, processorFunc(func(ctx context.Context, job *repository.JobEntity) (*Result, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		seen[job.JobID]++
+		mu.Unlock()
+		return &Result{Transcript: job.JobID}, nil
+	}), config)
+
+	w.Start(context.Background())
+	w.Notify()
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(seen) == 6
+	}, 5*time.Second, 10*time.Millisecond)
+	w.Stop()
+
+	for jobID, count := range seen {
+		assert.Equal(t, 1, count, jobID)
+	}
+	assert.LessOrEqual(t, maxRunning, int32(3))
+}
+
+func TestIsPermanent(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", Permanent(errors.New("bad input")))
+	assert.True(t, IsPermanent(err))
+	assert.EqualError(t, err, "wrapped: bad input")
+	assert.False(t, IsPermanent(errors.New("timeout")))
+	assert.Nil(t, Permanent(nil))
+}
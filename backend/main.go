@@ -6,11 +6,18 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/jphacks/os_2522/backend/internal/database"
+	"github.com/jphacks/os_2522/backend/internal/events"
+	"github.com/jphacks/os_2522/backend/internal/extraction"
 	"github.com/jphacks/os_2522/backend/internal/handler"
+	"github.com/jphacks/os_2522/backend/internal/llm"
 	"github.com/jphacks/os_2522/backend/internal/middleware"
 	"github.com/jphacks/os_2522/backend/internal/repository"
 	"github.com/jphacks/os_2522/backend/internal/service"
-	"github.com/jphacks/os_2522/backend/internal/utils"
+	"github.com/jphacks/os_2522/backend/internal/storage"
+	"github.com/jphacks/os_2522/backend/internal/transcription"
+	"github.com/jphacks/os_2522/backend/internal/vectorindex"
+	"github.com/jphacks/os_2522/backend/internal/webhook"
+	"github.com/jphacks/os_2522/backend/internal/worker"
 )
 
 func main() {
@@ -28,48 +35,130 @@ func main() {
 
 	log.Println("Database initialized successfully")
 
-	// Initialize Gemini client
+	// Initialize the language model used for summaries.
+	// Without one /summarize answers 503 and jobs finish with an empty summary.
 	ctx := context.Background()
-	geminiClient, err := utils.NewGeminiClient(ctx)
-	if err != nil {
-		log.Printf("Warning: Failed to initialize Gemini client: %v", err)
-		log.Println("Summarization endpoint will not be available")
+	var llmProvider llm.Provider
+	if p, err := llm.New(llm.GetConfigFromEnv()); err != nil {
+		log.Printf("Warning: Failed to initialize LLM provider: %v", err)
+		log.Println("Summarization, suggestions and profile updates will not be available")
+	} else {
+		llmProvider = p
+		defer llmProvider.Close()
+		log.Printf("LLM provider: %s", llmProvider.Name())
 	}
-	defer func() {
-		if geminiClient != nil {
-			geminiClient.Close()
-		}
-	}()
 
 	// Initialize repositories
 	personRepo := repository.NewPersonRepository(db)
 	faceRepo := repository.NewFaceRepository(db)
 	encounterRepo := repository.NewEncounterRepository(db)
 	jobRepo := repository.NewJobRepository(db)
+	transcriptRepo := repository.NewTranscriptRepository(db)
+	webhookDeliveryRepo := repository.NewWebhookDeliveryRepository(db)
+	uploadRepo := repository.NewUploadRepository(db)
+	modelRepo := repository.NewModelRepository(db)
+	suggestionRepo := repository.NewSuggestionRepository(db)
+	profileRepo := repository.NewProfileRepository(db)
+
+	// Initialize model registry
+	modelService := service.NewModelService(modelRepo)
+
+	// Load face embedding index
+	faceIndex, err := service.LoadFaceIndex(faceRepo, modelService, vectorindex.DefaultConfig())
+	if err != nil {
+		log.Fatalf("Failed to load face index: %v", err)
+	}
+	log.Printf("Face index loaded with %d embeddings", faceIndex.Len())
 
 	// Initialize services
-	personService := service.NewPersonService(personRepo, faceRepo)
-	faceService := service.NewFaceService(faceRepo, personRepo)
-	faceExtractionService := service.NewFaceExtractionService()
-	recognitionService := service.NewRecognitionService(faceRepo, personRepo, encounterRepo)
-	encounterService := service.NewEncounterService(encounterRepo, personRepo)
-	jobService := service.NewJobService(jobRepo)
-	var summarizeService *service.SummarizeService
-	if geminiClient != nil {
-		summarizeService = service.NewSummarizeService(geminiClient)
+	extractor, err := extraction.New(extraction.GetConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Failed to initialize face extraction: %v", err)
+	}
+	log.Printf("Face extraction backend: %s (model %s)", extractor.Name(), extractor.ModelVersion())
+	defer extractor.Close()
+	if err := modelService.CheckExtractionModel(extractor.ModelVersion(), extractor.Dimension()); err != nil {
+		log.Fatalf("Face extraction model does not match the registry: %v", err)
+	}
+	faceExtractionService := service.NewFaceExtractionService(extractor)
+	audioStore, err := storage.New(storage.GetConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Failed to initialize audio storage: %v", err)
+	}
+	log.Printf("Audio storage backend: %s", audioStore.Name())
+	faceService := service.NewFaceService(faceRepo, personRepo, faceIndex, modelService)
+	recognitionService := service.NewRecognitionService(faceIndex, modelService, personRepo, encounterRepo, service.GetRecognitionConfigFromEnv())
+	encounterService := service.NewEncounterService(encounterRepo, personRepo, service.GetEncounterConfigFromEnv())
+	summarizeService := service.NewSummarizeService(llmProvider, service.GetSummarizeConfigFromEnv())
+	var summarizer service.Summarizer
+	if llmProvider != nil {
+		summarizer = summarizeService
 	}
 
+	// Start the background job worker.
+	// Without a transcription backend jobs fail with "transcription backend is not configured".
+	var transcriber transcription.Transcriber
+	if t, err := transcription.New(transcription.GetConfigFromEnv()); err != nil {
+		log.Printf("Warning: Failed to initialize transcription: %v", err)
+		log.Println("Transcription jobs will fail until a backend is configured")
+	} else {
+		transcriber = t
+		log.Printf("Transcription backend: %s", transcriber.Name())
+	}
+	// Webhooks are sent by a dispatcher of their own so slow receivers never hold up jobs
+	webhookConfig, err := webhook.GetConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load webhook settings: %v", err)
+	}
+	webhookDispatcher := webhook.NewDispatcher(webhookDeliveryRepo, webhookConfig)
+	if len(webhookConfig.SigningKeys) > 0 {
+		webhookDispatcher.Start(ctx)
+		defer webhookDispatcher.Stop()
+	} else {
+		log.Println("Warning: WEBHOOK_SIGNING_KEYS is not set; webhook_url will be rejected")
+	}
+	webhookService := service.NewWebhookService(webhookDeliveryRepo, webhookConfig, webhookDispatcher)
+	// Progress events are kept in memory; clients that miss them resume from the job's current state
+	eventBroker := events.NewBroker(events.GetConfigFromEnv())
+	jobEventService := service.NewJobEventService(jobRepo, eventBroker)
+
+	// Encounter summaries are merged into person profiles in the background, one at a time
+	profileUpdater := service.NewProfileUpdater(llmProvider, personRepo, encounterRepo, profileRepo, service.GetProfileConfigFromEnv())
+	if llmProvider != nil {
+		profileUpdater.Start(ctx)
+		defer profileUpdater.Stop()
+	}
+	profileService := service.NewProfileService(personRepo, profileRepo, profileUpdater)
+
+	transcriptionProcessor := service.NewTranscriptionProcessor(audioStore, transcriber, summarizer)
+	// Completion hooks run in order; the encounter is linked before it is queued for the profile
+	// and before the webhook payload is built, and progress events go out last, once everything
+	// about the job is final
+	jobWorker := worker.New(jobRepo, transcriptionProcessor, worker.GetConfigFromEnv(), encounterService, profileService, webhookService, jobEventService)
+	jobWorker.Start(ctx)
+	defer jobWorker.Stop()
+	jobService := service.NewJobService(jobRepo, transcriptRepo, audioStore, jobWorker, webhookService, jobEventService, service.GetAudioConfigFromEnv())
+	uploadService := service.NewUploadService(uploadRepo, personRepo, audioStore, jobService, service.GetUploadConfigFromEnv())
+	// Audio left behind by crashes or other instances is deleted after a grace period
+	audioSweeper := service.NewAudioSweeper(jobService)
+	audioSweeper.Start(ctx)
+	defer audioSweeper.Stop()
+	personService := service.NewPersonService(personRepo, faceRepo, faceIndex, modelService, faceExtractionService, jobService)
+	suggestionService := service.NewSuggestionService(llmProvider, personRepo, encounterRepo, suggestionRepo, service.GetSuggestionConfigFromEnv())
+
 	// Initialize handlers
 	healthHandler := handler.NewHealthHandler()
 	personHandler := handler.NewPersonHandler(personService)
 	faceHandler := handler.NewFaceHandler(faceService, faceExtractionService)
 	recognitionHandler := handler.NewRecognitionHandler(recognitionService, faceExtractionService)
+	modelHandler := handler.NewModelHandler(modelService)
 	encounterHandler := handler.NewEncounterHandler(encounterService)
+	suggestionHandler := handler.NewSuggestionHandler(suggestionService)
+	profileHandler := handler.NewProfileHandler(profileService)
 	transcribeHandler := handler.NewTranscribeHandler(jobService)
-	var summarizeHandler *handler.SummarizeHandler
-	if summarizeService != nil {
-		summarizeHandler = handler.NewSummarizeHandler(summarizeService)
-	}
+	uploadHandler := handler.NewUploadHandler(uploadService)
+	jobEventsHandler := handler.NewJobEventsHandler(jobEventService, eventBroker.Config().Heartbeat)
+	summarizeHandler := handler.NewSummarizeHandler(summarizeService)
 
 	// Setup Gin router
 	r := gin.Default()
@@ -87,8 +176,14 @@ func main() {
 
 	// Recognition endpoints
 	protected.POST("/recognize", recognitionHandler.PostRecognize)
+	protected.POST("/recognize/batch", recognitionHandler.PostRecognizeBatch)
 	protected.POST("/recognize-image", recognitionHandler.PostRecognizeImage)
 
+	// Embedding model registry endpoints
+	protected.GET("/models", modelHandler.ListModels)
+	protected.POST("/models", modelHandler.CreateModel)
+	protected.GET("/models/:model_version", modelHandler.GetModel)
+
 	// Person endpoints
 	protected.GET("/persons", personHandler.ListPersons)
 	protected.POST("/persons", personHandler.CreatePerson)
@@ -105,14 +200,35 @@ func main() {
 	// Encounter endpoints
 	protected.GET("/persons/:person_id/encounters", encounterHandler.ListEncounters)
 
+	// Conversation suggestion endpoints
+	protected.GET("/persons/:person_id/suggestions", suggestionHandler.GetSuggestions)
+
+	// Person profile endpoints
+	protected.GET("/persons/:person_id/profile", profileHandler.GetProfile)
+	protected.GET("/persons/:person_id/profile/versions", profileHandler.ListVersions)
+	protected.GET("/persons/:person_id/profile/versions/:version", profileHandler.GetVersion)
+	protected.POST("/persons/:person_id/profile/revert", profileHandler.RevertProfile)
+
 	// Transcription endpoints
 	protected.POST("/transcribe", transcribeHandler.PostTranscribe)
+	protected.GET("/jobs", transcribeHandler.ListJobs)
 	protected.GET("/jobs/:job_id", transcribeHandler.GetJob)
+	protected.DELETE("/jobs/:job_id", transcribeHandler.DeleteJob)
+	protected.POST("/jobs/:job_id/cancel", transcribeHandler.CancelJob)
+	protected.POST("/jobs/:job_id/retry", transcribeHandler.RetryJob)
+	protected.GET("/jobs/:job_id/deliveries", transcribeHandler.ListDeliveries)
+	protected.GET("/jobs/:job_id/transcript", transcribeHandler.GetTranscript)
+	protected.GET("/jobs/:job_id/events", jobEventsHandler.StreamJobEvents)
+
+	// Resumable upload endpoints
+	protected.POST("/uploads", uploadHandler.CreateUpload)
+	protected.GET("/uploads/:upload_id", uploadHandler.GetUpload)
+	protected.PATCH("/uploads/:upload_id", uploadHandler.AppendChunk)
+	protected.POST("/uploads/:upload_id/complete", uploadHandler.CompleteUpload)
+	protected.DELETE("/uploads/:upload_id", uploadHandler.DeleteUpload)
 
 	// Summarization endpoint
-	if summarizeHandler != nil {
-		protected.POST("/summarize", summarizeHandler.PostSummarize)
-	}
+	protected.POST("/summarize", summarizeHandler.PostSummarize)
 
 	log.Println("Starting server on :8080")
 	if err := r.Run(":8080"); err != nil {
@@ -1,18 +1,28 @@
 
+import base64
+import os
 import sys
 import json
+import struct
 import numpy as np
 import cv2
 import tensorflow as tf
 
 # --- Constants ---
-FACENET_MODEL_PATH = "backend/ml/facenet.tflite"
-BLAZEFACE_MODEL_PATH = "backend/ml/blazeface.tflite"
+# Models live next to this script unless overridden, whatever the working directory
+ML_DIR = os.path.dirname(os.path.abspath(__file__))
+FACENET_MODEL_PATH = os.environ.get("EXTRACTION_FACENET_MODEL_PATH") or os.path.join(ML_DIR, "facenet.tflite")
+BLAZEFACE_MODEL_PATH = os.environ.get("EXTRACTION_BLAZEFACE_MODEL_PATH") or os.path.join(ML_DIR, "blazeface.tflite")
 
 # Input image settings
 FACENET_INPUT_SIZE = (160, 160)
 BLAZEFACE_INPUT_SIZE = (128, 128)
 
+# Detection settings
+DETECTION_THRESHOLD = 0.5  # Minimum BlazeFace confidence
+NMS_IOU_THRESHOLD = 0.3    # Detections overlapping more than this are the same face
+MAX_FACES = 20             # Matches the batch recognition limit
+
 # --- Load TFLite models ---
 try:
     facenet_interpreter = tf.lite.Interpreter(model_path=FACENET_MODEL_PATH)
@@ -37,52 +47,68 @@ def preprocess_image(image, target_size):
     # Add batch dimension
     return np.expand_dims(img_normalized, axis=0)
 
-def detect_face(image):
-    """Detects the most prominent face in an image using BlazeFace."""
+def iou(a, b):
+    """Intersection over union of two (x_min, y_min, x_max, y_max) boxes."""
+    ix = max(0, min(a[2], b[2]) - max(a[0], b[0]))
+    iy = max(0, min(a[3], b[3]) - max(a[1], b[1]))
+    intersection = ix * iy
+    union = (a[2] - a[0]) * (a[3] - a[1]) + (b[2] - b[0]) * (b[3] - b[1]) - intersection
+    return intersection / union if union > 0 else 0.0
+
+def detect_faces(image):
+    """Detects every face in an image using BlazeFace.
+
+    Returns a list of (cropped_face, box, score) sorted by score, highest first.
+    box is (x_min, y_min, x_max, y_max) in original image pixels.
+    """
     original_h, original_w, _ = image.shape
-    
+
     # Preprocess for BlazeFace
     input_tensor = preprocess_image(image, BLAZEFACE_INPUT_SIZE)
-    
+
     # Run BlazeFace inference
     blazeface_interpreter.set_tensor(blazeface_input_details[0]['index'], input_tensor)
     blazeface_interpreter.invoke()
-    
+
     # Process output: The model outputs detections and scores.
     # Output tensor at index 0 contains bounding boxes, index 1 contains scores.
     detections = blazeface_interpreter.get_tensor(blazeface_output_details[0]['index'])[0]
     scores = blazeface_interpreter.get_tensor(blazeface_output_details[1]['index'])[0]
 
-    if len(scores) == 0 or np.max(scores) < 0.5: # Confidence threshold
-        return None, "No face detected with sufficient confidence."
-
-    # Find the detection with the highest score
-    best_detection_idx = np.argmax(scores)
-    detection = detections[best_detection_idx]
-
-    # Bounding box coordinates are relative to the input size (128x128)
-    # and need to be scaled to the original image dimensions.
-    # The format is [ymin, xmin, ymax, xmax]
-    ymin, xmin, ymax, xmax = detection
-    
-    x_min = int(xmin * original_w)
-    x_max = int(xmax * original_w)
-    y_min = int(ymin * original_h)
-    y_max = int(ymax * original_h)
-
-    # Ensure coordinates are within image bounds
-    x_min = max(0, x_min)
-    y_min = max(0, y_min)
-    x_max = min(original_w, x_max)
-    y_max = min(original_h, y_max)
-
-    # Crop the face from the original image
-    cropped_face = image[y_min:y_max, x_min:x_max]
-    
-    if cropped_face.size == 0:
-        return None, "Failed to crop face from image."
-
-    return cropped_face, None
+    faces = []
+    kept_boxes = []
+    for idx in np.argsort(scores)[::-1]:
+        score = float(scores[idx])
+        if score < DETECTION_THRESHOLD:
+            break
+
+        # Bounding box coordinates are relative to the input size (128x128)
+        # and need to be scaled to the original image dimensions.
+        # The format is [ymin, xmin, ymax, xmax]
+        ymin, xmin, ymax, xmax = detections[idx]
+
+        # Ensure coordinates are within image bounds
+        x_min = max(0, int(xmin * original_w))
+        y_min = max(0, int(ymin * original_h))
+        x_max = min(original_w, int(xmax * original_w))
+        y_max = min(original_h, int(ymax * original_h))
+        box = (x_min, y_min, x_max, y_max)
+
+        # Overlapping anchors fire for the same face; keep only the strongest one
+        if any(iou(box, kept) > NMS_IOU_THRESHOLD for kept in kept_boxes):
+            continue
+
+        # Crop the face from the original image
+        cropped_face = image[y_min:y_max, x_min:x_max]
+        if cropped_face.size == 0:
+            continue
+
+        kept_boxes.append(box)
+        faces.append((cropped_face, box, score))
+        if len(faces) >= MAX_FACES:
+            break
+
+    return faces
 
 def get_embedding(face_image):
     """Generates a face embedding using the FaceNet model."""
@@ -101,7 +127,71 @@ def get_embedding(face_image):
     
     return embedding.tolist()
 
+def extract_faces(image_rgb):
+    """Detects every face and returns them in the JSON shape expected by the server."""
+    faces = []
+    for face_image, (x_min, y_min, x_max, y_max), score in detect_faces(image_rgb):
+        faces.append({
+            "bbox": {"x": x_min, "y": y_min, "width": x_max - x_min, "height": y_max - y_min},
+            "confidence": score,
+            "embedding": get_embedding(face_image),
+        })
+    return faces
+
+def read_frame(stream):
+    """Reads one length-prefixed JSON frame. Returns None at end of input."""
+    header = stream.read(4)
+    if len(header) < 4:
+        return None
+    (size,) = struct.unpack(">I", header)
+    payload = stream.read(size)
+    if len(payload) < size:
+        return None
+    return json.loads(payload)
+
+def write_frame(stream, message):
+    """Writes one length-prefixed JSON frame."""
+    payload = json.dumps(message).encode("utf-8")
+    stream.write(struct.pack(">I", len(payload)))
+    stream.write(payload)
+    stream.flush()
+
+def serve():
+    """Serves extraction requests over framed stdin/stdout until stdin is closed.
+
+    Requests are {"id", "op": "extract" | "ping", "image": base64}, and every request
+    gets exactly one response with the same id. Models are loaded once at import.
+    """
+    stdin = sys.stdin.buffer
+    stdout = sys.stdout.buffer
+    # Anything else printed (e.g. by TensorFlow) must not corrupt the frame stream
+    sys.stdout = sys.stderr
+
+    while True:
+        request = read_frame(stdin)
+        if request is None:
+            return
+
+        response = {"id": request.get("id")}
+        if request.get("op") == "extract":
+            try:
+                data = np.frombuffer(base64.b64decode(request.get("image", "")), dtype=np.uint8)
+                image = cv2.imdecode(data, cv2.IMREAD_COLOR)
+                if image is None:
+                    raise IOError("Could not decode image")
+                response["faces"] = extract_faces(cv2.cvtColor(image, cv2.COLOR_BGR2RGB))
+            except Exception as e:
+                response["error"] = f"Failed to load or process image: {e}"
+        elif request.get("op") != "ping":
+            response["error"] = f"Unknown op: {request.get('op')}"
+
+        write_frame(stdout, response)
+
 def main():
+    if len(sys.argv) == 2 and sys.argv[1] == "--serve":
+        serve()
+        return
+
     if len(sys.argv) != 2:
         print(json.dumps({"error": "Image path argument is required."}), file=sys.stderr)
         sys.exit(1)
@@ -117,17 +207,8 @@ def main():
         print(json.dumps({"error": f"Failed to load or process image: {e}"}), file=sys.stderr)
         sys.exit(1)
 
-    # 1. Detect face
-    face_image, err_msg = detect_face(image_rgb)
-    if err_msg:
-        print(json.dumps({"error": err_msg}), file=sys.stderr)
-        sys.exit(1)
-
-    # 2. Get embedding
-    embedding = get_embedding(face_image)
-
-    # 3. Print embedding as JSON to stdout
-    print(json.dumps(embedding))
+    # Detect faces and print them as JSON to stdout (an empty list when no face was found)
+    print(json.dumps({"faces": extract_faces(image_rgb)}))
 
 if __name__ == "__main__":
     main()